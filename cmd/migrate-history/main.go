@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/yourusername/cal-chatbot/internal/history"
+	"github.com/yourusername/cal-chatbot/internal/logging"
+)
+
+// migrate-history converts the legacy "[time] [role]: text" history files into
+// the conversation store used by the server.
+func main() {
+	from := flag.String("from", "history", "directory containing the legacy .txt history files")
+	to := flag.String("to", "history", "directory of the conversation store")
+	kind := flag.String("store", "jsonl", "conversation store to migrate to (jsonl or sqlite)")
+	flag.Parse()
+
+	if err := logging.Setup(); err != nil {
+		slog.Error("Failed to set up logging", "error", err)
+		os.Exit(1)
+	}
+
+	store, err := history.Open(*kind, *to)
+	if err != nil {
+		slog.Error("Failed to open conversation store", "error", err)
+		os.Exit(1)
+	}
+
+	migrated, err := history.MigrateTextHistory(*from, store)
+	// Close the store before exiting so SQLite checkpoints its write-ahead log
+	if closer, ok := store.(io.Closer); ok {
+		closer.Close()
+	}
+	if err != nil {
+		slog.Error("Migration stopped", "migrated", migrated, "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Migrated conversations", "migrated", migrated, "from", *from, "to", *to)
+}
@@ -1,29 +1,47 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/yourusername/cal-chatbot/internal/api"
+	"github.com/yourusername/cal-chatbot/internal/calcom"
 	"github.com/yourusername/cal-chatbot/internal/chatbot"
+	"github.com/yourusername/cal-chatbot/internal/history"
+	"github.com/yourusername/cal-chatbot/internal/logging"
 )
 
-func main() {
-	// Get the current directory
-	dir, err := os.Getwd()
-	if err != nil {
-		log.Printf("Warning: failed to get current directory: %v", err)
-	}
+// shutdownGracePeriod is how long in-flight requests may take to finish on shutdown
+const shutdownGracePeriod = 15 * time.Second
 
-	// Load environment variables from .env file
+func main() {
+	// Load environment variables from .env file before setting up logging, as it may configure it
+	dir, dirErr := os.Getwd()
 	envPath := filepath.Join(dir, ".env")
-	log.Printf("Loading .env from: %s", envPath)
-	if err := godotenv.Load(envPath); err != nil {
-		log.Printf("Warning: .env file not found at %s, using environment variables: %v", envPath, err)
+	envErr := godotenv.Load(envPath)
+
+	// Log with log/slog as configured by LOG_LEVEL and LOG_FORMAT
+	if err := logging.Setup(); err != nil {
+		slog.Error("Failed to set up logging", "error", err)
+		os.Exit(1)
+	}
+	if dirErr != nil {
+		slog.Warn("Failed to get current directory", "error", dirErr)
+	}
+	if envErr != nil {
+		slog.Warn(".env file not found, using environment variables", "path", envPath, "error", envErr)
+	} else {
+		slog.Info("Loaded .env", "path", envPath)
 	}
 
 	// Set up Gin
@@ -31,20 +49,30 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Create the Cal.com client backing the chatbot
+	calcomClient, err := calcom.NewBackend()
+	if err != nil {
+		slog.Error("Failed to create Cal.com client", "error", err)
+		os.Exit(1)
+	}
+
 	// Create a new chatbot instance
-	bot, err := chatbot.NewChatbot()
+	bot, err := chatbot.NewChatbot(calcomClient)
 	if err != nil {
-		log.Fatalf("Failed to create chatbot: %v", err)
+		slog.Error("Failed to create chatbot", "error", err)
+		os.Exit(1)
 	}
 
-	// Create a new router
-	router := gin.Default()
+	// Create a new router, giving every request an ID, logging requests with personal data masked
+	// and recording request metrics
+	router := gin.New()
+	router.Use(api.RequestID(), api.RequestLogger(), api.Metrics(), gin.Recovery())
 
 	// Setup CORS
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Conversation-Id, X-Request-Id")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -52,8 +80,19 @@ func main() {
 		c.Next()
 	})
 
+	// Open the conversation history store
+	historyDir := os.Getenv("HISTORY_DIR")
+	if historyDir == "" {
+		historyDir = "history"
+	}
+	store, err := history.Open(os.Getenv("HISTORY_STORE"), historyDir)
+	if err != nil {
+		slog.Error("Failed to open conversation history", "error", err)
+		os.Exit(1)
+	}
+
 	// Create API handlers
-	handler := api.NewHandler(bot)
+	handler := api.NewHandler(bot, store)
 	handler.SetupRoutes(router)
 
 	// Get port from environment variable
@@ -62,9 +101,34 @@ func main() {
 		port = "8080"
 	}
 
+	// Requests are served in contexts derived from baseCtx, so canceling it aborts in-flight Cal.com calls
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	server := &http.Server{
+		Addr:        fmt.Sprintf(":%s", port),
+		Handler:     router,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+
 	// Start the server
-	log.Printf("Server starting on port %s...", port)
-	if err := router.Run(fmt.Sprintf(":%s", port)); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	slog.Info("Server starting", "port", port)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("Failed to start server", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	// On SIGINT or SIGTERM, give in-flight requests a grace period, then cancel them
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-signals.Done()
+	slog.Info("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Info("Canceling requests still in flight", "error", err)
+		cancelRequests()
+		server.Close()
 	}
 }
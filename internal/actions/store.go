@@ -0,0 +1,110 @@
+// Package actions keeps calendar changes proposed by the chatbot until the user confirms them.
+package actions
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// DefaultTTL is how long a proposed action can be confirmed when not configured
+const DefaultTTL = 10 * time.Minute
+
+var (
+	// ErrNotFound is returned for unknown tokens and tokens of another conversation
+	ErrNotFound = errors.New("pending action not found")
+	// ErrExpired is returned when an action is confirmed after its expiry
+	ErrExpired = errors.New("pending action expired")
+)
+
+// Store holds pending actions in memory, keyed by token
+type Store struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	pending map[string]models.PendingAction
+}
+
+// NewStore creates a store whose actions expire after ttl
+func NewStore(ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Store{
+		ttl:     ttl,
+		pending: make(map[string]models.PendingAction),
+	}
+}
+
+// Propose records an action and returns it with its token and expiry set
+func (s *Store) Propose(action models.PendingAction) models.PendingAction {
+	now := time.Now()
+	action.Token = uuid.New().String()
+	action.CreatedAt = now
+	action.ExpiresAt = now.Add(s.ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeExpired(now)
+	s.pending[action.Token] = action
+	return action
+}
+
+// Get returns the pending action with token in the conversation without removing it
+func (s *Store) Get(conversationID, token string) (models.PendingAction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lookup(conversationID, token)
+}
+
+// Take removes and returns the pending action with token in the conversation.
+// Each action can be taken only once.
+func (s *Store) Take(conversationID, token string) (models.PendingAction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	action, err := s.lookup(conversationID, token)
+	if err == nil || err == ErrExpired {
+		delete(s.pending, token)
+	}
+	return action, err
+}
+
+// Pending returns the unexpired actions of a conversation, oldest first
+func (s *Store) Pending(conversationID string) []models.PendingAction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var actions []models.PendingAction
+	for _, action := range s.pending {
+		if action.ConversationID == conversationID && !now.After(action.ExpiresAt) {
+			actions = append(actions, action)
+		}
+	}
+	sort.Slice(actions, func(i, j int) bool { return actions[i].CreatedAt.Before(actions[j].CreatedAt) })
+	return actions
+}
+
+// lookup finds an action; the caller must hold mu
+func (s *Store) lookup(conversationID, token string) (models.PendingAction, error) {
+	action, ok := s.pending[token]
+	if !ok || action.ConversationID != conversationID {
+		return models.PendingAction{}, ErrNotFound
+	}
+	if time.Now().After(action.ExpiresAt) {
+		return action, ErrExpired
+	}
+	return action, nil
+}
+
+// removeExpired drops actions that expired more than a TTL ago; until then confirming
+// them reports ErrExpired rather than ErrNotFound. The caller must hold mu.
+func (s *Store) removeExpired(now time.Time) {
+	for token, action := range s.pending {
+		if now.After(action.ExpiresAt.Add(s.ttl)) {
+			delete(s.pending, token)
+		}
+	}
+}
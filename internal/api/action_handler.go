@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/cal-chatbot/internal/actions"
+	"github.com/yourusername/cal-chatbot/internal/history"
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// HandleConfirmAction executes a booking, cancellation or reschedule the chatbot proposed.
+// The X-Conversation-Id header must name the conversation the action was proposed in.
+func (h *Handler) HandleConfirmAction(c *gin.Context) {
+	confirmer, ok := h.chatbot.(ActionConfirmer)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Confirming actions is not supported."})
+		return
+	}
+
+	conversationID := c.GetHeader("X-Conversation-Id")
+	token := c.Param("token")
+	if conversationID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The X-Conversation-Id header is required."})
+		return
+	}
+
+	ctx := models.WithConversationID(c.Request.Context(), conversationID)
+	// A confirmed booking completes the booking draft of the conversation
+	stored, err := h.history.Load(conversationID)
+	if err != nil && err != history.ErrNotFound {
+		logError(ctx, "Failed to load conversation history", err)
+	}
+	ctx = models.WithDraftTracker(ctx, models.NewDraftTracker(history.LatestDraft(stored)))
+	action, record, err := confirmer.ConfirmAction(ctx, conversationID, token)
+	switch err {
+	case nil:
+	case actions.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "There is no pending action with this token in the conversation."})
+		return
+	case actions.ErrExpired:
+		c.JSON(http.StatusGone, gin.H{"error": "This action expired. Please ask again."})
+		return
+	default:
+		logError(ctx, "Failed to confirm action", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Sorry, we couldn't confirm this action. Please try again later."})
+		return
+	}
+
+	// Record the outcome in the conversation so the chatbot knows about it in later turns
+	message := "Confirmed: " + action.Summary
+	if record.Error != "" {
+		message = "Sorry, that didn't work: " + record.Error
+	}
+	h.saveAssistantMessage(ctx, conversationID, message, []models.ExecutedFunctionCall{record}, "")
+
+	status := http.StatusOK
+	if record.Error != "" {
+		status = http.StatusBadGateway
+	}
+	c.JSON(status, models.ChatResponse{
+		Message:        message,
+		FunctionCalls:  []models.ExecutedFunctionCall{record},
+		ConversationID: conversationID,
+		Draft:          currentDraft(ctx),
+	})
+}
@@ -1,72 +1,204 @@
 package api
 
 import (
-	"bytes"
-	"io/ioutil"
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/yourusername/cal-chatbot/internal/chatbot"
+	"github.com/yourusername/cal-chatbot/internal/history"
 	"github.com/yourusername/cal-chatbot/internal/models"
 )
 
-// logError logs errors with context and request ID
-func logError(context, requestID string, err error) {
-	if requestID != "" {
-		log.Printf("[ERROR] [%s] %s: %v", requestID, context, err)
+// logError logs an error with the request and conversation IDs of ctx
+func logError(ctx context.Context, message string, err error) {
+	if err != nil {
+		slog.ErrorContext(ctx, message, "error", err)
 	} else {
-		log.Printf("[ERROR] %s: %v", context, err)
+		slog.ErrorContext(ctx, message)
 	}
 }
 
-// HandleChat handles chat messages
-func (h *Handler) HandleChat(c *gin.Context) {
+// newHistoryEntry wraps a chat message for the history store
+func newHistoryEntry(message models.ChatMessage, toolCalls []models.ExecutedFunctionCall, userID string) models.HistoryEntry {
+	entry := models.HistoryEntry{
+		ChatMessage: message,
+		ToolCalls:   toolCalls,
+		Timestamp:   time.Now(),
+	}
+	if userID != "" {
+		entry.Metadata = map[string]string{"userId": userID}
+	}
+	return entry
+}
+
+// chatContext returns the request context carrying the conversation ID, the profile of the
+// user making a chat request and a recorder for the token usage of the turn. The timezone of
+// the request takes precedence over the one saved when the email was verified.
+func chatContext(c *gin.Context, conversationID string, req models.ChatRequest) context.Context {
+	profile := models.UserProfile{UserID: req.UserID, Timezone: req.Timezone}
+	if email, err := c.Cookie("verified_email"); err == nil {
+		profile.Email = email
+	}
+	if profile.Timezone == "" {
+		if timezone, err := c.Cookie("timezone"); err == nil {
+			profile.Timezone = timezone
+		}
+	}
+	ctx := models.WithConversationID(c.Request.Context(), conversationID)
+	ctx = models.WithUsageRecorder(ctx, &models.UsageRecorder{})
+	return models.WithUserProfile(ctx, profile)
+}
+
+// saveAssistantMessage saves an assistant response, the tool calls behind it, the token usage
+// recorded in ctx and the booking draft if the turn changed it to history
+func (h *Handler) saveAssistantMessage(ctx context.Context, conversationID, response string, functionCalls []models.ExecutedFunctionCall, userID string) {
+	message := models.ChatMessage{Role: "assistant", Content: response}
+	entry := newHistoryEntry(message, functionCalls, userID)
+	if recorder := models.UsageRecorderFromContext(ctx); recorder != nil {
+		usage := recorder.Usage()
+		entry.Usage = &usage
+	}
+	if tracker := models.DraftTrackerFromContext(ctx); tracker != nil && tracker.Changed() {
+		entry.Draft = currentDraft(ctx)
+	}
+	if err := h.history.Append(conversationID, entry); err != nil {
+		logError(ctx, "Failed to save assistant message", err)
+	}
+}
+
+// currentDraft returns the booking draft of the conversation in ctx, or nil if there is none
+func currentDraft(ctx context.Context) *models.BookingDraft {
+	tracker := models.DraftTrackerFromContext(ctx)
+	if tracker == nil {
+		return nil
+	}
+	draft := tracker.Draft()
+	if draft.IsEmpty() {
+		return nil
+	}
+	return &draft
+}
+
+// bindChatRequest resolves the conversation ID, parses the chat request body, prepends the
+// stored turns of the conversation and saves the new messages to history.
+// On failure it writes the error response and returns false.
+func (h *Handler) bindChatRequest(c *gin.Context) (string, models.ChatRequest, bool) {
 	// Generate or extract a conversation ID for history
 	conversationID := c.GetHeader("X-Conversation-Id")
 	if conversationID == "" {
 		conversationID = uuid.New().String()
 	}
 
-	// Debug: print raw request body and headers
-	log.Printf("[DEBUG] [%s] Content-Type: %s", conversationID, c.GetHeader("Content-Type"))
-	bodyBytes, _ := ioutil.ReadAll(c.Request.Body)
-	log.Printf("[DEBUG] [%s] Raw request body: %s", conversationID, string(bodyBytes))
-	// Re-parse the body for binding
-	c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
+	// Log the conversation ID with everything done for the request
+	c.Request = c.Request.WithContext(models.WithConversationID(c.Request.Context(), conversationID))
+	ctx := c.Request.Context()
+
+	// The request body is not logged, since messages contain email addresses and other personal data
+	slog.DebugContext(ctx, "Chat request received", "content_type", c.GetHeader("Content-Type"), "content_length", c.Request.ContentLength)
 
 	var req models.ChatRequest
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		logError("JSON binding error", conversationID, err)
-		log.Printf("[DEBUG] [%s] Failed to bind JSON. Raw body: %s", conversationID, string(bodyBytes))
+		logError(ctx, "JSON binding error", err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Your request was not understood. Please check your input and try again.",
 		})
-		return
+		return "", req, false
 	}
-	log.Printf("[DEBUG] [%s] Parsed ChatRequest: %+v", conversationID, req)
-	log.Printf("[DEBUG] [%s] req.Messages type: %T, len: %d", conversationID, req.Messages, len(req.Messages))
+	slog.DebugContext(ctx, "Parsed ChatRequest", "messages", len(req.Messages))
 
 	if len(req.Messages) == 0 {
-		logError("Empty messages array", conversationID, nil)
+		logError(ctx, "Empty messages array", nil)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Please provide at least one message in your request.",
 		})
-		return
+		return "", req, false
+	}
+
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			logError(ctx, "Invalid timezone", err)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "The timezone is not a valid IANA timezone, e.g. \"Europe/Berlin\".",
+			})
+			return "", req, false
+		}
+	}
+
+	if err := history.ValidateConversationID(conversationID); err != nil {
+		logError(ctx, "Invalid conversation ID", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "The conversation ID is invalid.",
+		})
+		return "", req, false
 	}
 
-	// Save all user messages to history
-	for _, m := range req.Messages {
-		if m.Role == "user" && m.Content != "" {
-			_ = chatbot.SaveMessage(conversationID, m.Role, m.Content)
+	// For a stored conversation the prior turns come from history and only the latest
+	// message of the request is new; otherwise every message in the request is new
+	newMessages := req.Messages
+	stored, err := h.history.Load(conversationID)
+	// Keep track of the booking the conversation is putting together, starting from the saved draft
+	tracker := models.NewDraftTracker(history.LatestDraft(stored))
+	c.Request = c.Request.WithContext(models.WithDraftTracker(ctx, tracker))
+	switch {
+	case err == nil:
+		last := req.Messages[len(req.Messages)-1]
+		if last.Role != "user" {
+			logError(ctx, "Last message is not from the user", nil)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "The last message in your request must be from the user.",
+			})
+			return "", req, false
+		}
+		newMessages = []models.ChatMessage{last}
+		req.Messages = make([]models.ChatMessage, 0, len(stored)+1)
+		for _, entry := range stored {
+			req.Messages = append(req.Messages, entry.ChatMessage)
 		}
+		req.Messages = append(req.Messages, last)
+		slog.DebugContext(ctx, "Continuing conversation", "stored_messages", len(stored))
+	case err != history.ErrNotFound:
+		logError(ctx, "Failed to load conversation history", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Sorry, we couldn't load your conversation. Please try again later.",
+		})
+		return "", req, false
 	}
 
-	response, err := h.chatbot.ProcessMessage(c.Request.Context(), req.Messages)
+	// Save only the new messages to history
+	var entries []models.HistoryEntry
+	for _, m := range newMessages {
+		if m.Content != "" || m.Booking != nil || m.ListEvents != nil {
+			entries = append(entries, newHistoryEntry(m, nil, req.UserID))
+		}
+	}
+	if err := h.history.Append(conversationID, entries...); err != nil {
+		logError(ctx, "Failed to save new messages", err)
+	}
+
+	return conversationID, req, true
+}
+
+// HandleChat handles chat messages. Clients that accept text/event-stream get a streamed response.
+func (h *Handler) HandleChat(c *gin.Context) {
+	if strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+		h.HandleChatStream(c)
+		return
+	}
+
+	conversationID, req, ok := h.bindChatRequest(c)
+	if !ok {
+		return
+	}
+
+	ctx := chatContext(c, conversationID, req)
+	response, functionCalls, err := h.chatbot.ProcessMessage(ctx, req.Messages)
 	if err != nil {
-		logError("Failed to process message", conversationID, err)
+		logError(ctx, "Failed to process message", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Sorry, something went wrong while processing your message. Please try again later.",
 		})
@@ -74,12 +206,64 @@ func (h *Handler) HandleChat(c *gin.Context) {
 	}
 
 	// Save assistant response to history
-	_ = chatbot.SaveMessage(conversationID, "assistant", response)
+	h.saveAssistantMessage(ctx, conversationID, response, functionCalls, req.UserID)
 
 	c.Header("X-Conversation-Id", conversationID)
 	c.JSON(http.StatusOK, models.ChatResponse{
-		Message: response,
-		// Optionally, add conversationID to the response for the frontend
-		// ConversationID: conversationID,
+		Message:        response,
+		FunctionCalls:  functionCalls,
+		ConversationID: conversationID,
+		Draft:          currentDraft(ctx),
+	})
+}
+
+// HandleChatStream handles chat messages and streams the response as Server-Sent Events.
+// It relays token deltas and tool call progress, and ends with a message event
+// carrying the full response and the conversation ID.
+func (h *Handler) HandleChatStream(c *gin.Context) {
+	conversationID, req, ok := h.bindChatRequest(c)
+	if !ok {
+		return
+	}
+
+	c.Header("X-Conversation-Id", conversationID)
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	send := func(event models.StreamEvent) {
+		c.SSEvent(event.Type, event)
+		c.Writer.Flush()
+	}
+
+	ctx := chatContext(c, conversationID, req)
+	var response string
+	var functionCalls []models.ExecutedFunctionCall
+	var err error
+	if streamer, ok := h.chatbot.(StreamingChatProcessor); ok {
+		response, functionCalls, err = streamer.ProcessMessageStream(ctx, req.Messages, send)
+	} else {
+		response, functionCalls, err = h.chatbot.ProcessMessage(ctx, req.Messages)
+	}
+	if err != nil {
+		logError(ctx, "Failed to process message", err)
+		send(models.StreamEvent{
+			Type:           models.StreamEventError,
+			Error:          "Sorry, something went wrong while processing your message. Please try again later.",
+			ConversationID: conversationID,
+		})
+		return
+	}
+
+	// Save assistant response to history
+	h.saveAssistantMessage(ctx, conversationID, response, functionCalls, req.UserID)
+
+	send(models.StreamEvent{
+		Type:           models.StreamEventMessage,
+		Content:        response,
+		FunctionCalls:  functionCalls,
+		ConversationID: conversationID,
+		Draft:          currentDraft(ctx),
 	})
 }
@@ -2,26 +2,55 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/yourusername/cal-chatbot/internal/chatbot"
+	"github.com/yourusername/cal-chatbot/internal/history"
+	"github.com/yourusername/cal-chatbot/internal/models"
 )
 
+// ChatProcessor is the chatbot behaviour the API handlers depend on.
+// *chatbot.Chatbot implements it.
+type ChatProcessor interface {
+	ProcessMessage(ctx context.Context, messages []models.ChatMessage) (string, []models.ExecutedFunctionCall, error)
+}
+
+// StreamingChatProcessor is implemented by chatbots that can report progress while
+// processing a message. *chatbot.Chatbot implements it.
+type StreamingChatProcessor interface {
+	ProcessMessageStream(ctx context.Context, messages []models.ChatMessage, handler func(models.StreamEvent)) (string, []models.ExecutedFunctionCall, error)
+}
+
+// ActionConfirmer is implemented by chatbots that propose bookings, cancellations and
+// reschedules for the user to confirm. *chatbot.Chatbot implements it.
+type ActionConfirmer interface {
+	ConfirmAction(ctx context.Context, conversationID, token string) (models.PendingAction, models.ExecutedFunctionCall, error)
+}
+
+// EventLister is implemented by chatbots that can list the events booked with an attendee.
+// *chatbot.Chatbot implements it.
+type EventLister interface {
+	GetEvents(ctx context.Context, email string) ([]models.Event, error)
+}
+
 // Handler contains all API handlers
 type Handler struct {
-	chatbot *chatbot.Chatbot
+	chatbot ChatProcessor
+	history history.ConversationStore
 }
 
 // NewHandler creates a new API handler
-func NewHandler(bot *chatbot.Chatbot) *Handler {
+func NewHandler(bot ChatProcessor, store history.ConversationStore) *Handler {
 	return &Handler{
 		chatbot: bot,
+		history: store,
 	}
 }
 
@@ -30,10 +59,13 @@ func (h *Handler) SetupRoutes(r *gin.Engine) {
 	api := r.Group("/api")
 	{
 		api.POST("/chat", h.HandleChat)
+		api.POST("/chat/stream", h.HandleChatStream)
 		api.GET("/health", h.HandleHealth)
 		api.GET("/endpoints", h.HandleEndpoints)
 		api.GET("/history/:conversation_id", h.HandleLoadHistory)
 		api.GET("/history/search", h.HandleSearchHistory)
+		api.POST("/actions/:token/confirm", h.HandleConfirmAction)
+		api.GET("/conversations/:conversation_id/usage", h.HandleConversationUsage)
 
 		// Cal.com email verification and events
 		cal := api.Group("/cal")
@@ -44,6 +76,9 @@ func (h *Handler) SetupRoutes(r *gin.Engine) {
 		}
 	}
 
+	// Prometheus metrics
+	r.GET("/metrics", h.HandleMetrics)
+
 	// Serve static files for the web interface
 	r.Static("/web", "./web")
 	r.StaticFile("/", "./web/index.html")
@@ -51,12 +86,15 @@ func (h *Handler) SetupRoutes(r *gin.Engine) {
 
 // HandleEndpoints returns a list of all available API endpoints
 func (h *Handler) HandleEndpoints(c *gin.Context) {
-	log.Printf("[INFO] Endpoints listed for %s", c.ClientIP())
+	slog.InfoContext(c.Request.Context(), "Endpoints listed", "client_ip", c.ClientIP())
 	c.JSON(200, gin.H{
 		"endpoints": []string{
 			"POST /api/chat",
+			"POST /api/chat/stream",
 			"GET /api/health",
 			"GET /api/endpoints",
+			"POST /api/actions/:token/confirm",
+			"GET /api/conversations/:id/usage",
 		},
 	})
 }
@@ -64,29 +102,82 @@ func (h *Handler) HandleEndpoints(c *gin.Context) {
 // HandleLoadHistory loads a conversation's history
 func (h *Handler) HandleLoadHistory(c *gin.Context) {
 	conversationID := c.Param("conversation_id")
-	history, err := chatbot.LoadHistory(conversationID)
+	entries, err := h.history.Load(conversationID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found."})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"history": history})
+	c.JSON(http.StatusOK, gin.H{"history": entries})
 }
 
-// HandleSearchHistory searches all conversations for a term
+// HandleConversationUsage returns the token usage and estimated cost of a conversation, turn by turn and in total
+func (h *Handler) HandleConversationUsage(c *gin.Context) {
+	conversationID := c.Param("conversation_id")
+	entries, err := h.history.Load(conversationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found."})
+		return
+	}
+	c.JSON(http.StatusOK, history.SummarizeUsage(conversationID, entries))
+}
+
+// HandleSearchHistory searches all conversations for a term. Results can be narrowed with
+// role, from/to (RFC3339 or YYYY-MM-DD) and attendee (email) query parameters.
 func (h *Handler) HandleSearchHistory(c *gin.Context) {
-	term := c.Query("q")
-	if term == "" {
+	query := history.SearchQuery{
+		Term:          c.Query("q"),
+		Role:          c.Query("role"),
+		AttendeeEmail: c.Query("attendee"),
+	}
+	if query.Term == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing search term."})
 		return
 	}
-	matches, err := chatbot.SearchHistory(term)
+
+	var err error
+	if query.From, err = parseSearchTime(c.Query("from"), false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'from' date."})
+		return
+	}
+	if query.To, err = parseSearchTime(c.Query("to"), true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'to' date."})
+		return
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit."})
+			return
+		}
+	}
+
+	matches, err := h.history.Search(query)
 	if err != nil {
+		logError(c.Request.Context(), "History search failed", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed."})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"matches": matches})
 }
 
+// parseSearchTime parses an RFC3339 time or a YYYY-MM-DD date. A date used as the end
+// of a range covers the whole day. An empty value returns the zero time.
+func parseSearchTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
 // HandleRequestVerificationCode proxies a request to Cal.com to send a verification code to the user's email
 func (h *Handler) HandleRequestVerificationCode(c *gin.Context) {
 	var req struct {
@@ -97,7 +188,7 @@ func (h *Handler) HandleRequestVerificationCode(c *gin.Context) {
 		return
 	}
 	// Call Cal.com API
-	calRes, err := proxyCalComRequest("POST", "/v2/verified-resources/emails/verification-code/request", map[string]string{"email": req.Email})
+	calRes, err := proxyCalComRequest(c.Request.Context(), "POST", "/v2/verified-resources/emails/verification-code/request", map[string]string{"email": req.Email})
 	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
 		return
@@ -108,15 +199,16 @@ func (h *Handler) HandleRequestVerificationCode(c *gin.Context) {
 // HandleVerifyEmailCode proxies a request to Cal.com to verify the code for the user's email
 func (h *Handler) HandleVerifyEmailCode(c *gin.Context) {
 	var req struct {
-		Email string `json:"email"`
-		Code  string `json:"code"`
+		Email    string `json:"email"`
+		Code     string `json:"code"`
+		Timezone string `json:"timezone,omitempty"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil || req.Email == "" || req.Code == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid email/code"})
 		return
 	}
 	// Call Cal.com API
-	calRes, err := proxyCalComRequest("POST", "/v2/verified-resources/emails/verification-code/verify", map[string]string{"email": req.Email, "code": req.Code})
+	calRes, err := proxyCalComRequest(c.Request.Context(), "POST", "/v2/verified-resources/emails/verification-code/verify", map[string]string{"email": req.Email, "code": req.Code})
 	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
 		return
@@ -124,28 +216,36 @@ func (h *Handler) HandleVerifyEmailCode(c *gin.Context) {
 	// If verification is successful, set a secure cookie with the verified email
 	if status, ok := calRes["status"].(string); ok && status == "success" {
 		c.SetCookie("verified_email", req.Email, 3600, "/", "", true, true)
+		// Remember the user's timezone for chat requests that don't send one
+		if _, err := time.LoadLocation(req.Timezone); err == nil && req.Timezone != "" {
+			c.SetCookie("timezone", req.Timezone, 3600, "/", "", true, true)
+		}
 	}
 	c.JSON(http.StatusOK, calRes)
 }
 
-// HandleGetScheduledEvents proxies a request to Cal.com to get scheduled events for the verified email
+// HandleGetScheduledEvents lists the events booked with the verified email through the calendar backend
 func (h *Handler) HandleGetScheduledEvents(c *gin.Context) {
 	email, err := c.Cookie("verified_email")
 	if err != nil || email == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not verified or missing email in session"})
 		return
 	}
-	// Call Cal.com API for events (replace with correct endpoint as needed)
-	calRes, err := proxyCalComRequest("GET", "/v2/bookings?email="+email, nil)
+	lister, ok := h.chatbot.(EventLister)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Listing events is not supported."})
+		return
+	}
+	events, err := lister.GetEvents(c.Request.Context(), email)
 	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, calRes)
+	c.JSON(http.StatusOK, gin.H{"events": events})
 }
 
-// proxyCalComRequest is a helper to call Cal.com API with your API key
-func proxyCalComRequest(method, path string, body interface{}) (map[string]interface{}, error) {
+// proxyCalComRequest is a helper to call Cal.com API with your API key. The request is canceled with ctx.
+func proxyCalComRequest(ctx context.Context, method, path string, body interface{}) (map[string]interface{}, error) {
 	apiKey := os.Getenv("CALCOM_API_KEY")
 	if apiKey == "" {
 		return nil, gin.Error{Err: io.EOF, Type: gin.ErrorTypePrivate, Meta: "CALCOM_API_KEY not set"}
@@ -159,7 +259,7 @@ func proxyCalComRequest(method, path string, body interface{}) (map[string]inter
 		}
 		reqBody = bytes.NewBuffer(b)
 	}
-	request, err := http.NewRequest(method, url, reqBody)
+	request, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
 		return nil, err
 	}
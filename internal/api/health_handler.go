@@ -3,14 +3,14 @@ package api
 import (
 	"net/http"
 
-	"log"
+	"log/slog"
 
 	"github.com/gin-gonic/gin"
 )
 
 // HandleHealth handles health check requests
 func (h *Handler) HandleHealth(c *gin.Context) {
-	log.Printf("[INFO] Health check requested from %s", c.ClientIP())
+	slog.InfoContext(c.Request.Context(), "Health check requested", "client_ip", c.ClientIP())
 	c.JSON(http.StatusOK, gin.H{
 		"status": "healthy",
 	})
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/internal/redact"
+)
+
+// RequestIDHeader is the header carrying the request ID
+const RequestIDHeader = "X-Request-Id"
+
+// validRequestID matches request IDs accepted from clients, so they can't inject text into logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._\-]{1,64}$`)
+
+// RequestID assigns every request an ID, taken from the X-Request-Id header if the client sent
+// a valid one. The ID is returned in the same header and carried in the request context, so
+// everything logged while serving the request can be correlated.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.New().String()
+		}
+		c.Header(RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(models.WithRequestID(c.Request.Context(), requestID))
+		c.Next()
+	}
+}
+
+// RequestLogger logs every request once it is served, with credentials and email addresses
+// masked in the query, e.g. in history searches by attendee
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= 500 {
+			level = slog.LevelError
+		}
+		slog.LogAttrs(c.Request.Context(), level, "Request served",
+			slog.String("method", c.Request.Method),
+			slog.String("path", redact.URL(c.Request.URL.RequestURI())),
+			slog.Int("status", c.Writer.Status()),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/yourusername/cal-chatbot/internal/metrics"
+)
+
+// Metrics counts requests and observes their latency by route, e.g. /api/chat
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		// Label by route pattern rather than path to keep IDs out of the labels
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequests.WithLabelValues(route, c.Request.Method, strconv.Itoa(c.Writer.Status())).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(route, c.Request.Method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// HandleMetrics serves the metrics in the Prometheus text format
+func (h *Handler) HandleMetrics(c *gin.Context) {
+	promhttp.Handler().ServeHTTP(c.Writer, c.Request)
+}
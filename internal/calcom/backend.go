@@ -0,0 +1,103 @@
+package calcom
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// CalendarBackend is the set of calendar operations the chatbot depends on.
+// Client and V2Client implement it against the Cal.com API v1 and v2; tests and alternative
+// scheduling services can provide their own implementation.
+type CalendarBackend interface {
+	// Bookings
+	GetEvents(ctx context.Context, email string) ([]models.Event, error)
+	GetAvailableSlots(ctx context.Context, eventTypeID int, startDate, endDate time.Time) ([]time.Time, error)
+	BookEvent(ctx context.Context, booking models.BookingRequest) (*models.Event, error)
+	CancelEvent(ctx context.Context, eventID string) error
+	RescheduleEvent(ctx context.Context, eventID string, newStartTime, newEndTime time.Time) (*models.Event, error)
+	FindBooking(ctx context.Context, bookingID string) (*models.Booking, error)
+	EditBooking(ctx context.Context, bookingID string, update models.BookingUpdate) (*models.Booking, error)
+	GetBookableSlots(ctx context.Context, startDate, endDate time.Time) ([]models.Slot, error)
+
+	// Event types
+	GetEventTypes(ctx context.Context) ([]models.EventType, error)
+	CreateEventType(ctx context.Context, req models.EventTypeCreateRequest) (*models.EventType, error)
+
+	// Schedules
+	FindAllSchedules(ctx context.Context) ([]models.Schedule, error)
+	CreateSchedule(ctx context.Context, name, timeZone string) (*models.Schedule, error)
+	EditSchedule(ctx context.Context, scheduleID string, update models.ScheduleUpdate) (*models.Schedule, error)
+	RemoveSchedule(ctx context.Context, scheduleID string) error
+}
+
+// IdempotentBackend is implemented by backends that remember the bookings made for idempotency keys
+type IdempotentBackend interface {
+	// BookedEvent returns the event booked for key, if any and still active
+	BookedEvent(ctx context.Context, key string) (*models.Event, bool)
+}
+
+// Ensure Client satisfies CalendarBackend
+var (
+	_ CalendarBackend   = (*Client)(nil)
+	_ IdempotentBackend = (*Client)(nil)
+)
+
+// bookableSlots collects the available slots of every event type of backend between startDate
+// and endDate, ordered by start time. Slots last as long as their event type. An event type
+// whose slots can't be fetched is left out; only if none can be fetched is it an error.
+func bookableSlots(ctx context.Context, backend CalendarBackend, startDate, endDate time.Time) ([]models.Slot, error) {
+	eventTypes, err := backend.GetEventTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var slots []models.Slot
+	var failed int
+	var firstErr error
+	for _, eventType := range eventTypes {
+		available, err := backend.GetAvailableSlots(ctx, eventType.ID, startDate, endDate)
+		if err != nil {
+			slog.WarnContext(ctx, "GetBookableSlots: skipping event type whose slots can't be fetched", "event_type_id", eventType.ID, "error", err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to get slots of event type %d: %w", eventType.ID, err)
+			}
+			failed++
+			continue
+		}
+		length := time.Duration(eventType.Length) * time.Minute
+		for _, start := range available {
+			slots = append(slots, models.Slot{EventTypeID: eventType.ID, Start: start, End: start.Add(length)})
+		}
+	}
+	if failed > 0 && failed == len(eventTypes) {
+		return nil, firstErr
+	}
+	sort.SliceStable(slots, func(i, j int) bool { return slots[i].Start.Before(slots[j].Start) })
+	return slots, nil
+}
+
+// NewBackend creates the Cal.com client for the API version set by CALCOM_API_VERSION,
+// v1 (the default) or v2
+func NewBackend() (CalendarBackend, error) {
+	switch version := os.Getenv("CALCOM_API_VERSION"); version {
+	case "", "v1":
+		client, err := NewClient()
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case "v2":
+		client, err := NewV2Client()
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	default:
+		return nil, fmt.Errorf("invalid CALCOM_API_VERSION %q, expected v1 or v2", version)
+	}
+}
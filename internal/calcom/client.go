@@ -1,130 +1,122 @@
 package calcom
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/yourusername/cal-chatbot/internal/models"
 )
 
-// Client represents a Cal.com API client
+// Client is a Cal.com API v1 client
 type Client struct {
-	httpClient *http.Client
-	baseURL    string
-	apiKey     string
-	username   string
+	*transport
+	username string
 }
 
-// NewClient creates a new Cal.com API client
+// defaultV1BaseURL is the Cal.com API v1 endpoint used when CALCOM_API_URL is not set
+const defaultV1BaseURL = "https://api.cal.com/v1"
+
+// NewClient creates a new Cal.com API v1 client
 func NewClient() (*Client, error) {
 	apiKey := os.Getenv("CALCOM_API_KEY")
-	fmt.Println("[DEBUG] CALCOM_API_KEY:", apiKey)
 	baseURL := os.Getenv("CALCOM_API_URL")
-	fmt.Println("[DEBUG] CALCOM_API_URL:", baseURL)
-	username := os.Getenv("CALCOM_USERNAME")
-	fmt.Println("[DEBUG] CALCOM_USERNAME:", username)
-	if apiKey == "" {
-		return nil, fmt.Errorf("CALCOM_API_KEY environment variable is not set")
-	}
-
-	return &Client{
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		baseURL:  baseURL,
-		apiKey:   apiKey,
-		username: username,
-	}, nil
-}
-
-// makeRequest makes an HTTP request to the Cal.com API
-func (c *Client) makeRequest(method, path string, body interface{}) ([]byte, error) {
-	var bodyReader io.Reader
-	if body != nil {
-		bodyBytes, err := json.Marshal(body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request body: %v", err)
-		}
-		bodyReader = bytes.NewReader(bodyBytes)
+	if baseURL == "" {
+		baseURL = defaultV1BaseURL
 	}
+	username := os.Getenv("CALCOM_USERNAME")
 
-	// Append apiKey as a query parameter
-	url := fmt.Sprintf("%s%s", c.baseURL, path)
-	if strings.Contains(url, "?") {
-		url += "&apiKey=" + c.apiKey
-	} else {
-		url += "?apiKey=" + c.apiKey
-	}
-
-	req, err := http.NewRequest(method, url, bodyReader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
-
-	resp, err := c.httpClient.Do(req)
+	t, err := newTransport(baseURL, apiKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	t.apiKeyInQuery = true
+	return &Client{transport: t, username: username}, nil
+}
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
-	}
+// v1Booking is a booking as returned by the v1 API
+type v1Booking struct {
+	ID          json.Number       `json:"id"`
+	UID         string            `json:"uid"`
+	EventTypeID int               `json:"eventTypeId"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	StartTime   time.Time         `json:"startTime"`
+	EndTime     time.Time         `json:"endTime"`
+	Status      string            `json:"status"`
+	Location    string            `json:"location"`
+	Attendees   []models.Attendee `json:"attendees"`
+	User        *models.Attendee  `json:"user"`
+}
 
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error: %s (status code: %d)", respBody, resp.StatusCode)
+// booking converts the v1 booking to a Booking; its user is the organizer
+func (b v1Booking) booking() models.Booking {
+	id, _ := b.ID.Int64()
+	return models.Booking{
+		ID:          int(id),
+		UID:         b.UID,
+		EventTypeID: b.EventTypeID,
+		Title:       b.Title,
+		Description: b.Description,
+		StartTime:   b.StartTime,
+		EndTime:     b.EndTime,
+		Status:      b.Status,
+		Location:    b.Location,
+		Attendees:   b.Attendees,
+		Organizer:   b.User,
 	}
+}
 
-	return respBody, nil
+// event converts the v1 booking to an Event
+func (b v1Booking) event() models.Event {
+	return b.booking().Event()
 }
 
 // GetEvents retrieves all events for a user
-func (c *Client) GetEvents(email string) ([]models.Event, error) {
+func (c *Client) GetEvents(ctx context.Context, email string) ([]models.Event, error) {
 	path := "/bookings"
 	if email != "" {
-		path = fmt.Sprintf("%s?email=%s", path, email)
+		path = fmt.Sprintf("%s?email=%s", path, url.QueryEscape(email))
 	}
 
-	respBody, err := c.makeRequest(http.MethodGet, path, nil)
+	respBody, err := c.makeRequest(ctx, http.MethodGet, path, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	// Debug: log the raw API response
-	fmt.Printf("[DEBUG] Cal.com GetEvents raw response for email '%s': %s\n", email, string(respBody))
-
 	var response struct {
-		Bookings []models.Event `json:"bookings"`
+		Bookings []v1Booking `json:"bookings"`
 	}
 	if err := json.Unmarshal(respBody, &response); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal events: %v", err)
 	}
 
-	return response.Bookings, nil
+	events := make([]models.Event, len(response.Bookings))
+	for i, booking := range response.Bookings {
+		events[i] = booking.event()
+	}
+	return events, nil
 }
 
-// GetAvailableSlots retrieves available time slots for a specific event type
-func (c *Client) GetAvailableSlots(eventTypeID int, startDate, endDate time.Time) ([]time.Time, error) {
+// GetAvailableSlots retrieves available time slots for a specific event type.
+// Slots are requested in the timezone of startDate.
+func (c *Client) GetAvailableSlots(ctx context.Context, eventTypeID int, startDate, endDate time.Time) ([]time.Time, error) {
 	path := fmt.Sprintf("/availability/%s/%d", c.username, eventTypeID)
 	query := struct {
 		StartTime time.Time `json:"startTime"`
 		EndTime   time.Time `json:"endTime"`
+		TimeZone  string    `json:"timeZone"`
 	}{
 		StartTime: startDate,
 		EndTime:   endDate,
+		TimeZone:  startDate.Location().String(),
 	}
 
-	respBody, err := c.makeRequest(http.MethodPost, path, query)
+	respBody, err := c.makeRequest(ctx, http.MethodPost, path, query)
 	if err != nil {
 		return nil, err
 	}
@@ -139,9 +131,25 @@ func (c *Client) GetAvailableSlots(eventTypeID int, startDate, endDate time.Time
 	return response.Available, nil
 }
 
-// BookEvent books a new event
-func (c *Client) BookEvent(booking models.BookingRequest) (*models.Event, error) {
-	fmt.Printf("[DEBUG] BookEvent called with payload: %+v\n", booking)
+// BookEvent books a new event. Booking again with the same idempotency key returns the
+// event booked the first time instead of creating a duplicate.
+func (c *Client) BookEvent(ctx context.Context, booking models.BookingRequest) (*models.Event, error) {
+	return c.bookOnce(ctx, booking, func(ctx context.Context, key string) (*models.Event, error) {
+		return c.createBooking(ctx, booking, key)
+	}, c.GetEvents)
+}
+
+// BookedEvent returns the event booked for an idempotency key, if any and not cancelled since
+func (c *Client) BookedEvent(ctx context.Context, key string) (*models.Event, bool) {
+	return c.activeBooking(ctx, key, c.GetEvents)
+}
+
+// createBooking makes the booking request
+func (c *Client) createBooking(ctx context.Context, booking models.BookingRequest, key string) (*models.Event, error) {
+	timeZone := booking.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
 
 	// Build payload according to Cal.com API reference
 	payload := map[string]interface{}{
@@ -156,42 +164,42 @@ func (c *Client) BookEvent(booking models.BookingRequest) (*models.Event, error)
 				"optionValue": "",
 			},
 		},
-		"timeZone":    "UTC", // You may want to make this dynamic
+		"timeZone":    timeZone,
 		"language":    "en",
 		"title":       booking.Title, // Add Title to BookingRequest if not present
 		"description": booking.Notes, // Use Notes as description
 		"status":      "PENDING",
-		"metadata":    map[string]interface{}{},
+		"metadata":    map[string]interface{}{"idempotencyKey": key},
 	}
 
-	respBody, err := c.makeRequest(http.MethodPost, "/bookings", payload)
-	fmt.Printf("[DEBUG] Cal.com raw response: %s\n", string(respBody))
+	respBody, err := c.makeRequestWithHeaders(ctx, http.MethodPost, "/bookings", payload, map[string]string{"Idempotency-Key": key})
 	if err != nil {
-		fmt.Printf("[ERROR] BookEvent failed: %v\n", err)
 		return nil, err
 	}
 
 	var response struct {
-		Booking models.Event `json:"booking"`
+		Booking v1Booking `json:"booking"`
 	}
 	if err := json.Unmarshal(respBody, &response); err != nil {
-		fmt.Printf("[ERROR] Failed to unmarshal booking response: %v\n", err)
 		return nil, fmt.Errorf("failed to unmarshal booking response: %v", err)
 	}
-
-	return &response.Booking, nil
+	event := response.Booking.event()
+	return &event, nil
 }
 
 // CancelEvent cancels an existing event
-func (c *Client) CancelEvent(eventID string) error {
-	path := fmt.Sprintf("/bookings/%s/cancel", eventID)
-	_, err := c.makeRequest(http.MethodPost, path, nil)
-	return err
+func (c *Client) CancelEvent(ctx context.Context, eventID string) error {
+	path := fmt.Sprintf("/bookings/%s/cancel", url.PathEscape(eventID))
+	if _, err := c.makeRequest(ctx, http.MethodPost, path, nil); err != nil {
+		return err
+	}
+	c.forgetBooking(ctx, eventID)
+	return nil
 }
 
 // RescheduleEvent reschedules an existing event
-func (c *Client) RescheduleEvent(eventID string, newStartTime, newEndTime time.Time) (*models.Event, error) {
-	path := fmt.Sprintf("/bookings/%s/reschedule", eventID)
+func (c *Client) RescheduleEvent(ctx context.Context, eventID string, newStartTime, newEndTime time.Time) (*models.Event, error) {
+	path := fmt.Sprintf("/bookings/%s/reschedule", url.PathEscape(eventID))
 	body := struct {
 		Start time.Time `json:"start"`
 		End   time.Time `json:"end"`
@@ -200,37 +208,41 @@ func (c *Client) RescheduleEvent(eventID string, newStartTime, newEndTime time.T
 		End:   newEndTime,
 	}
 
-	respBody, err := c.makeRequest(http.MethodPost, path, body)
+	respBody, err := c.makeRequest(ctx, http.MethodPost, path, body)
 	if err != nil {
 		return nil, err
 	}
+	c.forgetBooking(ctx, eventID)
 
 	var response struct {
-		Booking models.Event `json:"booking"`
+		Booking v1Booking `json:"booking"`
 	}
 	if err := json.Unmarshal(respBody, &response); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal rescheduled booking: %v", err)
 	}
 
-	return &response.Booking, nil
+	event := response.Booking.event()
+	return &event, nil
 }
 
 // CreateEventType creates a new event type
-func (c *Client) CreateEventType(req models.EventTypeCreateRequest) (map[string]interface{}, error) {
-	respBody, err := c.makeRequest(http.MethodPost, "/event-types", req)
+func (c *Client) CreateEventType(ctx context.Context, req models.EventTypeCreateRequest) (*models.EventType, error) {
+	respBody, err := c.makeRequest(ctx, http.MethodPost, "/event-types", req)
 	if err != nil {
 		return nil, err
 	}
-	var response map[string]interface{}
+	var response struct {
+		EventType models.EventType `json:"event_type"`
+	}
 	if err := json.Unmarshal(respBody, &response); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal event type creation response: %v", err)
 	}
-	return response, nil
+	return &response.EventType, nil
 }
 
 // GetEventTypes fetches all event types for the user
-func (c *Client) GetEventTypes() ([]models.EventType, error) {
-	respBody, err := c.makeRequest(http.MethodGet, "/event-types", nil)
+func (c *Client) GetEventTypes(ctx context.Context) ([]models.EventType, error) {
+	respBody, err := c.makeRequest(ctx, http.MethodGet, "/event-types", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -244,8 +256,8 @@ func (c *Client) GetEventTypes() ([]models.EventType, error) {
 }
 
 // FindAllEventTypes fetches all event types
-func (c *Client) FindAllEventTypes() ([]models.EventType, error) {
-	respBody, err := c.makeRequest(http.MethodGet, "/event-types", nil)
+func (c *Client) FindAllEventTypes(ctx context.Context) ([]models.EventType, error) {
+	respBody, err := c.makeRequest(ctx, http.MethodGet, "/event-types", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -258,106 +270,162 @@ func (c *Client) FindAllEventTypes() ([]models.EventType, error) {
 	return response.EventTypes, nil
 }
 
+// v1Schedule is a schedule as returned by the v1 API. Weekly rules and date overrides are both
+// availabilities; overrides have a date.
+type v1Schedule struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	TimeZone     string `json:"timeZone"`
+	IsDefault    bool   `json:"isDefault"`
+	Availability []struct {
+		// Days are weekday numbers, 0 for Sunday
+		Days      []int     `json:"days"`
+		StartTime time.Time `json:"startTime"`
+		EndTime   time.Time `json:"endTime"`
+		Date      string    `json:"date"`
+	} `json:"availability"`
+}
+
+// schedule converts the v1 schedule to a Schedule
+func (s v1Schedule) schedule() models.Schedule {
+	schedule := models.Schedule{
+		ID:           s.ID,
+		Name:         s.Name,
+		TimeZone:     s.TimeZone,
+		IsDefault:    s.IsDefault,
+		Availability: []models.AvailabilityRule{},
+		Overrides:    []models.DateOverride{},
+	}
+	for _, availability := range s.Availability {
+		// Times are times of day on 1970-01-01 UTC
+		startTime, endTime := availability.StartTime.UTC().Format("15:04"), availability.EndTime.UTC().Format("15:04")
+		if availability.Date != "" {
+			date := availability.Date
+			if len(date) > len("2006-01-02") {
+				date = date[:len("2006-01-02")]
+			}
+			schedule.Overrides = append(schedule.Overrides, models.DateOverride{Date: date, StartTime: startTime, EndTime: endTime})
+			continue
+		}
+		days := make([]string, len(availability.Days))
+		for i, day := range availability.Days {
+			days[i] = time.Weekday(day).String()
+		}
+		schedule.Availability = append(schedule.Availability, models.AvailabilityRule{Days: days, StartTime: startTime, EndTime: endTime})
+	}
+	return schedule
+}
+
 // FindAllSchedules fetches all schedules
-func (c *Client) FindAllSchedules() ([]map[string]interface{}, error) {
-	respBody, err := c.makeRequest(http.MethodGet, "/schedules", nil)
+func (c *Client) FindAllSchedules(ctx context.Context) ([]models.Schedule, error) {
+	respBody, err := c.makeRequest(ctx, http.MethodGet, "/schedules", nil)
 	if err != nil {
 		return nil, err
 	}
 	var response struct {
-		Schedules []map[string]interface{} `json:"schedules"`
+		Schedules []v1Schedule `json:"schedules"`
 	}
 	if err := json.Unmarshal(respBody, &response); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal schedules: %v", err)
 	}
-	return response.Schedules, nil
+	schedules := make([]models.Schedule, len(response.Schedules))
+	for i, schedule := range response.Schedules {
+		schedules[i] = schedule.schedule()
+	}
+	return schedules, nil
 }
 
 // CreateSchedule creates a new schedule
-func (c *Client) CreateSchedule(name, timeZone string) (map[string]interface{}, error) {
+func (c *Client) CreateSchedule(ctx context.Context, name, timeZone string) (*models.Schedule, error) {
 	payload := map[string]interface{}{
 		"name":     name,
 		"timeZone": timeZone,
 	}
-	respBody, err := c.makeRequest(http.MethodPost, "/schedules", payload)
+	respBody, err := c.makeRequest(ctx, http.MethodPost, "/schedules", payload)
 	if err != nil {
 		return nil, err
 	}
-	var response map[string]interface{}
+	var response struct {
+		Schedule v1Schedule `json:"schedule"`
+	}
 	if err := json.Unmarshal(respBody, &response); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal schedule creation response: %v", err)
 	}
-	return response, nil
+	schedule := response.Schedule.schedule()
+	return &schedule, nil
 }
 
-// GetBookableSlots fetches all bookable slots between a datetime range
-func (c *Client) GetBookableSlots(start, end string) (map[string][]map[string]interface{}, error) {
-	params := fmt.Sprintf("?start=%s&end=%s", start, end)
-	respBody, err := c.makeRequest(http.MethodGet, "/slots"+params, nil)
-	if err != nil {
-		return nil, err
-	}
-	var response struct {
-		Slots map[string][]map[string]interface{} `json:"slots"`
-	}
-	if err := json.Unmarshal(respBody, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal slots: %v", err)
-	}
-	return response.Slots, nil
+// GetBookableSlots fetches the available slots of every event type between startDate and endDate,
+// ordered by start time. Slots are requested in the timezone of startDate.
+func (c *Client) GetBookableSlots(ctx context.Context, startDate, endDate time.Time) ([]models.Slot, error) {
+	return bookableSlots(ctx, c, startDate, endDate)
 }
 
 // RemoveSchedule deletes a schedule by ID
-func (c *Client) RemoveSchedule(scheduleID string) error {
-	path := fmt.Sprintf("/schedules/%s", scheduleID)
-	_, err := c.makeRequest(http.MethodDelete, path, nil)
+func (c *Client) RemoveSchedule(ctx context.Context, scheduleID string) error {
+	path := fmt.Sprintf("/schedules/%s", url.PathEscape(scheduleID))
+	_, err := c.makeRequest(ctx, http.MethodDelete, path, nil)
 	return err
 }
 
-// EditSchedule edits an existing schedule by ID
-func (c *Client) EditSchedule(scheduleID string, updates map[string]interface{}) (map[string]interface{}, error) {
-	path := fmt.Sprintf("/schedules/%s", scheduleID)
-	respBody, err := c.makeRequest(http.MethodPatch, path, updates)
+// EditSchedule renames a schedule or changes its timezone. The v1 API changes availability
+// and the default schedule through other resources, so those updates are not supported.
+func (c *Client) EditSchedule(ctx context.Context, scheduleID string, update models.ScheduleUpdate) (*models.Schedule, error) {
+	if update.IsDefault != nil || update.Availability != nil || update.Overrides != nil {
+		return nil, fmt.Errorf("changing a schedule's hours or making it the default is %w", ErrUnsupported)
+	}
+	path := fmt.Sprintf("/schedules/%s", url.PathEscape(scheduleID))
+	respBody, err := c.makeRequest(ctx, http.MethodPatch, path, update)
 	if err != nil {
 		return nil, err
 	}
-	var response map[string]interface{}
+	var response struct {
+		Schedule v1Schedule `json:"schedule"`
+	}
 	if err := json.Unmarshal(respBody, &response); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal schedule edit response: %v", err)
 	}
-	return response, nil
+	schedule := response.Schedule.schedule()
+	return &schedule, nil
 }
 
 // FindBooking fetches a booking by ID
-func (c *Client) FindBooking(bookingID string) (map[string]interface{}, error) {
-	path := fmt.Sprintf("/bookings/%s", bookingID)
-	respBody, err := c.makeRequest(http.MethodGet, path, nil)
+func (c *Client) FindBooking(ctx context.Context, bookingID string) (*models.Booking, error) {
+	path := fmt.Sprintf("/bookings/%s", url.PathEscape(bookingID))
+	respBody, err := c.makeRequest(ctx, http.MethodGet, path, nil)
 	if err != nil {
 		return nil, err
 	}
-	var response map[string]interface{}
+	var response struct {
+		Booking v1Booking `json:"booking"`
+	}
 	if err := json.Unmarshal(respBody, &response); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal booking: %v", err)
 	}
-	return response, nil
+	booking := response.Booking.booking()
+	return &booking, nil
 }
 
-// EditBooking edits an existing booking by ID
-func (c *Client) EditBooking(bookingID string, updates map[string]interface{}) (map[string]interface{}, error) {
-	path := fmt.Sprintf("/bookings/%s", bookingID)
-	respBody, err := c.makeRequest(http.MethodPatch, path, updates)
+// EditBooking edits an existing booking by ID, e.g. its title, description or location
+func (c *Client) EditBooking(ctx context.Context, bookingID string, update models.BookingUpdate) (*models.Booking, error) {
+	path := fmt.Sprintf("/bookings/%s", url.PathEscape(bookingID))
+	respBody, err := c.makeRequest(ctx, http.MethodPatch, path, update)
 	if err != nil {
 		return nil, err
 	}
-	var response map[string]interface{}
+	var response struct {
+		Booking v1Booking `json:"booking"`
+	}
 	if err := json.Unmarshal(respBody, &response); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal booking edit response: %v", err)
 	}
-	return response, nil
+	booking := response.Booking.booking()
+	return &booking, nil
 }
 
 // CancelBooking cancels a booking by ID
-func (c *Client) CancelBooking(bookingID string) error {
-	path := fmt.Sprintf("/bookings/%s/cancel", bookingID)
-	_, err := c.makeRequest(http.MethodPost, path, nil)
+func (c *Client) CancelBooking(ctx context.Context, bookingID string) error {
+	path := fmt.Sprintf("/bookings/%s/cancel", url.PathEscape(bookingID))
+	_, err := c.makeRequest(ctx, http.MethodPost, path, nil)
 	return err
 }
@@ -0,0 +1,126 @@
+package calcom
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIError is an error response from the Cal.com API
+type APIError struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+	// Code is Cal.com's error code, e.g. "no_available_users_found_error", if the response had one
+	Code string
+	// Message is the error message from the response, or the status text
+	Message string
+	// RequestID identifies the request in Cal.com's logs, if the response had one
+	RequestID string
+}
+
+// Error describes the error
+func (e *APIError) Error() string {
+	details := fmt.Sprintf("status code: %d", e.StatusCode)
+	if e.Code != "" {
+		details += ", code: " + e.Code
+	}
+	if e.RequestID != "" {
+		details += ", request ID: " + e.RequestID
+	}
+	return fmt.Sprintf("Cal.com API error: %s (%s)", e.Message, details)
+}
+
+// ErrUnsupported is returned for operations the configured Cal.com API version doesn't offer
+var ErrUnsupported = errors.New("not supported by this Cal.com API version")
+
+// conflictCodes are the Cal.com error codes meaning the requested time can't be booked
+var conflictCodes = map[string]bool{
+	"no_available_users_found_error":           true,
+	"booking_time_out_of_bounds_error":         true,
+	"fixed_hosts_unavailable_for_booking":      true,
+	"already_signed_up_for_this_booking_error": true,
+}
+
+// newAPIError builds an APIError from an error response. Cal.com answers with
+// {"message": "...", "code": "..."} or {"error": "..."} in v1 and {"error": {"code": "...", "message": "..."}} in v2;
+// anything else keeps the status text as message.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    http.StatusText(resp.StatusCode),
+		RequestID:  resp.Header.Get("X-Request-Id"),
+	}
+
+	var payload struct {
+		Message string          `json:"message"`
+		Code    string          `json:"code"`
+		Error   json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return apiErr
+	}
+	var nested struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	var errorText string
+	if json.Unmarshal(payload.Error, &nested) == nil {
+		payload.Code, payload.Message = firstNonEmpty(payload.Code, nested.Code), firstNonEmpty(payload.Message, nested.Message)
+	} else if json.Unmarshal(payload.Error, &errorText) == nil {
+		payload.Message = firstNonEmpty(payload.Message, errorText)
+	}
+
+	// v1 often reports the error code as the message
+	if payload.Code == "" && conflictCodes[payload.Message] {
+		payload.Code = payload.Message
+	}
+	apiErr.Code = payload.Code
+	apiErr.Message = firstNonEmpty(payload.Message, apiErr.Message)
+	return apiErr
+}
+
+// firstNonEmpty returns the first of values that isn't empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// asAPIError returns the APIError in err's chain, if any
+func asAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
+}
+
+// IsNotFound reports whether err means the requested booking, event type or schedule doesn't exist
+func IsNotFound(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsConflict reports whether err means the request conflicts with the calendar's state,
+// e.g. the slot is no longer available
+func IsConflict(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && (apiErr.StatusCode == http.StatusConflict || conflictCodes[strings.ToLower(apiErr.Code)])
+}
+
+// IsRateLimited reports whether err means too many requests were made
+func IsRateLimited(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+// IsAuth reports whether err means the API key is missing, invalid or lacks permission
+func IsAuth(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden)
+}
@@ -0,0 +1,169 @@
+package calcom
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how failed Cal.com requests are retried
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt; 0 disables retries
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles with every further retry
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff. A Retry-After longer than this is not waited for.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is used when no retry policy is configured
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  250 * time.Millisecond,
+	MaxDelay:   10 * time.Second,
+}
+
+// Cal.com allows 120 requests per minute per API key by default
+const (
+	defaultRateLimit = 120
+	defaultRateBurst = 10
+)
+
+// SetRetryPolicy sets how failed requests are retried
+func (t *transport) SetRetryPolicy(policy RetryPolicy) {
+	t.retry = policy
+}
+
+// SetRateLimit limits requests to requestsPerMinute, allowing bursts of up to burst requests.
+// A requestsPerMinute below 1 disables the limit.
+func (t *transport) SetRateLimit(requestsPerMinute, burst int) {
+	if requestsPerMinute <= 0 {
+		t.limiter = nil
+		return
+	}
+	t.limiter = newRateLimiter(float64(requestsPerMinute)/60, burst)
+}
+
+// isIdempotentMethod reports whether a request can be repeated without changing the outcome
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryDelay decides whether the attempt-th retry of a request should be made, and after how long.
+// Rate-limited requests were not processed and are retried for any method; server and network
+// errors are only retried for idempotent methods.
+func (p RetryPolicy) retryDelay(method string, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= p.MaxRetries {
+		return 0, false
+	}
+	switch {
+	case err != nil || resp.StatusCode >= 500:
+		if !isIdempotentMethod(method) {
+			return 0, false
+		}
+		return p.backoff(attempt), true
+	case resp.StatusCode == http.StatusTooManyRequests:
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if retryAfter > p.MaxDelay {
+				return 0, false
+			}
+			return retryAfter, true
+		}
+		return p.backoff(attempt), true
+	}
+	return 0, false
+}
+
+// backoff returns the exponential backoff before the attempt-th retry with jitter, a random
+// duration between half and all of it, so clients that failed together don't retry together
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(attempt))
+	if delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	return time.Duration(delay/2 + rand.Float64()*delay/2)
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// rateLimiter is a token bucket: it holds up to burst tokens, refilled at rate tokens per second,
+// and every request takes one
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter creates a full token bucket
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait takes a token, blocking until one is available or ctx is done. Callers queue up in order:
+// a caller that finds the bucket empty reserves the next token and sleeps until it is refilled.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if err := sleepContext(ctx, delay); err != nil {
+		// Give the reserved token back
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// sleepContext sleeps for d, returning early with ctx's error if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
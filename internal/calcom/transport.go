@@ -0,0 +1,301 @@
+package calcom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/idempotency"
+	"github.com/yourusername/cal-chatbot/internal/metrics"
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/internal/redact"
+)
+
+// transport is the HTTP plumbing shared by the Cal.com API versions: authentication,
+// rate limiting, retries and remembering bookings by idempotency key
+type transport struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	// apiKeyInQuery also sends the API key as the apiKey query parameter, as v1 accepts it
+	apiKeyInQuery bool
+	bookings      idempotency.Store
+	bookingMu     sync.Mutex
+	retry         RetryPolicy
+	limiter       *rateLimiter
+}
+
+// newTransport creates the transport for baseURL, configured from the environment
+func newTransport(baseURL, apiKey string) (*transport, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("CALCOM_API_KEY environment variable is not set")
+	}
+
+	// Remember bookings by idempotency key, across restarts if a file is configured
+	var ttl time.Duration
+	if value := os.Getenv("CALCOM_IDEMPOTENCY_TTL"); value != "" {
+		var err error
+		if ttl, err = time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("invalid CALCOM_IDEMPOTENCY_TTL: %v", err)
+		}
+	}
+	var bookings idempotency.Store = idempotency.NewMemoryStore(ttl)
+	if path := os.Getenv("CALCOM_IDEMPOTENCY_FILE"); path != "" {
+		fileStore, err := idempotency.NewFileStore(path, ttl)
+		if err != nil {
+			return nil, err
+		}
+		bookings = fileStore
+	}
+
+	t := &transport{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		apiKey:   apiKey,
+		bookings: bookings,
+		retry:    DefaultRetryPolicy,
+		limiter:  newRateLimiter(defaultRateLimit/60.0, defaultRateBurst),
+	}
+	if maxRetries := os.Getenv("CALCOM_MAX_RETRIES"); maxRetries != "" {
+		n, err := strconv.Atoi(maxRetries)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CALCOM_MAX_RETRIES: %v", err)
+		}
+		t.retry.MaxRetries = n
+	}
+	if rateLimit := os.Getenv("CALCOM_RATE_LIMIT"); rateLimit != "" {
+		n, err := strconv.Atoi(rateLimit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CALCOM_RATE_LIMIT: %v", err)
+		}
+		t.SetRateLimit(n, defaultRateBurst)
+	}
+	return t, nil
+}
+
+// SetIdempotencyStore sets the store bookings are remembered in by idempotency key
+func (t *transport) SetIdempotencyStore(store idempotency.Store) {
+	t.bookings = store
+}
+
+// makeRequest makes an HTTP request to the Cal.com API
+func (t *transport) makeRequest(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+	return t.makeRequestWithHeaders(ctx, method, path, body, nil)
+}
+
+// makeRequestWithHeaders makes an HTTP request to the Cal.com API with additional headers.
+// Requests wait for the rate limiter and are retried according to the retry policy.
+func (t *transport) makeRequestWithHeaders(ctx context.Context, method, path string, body interface{}, headers map[string]string) ([]byte, error) {
+	var bodyBytes []byte
+	if body != nil {
+		var err error
+		bodyBytes, err = json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %v", err)
+		}
+	}
+
+	requestURL := t.baseURL + path
+	if t.apiKeyInQuery {
+		// v1 only accepts the API key as a query parameter. It is masked in errors and logs.
+		separator := "?"
+		if strings.Contains(requestURL, "?") {
+			separator = "&"
+		}
+		requestURL += separator + "apiKey=" + url.QueryEscape(t.apiKey)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter.wait(ctx); err != nil {
+			return nil, fmt.Errorf("failed to make request: %v", err)
+		}
+		resp, respBody, err := t.doRequest(ctx, method, requestURL, bodyBytes, headers)
+		if err == nil && resp.StatusCode < 400 {
+			return respBody, nil
+		}
+
+		delay, retry := t.retry.retryDelay(method, attempt, resp, err)
+		if !retry || ctx.Err() != nil {
+			if err != nil {
+				return nil, err
+			}
+			return nil, newAPIError(resp, respBody)
+		}
+		reason := fmt.Sprint(err)
+		if err == nil {
+			reason = fmt.Sprintf("status code %d", resp.StatusCode)
+		}
+		slog.InfoContext(ctx, "makeRequest: request failed, retrying", "method", method, "path", redact.URL(path), "reason", reason, "retry", attempt+1, "max_retries", t.retry.MaxRetries, "delay", delay)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, fmt.Errorf("failed to make request: %v", err)
+		}
+	}
+}
+
+// doRequest makes a single attempt of a request and reads the response body.
+// Errors never contain the request URL, which may carry the API key and email addresses.
+func (t *transport) doRequest(ctx context.Context, method, requestURL string, body []byte, headers map[string]string) (*http.Response, []byte, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, bodyReader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %v", unwrapURLError(err))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", t.apiKey))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	start := time.Now()
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		metrics.ObserveCalcomRequest(method, req.URL.Path, 0, time.Since(start))
+		return nil, nil, fmt.Errorf("failed to make request: %s %s: %v", method, req.URL.Path, unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+	metrics.ObserveCalcomRequest(method, req.URL.Path, resp.StatusCode, time.Since(start))
+	slog.DebugContext(ctx, "Cal.com request", "method", method, "path", req.URL.Path, "status", resp.StatusCode, "duration", time.Since(start))
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+	return resp, respBody, nil
+}
+
+// unwrapURLError returns the cause of a *url.Error, whose message includes the full request URL
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// bookOnce books an event with book unless it was already booked for the booking's idempotency
+// key, deriving the key from the booking if it has none. If book fails, the attendee's events are
+// looked up with getEvents in case the booking was created anyway, e.g. when the request timed out.
+func (t *transport) bookOnce(ctx context.Context, booking models.BookingRequest, book func(ctx context.Context, key string) (*models.Event, error), getEvents func(ctx context.Context, email string) ([]models.Event, error)) (*models.Event, error) {
+	// Serialize bookings so a double submit can't race past the idempotency check
+	t.bookingMu.Lock()
+	defer t.bookingMu.Unlock()
+
+	key := booking.IdempotencyKey
+	if key == "" {
+		key = idempotency.BookingKey(booking)
+	}
+	if event, ok := t.activeBooking(ctx, key, getEvents); ok {
+		slog.InfoContext(ctx, "BookEvent: returning booking already made for idempotency key", "uid", event.UID, "idempotency_key", key)
+		return event, nil
+	}
+
+	event, err := book(ctx, key)
+	if err != nil {
+		// Look for the booking even if ctx is done, since that may be why the request failed
+		lookupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), t.httpClient.Timeout)
+		defer cancel()
+		existing, found := findBooking(lookupCtx, booking, getEvents)
+		if !found {
+			return nil, err
+		}
+		slog.InfoContext(ctx, "BookEvent: booking was created despite the error", "uid", existing.UID, "error", err)
+		event = existing
+	}
+
+	t.rememberBooking(ctx, key, booking.Email, event)
+	return event, nil
+}
+
+// activeBooking returns the event booked for an idempotency key, if any and still active.
+// The attendee's events are looked up with getEvents so that a booking cancelled or moved
+// elsewhere, e.g. in the Cal.com app, is forgotten instead of replayed. If the lookup fails
+// the record is trusted, as booking again could make a duplicate.
+func (t *transport) activeBooking(ctx context.Context, key string, getEvents func(ctx context.Context, email string) ([]models.Event, error)) (*models.Event, bool) {
+	if t.bookings == nil {
+		return nil, false
+	}
+	record, ok := t.bookings.Get(key)
+	if !ok {
+		return nil, false
+	}
+	if record.Email == "" {
+		return &record.Event, true
+	}
+
+	events, err := getEvents(ctx, record.Email)
+	if err != nil {
+		slog.ErrorContext(ctx, "BookEvent: failed to check whether the booking is still active", "uid", record.BookingUID, "error", err)
+		return &record.Event, true
+	}
+	for _, event := range events {
+		if sameBooking(event, record.Event) && !event.Cancelled() {
+			return &record.Event, true
+		}
+	}
+	slog.InfoContext(ctx, "BookEvent: booking made for idempotency key is no longer active", "uid", record.BookingUID, "idempotency_key", key)
+	t.forgetBooking(ctx, record.Event.ID)
+	return nil, false
+}
+
+// forgetBooking removes the idempotency records of a cancelled or rescheduled booking, so the
+// same booking can be made again. A failure is only logged, since the change itself succeeded.
+func (t *transport) forgetBooking(ctx context.Context, eventID string) {
+	if t.bookings == nil {
+		return
+	}
+	if err := t.bookings.Forget(eventID); err != nil {
+		slog.ErrorContext(ctx, "forgetBooking: failed to remove idempotency records", "event_id", eventID, "error", err)
+	}
+}
+
+// rememberBooking records the event booked for an idempotency key and the attendee's email.
+// A failure is only logged, since the booking itself succeeded.
+func (t *transport) rememberBooking(ctx context.Context, key, email string, event *models.Event) {
+	if t.bookings == nil {
+		return
+	}
+	record := idempotency.Record{Key: key, BookingUID: event.UID, Event: *event, Email: email, CreatedAt: time.Now()}
+	if err := t.bookings.Put(record); err != nil {
+		slog.ErrorContext(ctx, "BookEvent: failed to record idempotency key", "idempotency_key", key, "error", err)
+	}
+}
+
+// findBooking looks for an active booking of the attendee at exactly the requested time
+func findBooking(ctx context.Context, booking models.BookingRequest, getEvents func(ctx context.Context, email string) ([]models.Event, error)) (*models.Event, bool) {
+	events, err := getEvents(ctx, booking.Email)
+	if err != nil {
+		slog.ErrorContext(ctx, "BookEvent: failed to look for an existing booking", "error", err)
+		return nil, false
+	}
+	for _, event := range events {
+		if event.StartTime.Equal(booking.Start) && event.EndTime.Equal(booking.End) && !event.Cancelled() {
+			return &event, true
+		}
+	}
+	return nil, false
+}
+
+// sameBooking reports whether two events are the same booking, by UID or else by ID
+func sameBooking(a, b models.Event) bool {
+	if a.UID != "" && b.UID != "" {
+		return a.UID == b.UID
+	}
+	return a.ID != "" && a.ID == b.ID
+}
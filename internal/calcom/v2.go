@@ -0,0 +1,349 @@
+package calcom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// defaultV2BaseURL is the Cal.com API v2 endpoint used when CALCOM_API_URL is not set
+const defaultV2BaseURL = "https://api.cal.com/v2"
+
+// Each v2 endpoint group is versioned separately with the cal-api-version header
+const (
+	bookingsAPIVersion   = "2024-08-13"
+	slotsAPIVersion      = "2024-09-04"
+	eventTypesAPIVersion = "2024-06-14"
+	schedulesAPIVersion  = "2024-06-11"
+)
+
+// V2Client is a Cal.com API v2 client. It authenticates with the bearer token only.
+// Bookings are identified by their UID, which is also used as the event ID.
+type V2Client struct {
+	*transport
+}
+
+// Ensure V2Client satisfies CalendarBackend
+var (
+	_ CalendarBackend   = (*V2Client)(nil)
+	_ IdempotentBackend = (*V2Client)(nil)
+)
+
+// NewV2Client creates a new Cal.com API v2 client
+func NewV2Client() (*V2Client, error) {
+	baseURL := os.Getenv("CALCOM_API_URL")
+	if baseURL == "" {
+		baseURL = defaultV2BaseURL
+	}
+	t, err := newTransport(baseURL, os.Getenv("CALCOM_API_KEY"))
+	if err != nil {
+		return nil, err
+	}
+	return &V2Client{transport: t}, nil
+}
+
+// call makes a v2 request with the given API version and decodes the data of the response
+// envelope, {"status": "success", "data": ...}, into out unless out is nil
+func (c *V2Client) call(ctx context.Context, method, path, version string, body, out interface{}, headers map[string]string) error {
+	requestHeaders := map[string]string{"cal-api-version": version}
+	for name, value := range headers {
+		requestHeaders[name] = value
+	}
+	respBody, err := c.makeRequestWithHeaders(ctx, method, path, body, requestHeaders)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+
+	var envelope struct {
+		Status string          `json:"status"`
+		Data   json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &envelope); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %v", err)
+	}
+	if envelope.Status != "success" {
+		return fmt.Errorf("unexpected response status %q", envelope.Status)
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response data: %v", err)
+	}
+	return nil
+}
+
+// v2Booking is a booking as returned by the v2 API
+type v2Booking struct {
+	ID          int               `json:"id"`
+	UID         string            `json:"uid"`
+	EventTypeID int               `json:"eventTypeId"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Status      string            `json:"status"`
+	Start       time.Time         `json:"start"`
+	End         time.Time         `json:"end"`
+	Location    string            `json:"location"`
+	Attendees   []models.Attendee `json:"attendees"`
+	Hosts       []models.Attendee `json:"hosts"`
+}
+
+// booking converts the v2 booking to a Booking; its first host is the organizer
+func (b v2Booking) booking() models.Booking {
+	booking := models.Booking{
+		ID:          b.ID,
+		UID:         b.UID,
+		EventTypeID: b.EventTypeID,
+		Title:       b.Title,
+		Description: b.Description,
+		StartTime:   b.Start,
+		EndTime:     b.End,
+		Status:      b.Status,
+		Location:    b.Location,
+		Attendees:   b.Attendees,
+	}
+	if len(b.Hosts) > 0 {
+		booking.Organizer = &b.Hosts[0]
+	}
+	return booking
+}
+
+// event converts the booking to an Event identified by its UID
+func (b v2Booking) event() models.Event {
+	event := b.booking().Event()
+	event.ID = b.UID
+	return event
+}
+
+// GetEvents retrieves the bookings with the given attendee, or all bookings if email is empty
+func (c *V2Client) GetEvents(ctx context.Context, email string) ([]models.Event, error) {
+	path := "/bookings"
+	if email != "" {
+		path += "?attendeeEmail=" + url.QueryEscape(email)
+	}
+	var bookings []v2Booking
+	if err := c.call(ctx, http.MethodGet, path, bookingsAPIVersion, nil, &bookings, nil); err != nil {
+		return nil, err
+	}
+	events := make([]models.Event, len(bookings))
+	for i, booking := range bookings {
+		events[i] = booking.event()
+	}
+	return events, nil
+}
+
+// GetAvailableSlots retrieves the start times of the available slots of an event type.
+// Slots are requested in the timezone of startDate.
+func (c *V2Client) GetAvailableSlots(ctx context.Context, eventTypeID int, startDate, endDate time.Time) ([]time.Time, error) {
+	query := url.Values{}
+	query.Set("eventTypeId", fmt.Sprint(eventTypeID))
+	query.Set("start", startDate.UTC().Format(time.RFC3339))
+	query.Set("end", endDate.UTC().Format(time.RFC3339))
+	query.Set("timeZone", startDate.Location().String())
+
+	// Slots are grouped by date: {"2050-09-05": [{"start": "2050-09-05T09:00:00.000+02:00"}]}
+	var days map[string][]struct {
+		Start time.Time `json:"start"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/slots?"+query.Encode(), slotsAPIVersion, nil, &days, nil); err != nil {
+		return nil, err
+	}
+	var slots []time.Time
+	for _, day := range days {
+		for _, slot := range day {
+			slots = append(slots, slot.Start)
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Before(slots[j]) })
+	return slots, nil
+}
+
+// BookEvent books a new event. The length of the booking is the event type's.
+// Booking again with the same idempotency key returns the event booked the first time.
+func (c *V2Client) BookEvent(ctx context.Context, booking models.BookingRequest) (*models.Event, error) {
+	return c.bookOnce(ctx, booking, func(ctx context.Context, key string) (*models.Event, error) {
+		timeZone := booking.TimeZone
+		if timeZone == "" {
+			timeZone = "UTC"
+		}
+		payload := map[string]interface{}{
+			"start":       booking.Start.UTC().Format(time.RFC3339),
+			"eventTypeId": booking.EventTypeID,
+			"attendee": map[string]interface{}{
+				"name":     booking.Name,
+				"email":    booking.Email,
+				"timeZone": timeZone,
+				"language": "en",
+			},
+			"metadata": map[string]interface{}{"idempotencyKey": key},
+		}
+		// The title and notes are booking fields; the location is one the attendee defines
+		responses := map[string]interface{}{}
+		if booking.Title != "" {
+			responses["title"] = booking.Title
+		}
+		if booking.Notes != "" {
+			responses["notes"] = booking.Notes
+		}
+		if len(responses) > 0 {
+			payload["bookingFieldsResponses"] = responses
+		}
+		if booking.Location != "" {
+			payload["location"] = map[string]interface{}{"type": "attendeeDefined", "location": booking.Location}
+		}
+
+		var created v2Booking
+		if err := c.call(ctx, http.MethodPost, "/bookings", bookingsAPIVersion, payload, &created, map[string]string{"Idempotency-Key": key}); err != nil {
+			return nil, err
+		}
+		event := created.event()
+		return &event, nil
+	}, c.GetEvents)
+}
+
+// BookedEvent returns the event booked for an idempotency key, if any and not cancelled since
+func (c *V2Client) BookedEvent(ctx context.Context, key string) (*models.Event, bool) {
+	return c.activeBooking(ctx, key, c.GetEvents)
+}
+
+// CancelEvent cancels the booking with the given UID
+func (c *V2Client) CancelEvent(ctx context.Context, eventID string) error {
+	path := fmt.Sprintf("/bookings/%s/cancel", url.PathEscape(eventID))
+	if err := c.call(ctx, http.MethodPost, path, bookingsAPIVersion, map[string]interface{}{}, nil, nil); err != nil {
+		return err
+	}
+	c.forgetBooking(ctx, eventID)
+	return nil
+}
+
+// RescheduleEvent moves the booking with the given UID to newStartTime. The v2 API keeps the
+// event type's length, so newEndTime is not sent. The rescheduled booking has a new UID.
+func (c *V2Client) RescheduleEvent(ctx context.Context, eventID string, newStartTime, newEndTime time.Time) (*models.Event, error) {
+	path := fmt.Sprintf("/bookings/%s/reschedule", url.PathEscape(eventID))
+	body := map[string]interface{}{"start": newStartTime.UTC().Format(time.RFC3339)}
+	var rescheduled v2Booking
+	if err := c.call(ctx, http.MethodPost, path, bookingsAPIVersion, body, &rescheduled, nil); err != nil {
+		return nil, err
+	}
+	c.forgetBooking(ctx, eventID)
+	event := rescheduled.event()
+	return &event, nil
+}
+
+// FindBooking fetches the booking with the given UID
+func (c *V2Client) FindBooking(ctx context.Context, bookingID string) (*models.Booking, error) {
+	path := fmt.Sprintf("/bookings/%s", url.PathEscape(bookingID))
+	var found v2Booking
+	if err := c.call(ctx, http.MethodGet, path, bookingsAPIVersion, nil, &found, nil); err != nil {
+		return nil, err
+	}
+	booking := found.booking()
+	return &booking, nil
+}
+
+// EditBooking is not offered by the v2 API, whose bookings can only be rescheduled or cancelled
+func (c *V2Client) EditBooking(ctx context.Context, bookingID string, update models.BookingUpdate) (*models.Booking, error) {
+	return nil, fmt.Errorf("editing bookings is %w", ErrUnsupported)
+}
+
+// GetBookableSlots fetches the available slots of every event type between startDate and endDate,
+// ordered by start time. Slots are requested in the timezone of startDate.
+func (c *V2Client) GetBookableSlots(ctx context.Context, startDate, endDate time.Time) ([]models.Slot, error) {
+	return bookableSlots(ctx, c, startDate, endDate)
+}
+
+// v2EventType is an event type as returned by the v2 API
+type v2EventType struct {
+	ID              int    `json:"id"`
+	Title           string `json:"title"`
+	Slug            string `json:"slug"`
+	Description     string `json:"description"`
+	LengthInMinutes int    `json:"lengthInMinutes"`
+}
+
+// eventType converts the v2 event type to an EventType
+func (e v2EventType) eventType() models.EventType {
+	return models.EventType{
+		ID:          e.ID,
+		Title:       e.Title,
+		Description: e.Description,
+		Slug:        e.Slug,
+		Length:      e.LengthInMinutes,
+		LengthUnit:  "minutes",
+	}
+}
+
+// GetEventTypes fetches all event types of the user
+func (c *V2Client) GetEventTypes(ctx context.Context) ([]models.EventType, error) {
+	var eventTypes []v2EventType
+	if err := c.call(ctx, http.MethodGet, "/event-types", eventTypesAPIVersion, nil, &eventTypes, nil); err != nil {
+		return nil, err
+	}
+	result := make([]models.EventType, len(eventTypes))
+	for i, eventType := range eventTypes {
+		result[i] = eventType.eventType()
+	}
+	return result, nil
+}
+
+// CreateEventType creates a new event type. The length is taken to be in minutes.
+func (c *V2Client) CreateEventType(ctx context.Context, req models.EventTypeCreateRequest) (*models.EventType, error) {
+	payload := map[string]interface{}{
+		"title":           req.Title,
+		"slug":            req.Slug,
+		"description":     req.Description,
+		"lengthInMinutes": req.Length,
+	}
+	var created v2EventType
+	if err := c.call(ctx, http.MethodPost, "/event-types", eventTypesAPIVersion, payload, &created, nil); err != nil {
+		return nil, err
+	}
+	eventType := created.eventType()
+	return &eventType, nil
+}
+
+// FindAllSchedules fetches all schedules
+func (c *V2Client) FindAllSchedules(ctx context.Context) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	if err := c.call(ctx, http.MethodGet, "/schedules", schedulesAPIVersion, nil, &schedules, nil); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// CreateSchedule creates a new schedule
+func (c *V2Client) CreateSchedule(ctx context.Context, name, timeZone string) (*models.Schedule, error) {
+	payload := map[string]interface{}{
+		"name":      name,
+		"timeZone":  timeZone,
+		"isDefault": false,
+	}
+	var created models.Schedule
+	if err := c.call(ctx, http.MethodPost, "/schedules", schedulesAPIVersion, payload, &created, nil); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// EditSchedule edits an existing schedule by ID
+func (c *V2Client) EditSchedule(ctx context.Context, scheduleID string, update models.ScheduleUpdate) (*models.Schedule, error) {
+	path := fmt.Sprintf("/schedules/%s", url.PathEscape(scheduleID))
+	var updated models.Schedule
+	if err := c.call(ctx, http.MethodPatch, path, schedulesAPIVersion, update, &updated, nil); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// RemoveSchedule deletes a schedule by ID
+func (c *V2Client) RemoveSchedule(ctx context.Context, scheduleID string) error {
+	path := fmt.Sprintf("/schedules/%s", url.PathEscape(scheduleID))
+	return c.call(ctx, http.MethodDelete, path, schedulesAPIVersion, nil, nil, nil)
+}
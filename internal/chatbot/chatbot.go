@@ -2,28 +2,28 @@ package chatbot
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
+	"strconv"
 	"time"
 
+	goopenai "github.com/sashabaranov/go-openai"
 	"github.com/yourusername/cal-chatbot/internal/calcom"
 	openai "github.com/yourusername/cal-chatbot/internal/chatbot/openai"
 	"github.com/yourusername/cal-chatbot/internal/models"
 )
 
-const historyDir = "history"
-
 // Chatbot represents the chatbot instance
 type Chatbot struct {
 	openaiClient *openai.Client
-	calcomClient *calcom.Client
+	calcomClient calcom.CalendarBackend
 	model        string
 }
 
-// NewChatbot creates a new chatbot instance
-func NewChatbot() (*Chatbot, error) {
+// NewChatbot creates a new chatbot instance using the given calendar backend.
+// If backend is nil, a Cal.com client for CALCOM_API_VERSION is created from the environment.
+func NewChatbot(backend calcom.CalendarBackend) (*Chatbot, error) {
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
 		return nil, fmt.Errorf("OPENAI_API_KEY environment variable is not set")
@@ -34,14 +34,76 @@ func NewChatbot() (*Chatbot, error) {
 		model = "gpt-4-turbo"
 	}
 
-	calcomClient, err := calcom.NewClient()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Cal.com client: %v", err)
+	if backend == nil {
+		calcomClient, err := calcom.NewBackend()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Cal.com client: %v", err)
+		}
+		backend = calcomClient
+	}
+
+	config := goopenai.DefaultConfig(apiKey)
+	if baseURL := os.Getenv("OPENAI_BASE_URL"); baseURL != "" {
+		config.BaseURL = baseURL
+	}
+
+	openaiClient := openai.NewClientWithConfig(config, backend, model)
+	if maxIterations := os.Getenv("OPENAI_MAX_ITERATIONS"); maxIterations != "" {
+		n, err := strconv.Atoi(maxIterations)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OPENAI_MAX_ITERATIONS: %v", err)
+		}
+		openaiClient.SetMaxIterations(n)
+	}
+	if maxParallel := os.Getenv("OPENAI_MAX_PARALLEL_TOOL_CALLS"); maxParallel != "" {
+		n, err := strconv.Atoi(maxParallel)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OPENAI_MAX_PARALLEL_TOOL_CALLS: %v", err)
+		}
+		openaiClient.SetMaxParallelToolCalls(n)
+	}
+
+	if timeout := os.Getenv("TOOL_CALL_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TOOL_CALL_TIMEOUT: %v", err)
+		}
+		openaiClient.SetToolCallTimeout(d)
+	}
+
+	if prices := os.Getenv("OPENAI_PRICES"); prices != "" {
+		var table map[string]openai.Price
+		if err := json.Unmarshal([]byte(prices), &table); err != nil {
+			return nil, fmt.Errorf("invalid OPENAI_PRICES: %v", err)
+		}
+		openaiClient.SetPrices(table)
+	}
+
+	systemPrompt := os.Getenv("SYSTEM_PROMPT")
+	if path := os.Getenv("SYSTEM_PROMPT_FILE"); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SYSTEM_PROMPT_FILE: %v", err)
+		}
+		systemPrompt = string(content)
+	}
+	if systemPrompt != "" {
+		if err := openaiClient.SetSystemPrompt(systemPrompt); err != nil {
+			return nil, err
+		}
+	}
+	openaiClient.SetUsername(os.Getenv("CALCOM_USERNAME"))
+	if ttl := os.Getenv("PENDING_ACTION_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PENDING_ACTION_TTL: %v", err)
+		}
+		openaiClient.SetPendingActionTTL(d)
 	}
 
 	return &Chatbot{
-		openaiClient: openai.NewClient(apiKey, calcomClient, model),
-		calcomClient: calcomClient,
+		openaiClient: openaiClient,
+		calcomClient: backend,
 		model:        model,
 	}, nil
 }
@@ -52,59 +114,21 @@ func (c *Chatbot) CheckOpenAIConnection() error {
 }
 
 // ProcessMessage delegates to the OpenAI client
-func (c *Chatbot) ProcessMessage(ctx context.Context, messages []models.ChatMessage) (string, error) {
+func (c *Chatbot) ProcessMessage(ctx context.Context, messages []models.ChatMessage) (string, []models.ExecutedFunctionCall, error) {
 	return c.openaiClient.ProcessMessage(ctx, messages)
 }
 
-// SaveMessage appends a message to the conversation history file
-func SaveMessage(conversationID, role, message string) error {
-	if err := os.MkdirAll(historyDir, 0755); err != nil {
-		return err
-	}
-	filePath := filepath.Join(historyDir, conversationID+".txt")
-	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	line := fmt.Sprintf("[%s] [%s]: %s\n", time.Now().Format(time.RFC3339), role, message)
-	_, err = f.WriteString(line)
-	return err
+// ConfirmAction executes a booking, cancellation or reschedule the user confirmed
+func (c *Chatbot) ConfirmAction(ctx context.Context, conversationID, token string) (models.PendingAction, models.ExecutedFunctionCall, error) {
+	return c.openaiClient.ConfirmAction(ctx, conversationID, token)
 }
 
-// LoadHistory loads all messages for a conversation
-func LoadHistory(conversationID string) ([]string, error) {
-	filePath := filepath.Join(historyDir, conversationID+".txt")
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, err
-	}
-	lines := strings.Split(string(data), "\n")
-	return lines, nil
+// GetEvents retrieves the events booked with the given attendee from the calendar backend
+func (c *Chatbot) GetEvents(ctx context.Context, email string) ([]models.Event, error) {
+	return c.calcomClient.GetEvents(ctx, email)
 }
 
-// SearchHistory searches all conversation files for a term and returns matching conversation IDs
-func SearchHistory(term string) ([]string, error) {
-	if err := os.MkdirAll(historyDir, 0755); err != nil {
-		return nil, err
-	}
-	entries, err := os.ReadDir(historyDir)
-	if err != nil {
-		return nil, err
-	}
-	var matches []string
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".txt") {
-			continue
-		}
-		filePath := filepath.Join(historyDir, entry.Name())
-		data, err := os.ReadFile(filePath)
-		if err != nil {
-			continue
-		}
-		if strings.Contains(strings.ToLower(string(data)), strings.ToLower(term)) {
-			matches = append(matches, strings.TrimSuffix(entry.Name(), ".txt"))
-		}
-	}
-	return matches, nil
+// ProcessMessageStream delegates to the OpenAI client, relaying progress events to handler
+func (c *Chatbot) ProcessMessageStream(ctx context.Context, messages []models.ChatMessage, handler func(models.StreamEvent)) (string, []models.ExecutedFunctionCall, error) {
+	return c.openaiClient.ProcessMessageStream(ctx, messages, handler)
 }
@@ -1,94 +1,443 @@
 package openai
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"math/rand"
+	"log/slog"
+	"net/mail"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/yourusername/cal-chatbot/internal/calcom"
+	"github.com/yourusername/cal-chatbot/internal/eventtypes"
+	"github.com/yourusername/cal-chatbot/internal/idempotency"
 	"github.com/yourusername/cal-chatbot/internal/models"
 )
 
-// bookMeeting handles the bookMeeting function call
-func (c *Client) bookMeeting(args string) (interface{}, error) {
-	log.Printf("[INFO] bookMeeting called with args: %s", args)
+// bookMeeting handles the bookMeeting function call. The booking is proposed for the
+// user to confirm rather than made right away.
+func (c *Client) bookMeeting(ctx context.Context, args string) (interface{}, error) {
+	slog.InfoContext(ctx, "bookMeeting called", "args", args)
+	booking, err := c.prepareBooking(ctx, args)
+	var toolResult toolResultError
+	if errors.As(err, &toolResult) {
+		slog.InfoContext(ctx, "bookMeeting: not bookable as requested", "reason", err)
+		return toolResult.result(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	location := userLocation(ctx)
+	summary := fmt.Sprintf("Book '%s' with %s <%s>, %s", booking.Title, booking.Name, booking.Email, formatEventTime(booking.Start, booking.End, location))
+	result, err := c.proposeAction(ctx, "bookMeeting", booking, summary)
+	if err == nil {
+		updateDraft(ctx, func(draft *models.BookingDraft) { draft.Status = models.DraftAwaitingConfirmation })
+	}
+	return result, err
+}
+
+// bookDirectly books a meeting from a booking form payload without asking for confirmation
+func (c *Client) bookDirectly(ctx context.Context, args string) (interface{}, error) {
+	ctx, cancel := c.withToolCallTimeout(ctx)
+	defer cancel()
+	booking, err := c.prepareBooking(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return c.executeBooking(ctx, booking)
+}
+
+// toolResultError is an error the model can help the user resolve, e.g. by asking for a
+// missing detail. It is returned to the model as a tool result rather than a failure.
+type toolResultError interface {
+	error
+	result() map[string]interface{}
+}
+
+// bookingInputError means a booking can't be prepared until the user provides missing details
+// or chooses between event types. Nothing is made up in their place.
+type bookingInputError struct {
+	// Missing are the booking fields the user has to provide, e.g. "email"
+	Missing []string
+	// EventTypes are the event types the user has to choose from
+	EventTypes []models.EventType
+	// Problem explains why the event type couldn't be chosen, if it couldn't
+	Problem string
+}
+
+// fieldDescriptions describe the booking fields for the user
+var fieldDescriptions = map[string]string{
+	"name":      "their name",
+	"email":     "their email address",
+	"startTime": "when the meeting should start",
+	"endTime":   "when the meeting should end",
+}
+
+// Error tells the user what is needed
+func (e *bookingInputError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		details := make([]string, len(e.Missing))
+		for i, field := range e.Missing {
+			details[i] = fieldDescriptions[field]
+		}
+		parts = append(parts, "Please provide "+joinWords(details)+".")
+	}
+	if len(e.EventTypes) > 0 {
+		options := make([]string, len(e.EventTypes))
+		for i, eventType := range e.EventTypes {
+			options[i] = fmt.Sprintf("%s (%d minutes)", eventType.Title, eventType.Length)
+		}
+		parts = append(parts, strings.TrimSpace(e.Problem+" Which event type should be booked: "+joinWords(options)+"?"))
+	}
+	return strings.Join(parts, " ")
+}
+
+// result is the tool result asking the model to get the details from the user
+func (e *bookingInputError) result() map[string]interface{} {
+	result := map[string]interface{}{
+		"status":  "needs_input",
+		"message": "Nothing has been booked. Ask the user, and don't make up any of these details: " + e.Error(),
+	}
+	if len(e.Missing) > 0 {
+		result["missing"] = e.Missing
+	}
+	if len(e.EventTypes) > 0 {
+		result["eventTypeOptions"] = e.EventTypes
+	}
+	return result
+}
+
+// joinWords joins words into a list such as "a, b or c"
+func joinWords(words []string) string {
+	if len(words) < 2 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " or " + words[len(words)-1]
+}
+
+// resolveEventType finds the event type the user described, e.g. "30 min intro". It returns a
+// bookingInputError listing the options when the description fits several or none of them.
+func (c *Client) resolveEventType(ctx context.Context, description string) (models.EventType, error) {
+	eventTypes := c.cachedEventTypes(ctx)
+	if len(eventTypes) == 0 {
+		var err error
+		if eventTypes, err = c.calcomClient.GetEventTypes(ctx); err != nil {
+			slog.ErrorContext(ctx, "bookMeeting: failed to fetch event types", "error", err)
+			return models.EventType{}, calendarError("fetch event types", "the event type list", err)
+		}
+		if len(eventTypes) == 0 {
+			return models.EventType{}, fmt.Errorf("There are no event types to book; one has to be created first.")
+		}
+	}
+
+	if description == "" {
+		if len(eventTypes) == 1 {
+			return eventTypes[0], nil
+		}
+		return models.EventType{}, &bookingInputError{EventTypes: eventTypes}
+	}
+	matches := eventtypes.Resolve(description, eventTypes)
+	switch len(matches) {
+	case 1:
+		slog.InfoContext(ctx, "bookMeeting: resolved event type", "description", description, "event_type_id", matches[0].ID)
+		return matches[0], nil
+	case 0:
+		return models.EventType{}, &bookingInputError{EventTypes: eventTypes, Problem: fmt.Sprintf("No event type matches %q.", description)}
+	default:
+		return models.EventType{}, &bookingInputError{EventTypes: matches, Problem: fmt.Sprintf("Several event types match %q.", description)}
+	}
+}
+
+// prepareBooking parses and checks the bookMeeting arguments and builds the booking request.
+// Details left out are taken from the conversation's booking draft, and the draft is updated
+// with the ones given. Missing attendee details and event types that can't be told apart are
+// reported with a bookingInputError, and times that can't be booked with a slotUnavailableError.
+func (c *Client) prepareBooking(ctx context.Context, args string) (models.BookingRequest, error) {
 	var params struct {
-		EventTypeID int    `json:"eventTypeId"`
-		StartTime   string `json:"startTime"`
-		EndTime     string `json:"endTime"`
-		Name        string `json:"name"`
-		Email       string `json:"email"`
-		Notes       string `json:"notes,omitempty"`
+		EventTypeID int `json:"eventTypeId"`
+		// EventType describes the event type in the user's words when the ID isn't known
+		EventType string `json:"eventType"`
+		StartTime string `json:"startTime"`
+		EndTime   string `json:"endTime"`
+		Name      string `json:"name"`
+		Email     string `json:"email"`
+		Notes     string `json:"notes,omitempty"`
+		Location  string `json:"location,omitempty"`
+		// IdempotencyKey lets booking forms make resubmitting the same form a no-op
+		IdempotencyKey string `json:"idempotencyKey,omitempty"`
 	}
 
 	if err := json.Unmarshal([]byte(args), &params); err != nil {
-		log.Printf("[ERROR] bookMeeting: failed to parse args: %v", err)
-		return nil, fmt.Errorf("failed to parse booking parameters: %v", err)
+		slog.ErrorContext(ctx, "bookMeeting: failed to parse args", "error", err)
+		return models.BookingRequest{}, fmt.Errorf("failed to parse booking parameters: %v", err)
+	}
+
+	// Details the user gave in earlier turns fill in the ones left out now
+	draft := bookingDraft(ctx)
+	params.Name, params.Email = strings.TrimSpace(params.Name), strings.TrimSpace(params.Email)
+	if params.EventTypeID == 0 && strings.TrimSpace(params.EventType) == "" {
+		params.EventTypeID = draft.EventTypeID
+	}
+	if params.Name == "" {
+		params.Name = draft.AttendeeName
+	}
+	if params.Email == "" {
+		params.Email = draft.AttendeeEmail
+	}
+	if strings.TrimSpace(params.StartTime) == "" && draft.Start != nil {
+		params.StartTime = draft.Start.Format(time.RFC3339)
+	}
+	if params.Notes == "" {
+		params.Notes = draft.Notes
+	}
+	if params.Location == "" {
+		params.Location = draft.Location
+	}
+
+	// The verified email is the user's own; other addresses must be real ones the user gave
+	if params.Email == "" {
+		params.Email = models.UserProfileFromContext(ctx).Email
+	}
+	needsInput := &bookingInputError{}
+	if params.Name == "" {
+		needsInput.Missing = append(needsInput.Missing, "name")
+	}
+	if !validEmail(params.Email) {
+		needsInput.Missing = append(needsInput.Missing, "email")
 	}
 
-	// If email is empty or contains 'random' or 'placeholder', generate a random email
-	if params.Email == "" || containsIgnoreCase(params.Email, "random") || containsIgnoreCase(params.Email, "placeholder") {
-		params.Email = fmt.Sprintf("randomuser+%d@example.com", time.Now().Unix())
-		log.Printf("[INFO] bookMeeting: generated random email: %s", params.Email)
+	location := userLocation(ctx)
+	var startTime, endTime time.Time
+	var err error
+	if strings.TrimSpace(params.StartTime) == "" {
+		needsInput.Missing = append(needsInput.Missing, "startTime")
+	} else if startTime, err = parseUserTime(params.StartTime, location); err != nil {
+		slog.ErrorContext(ctx, "bookMeeting: invalid start time format", "error", err)
+		return models.BookingRequest{}, fmt.Errorf("invalid start time format: %v", err)
 	}
 
-	// If event type is 0 or contains 'random', select a random event type
-	if params.EventTypeID == 0 || containsIgnoreCase(fmt.Sprint(params.EventTypeID), "random") {
-		eventTypes, err := c.calcomClient.GetEventTypes()
-		if err != nil || len(eventTypes) == 0 {
-			log.Printf("[ERROR] bookMeeting: could not fetch event types for random selection: %v", err)
-			return nil, fmt.Errorf("could not fetch event types for random selection")
+	var eventType models.EventType
+	if params.EventTypeID == 0 {
+		eventType, err = c.resolveEventType(ctx, strings.TrimSpace(params.EventType))
+		var choice *bookingInputError
+		switch {
+		case errors.As(err, &choice):
+			needsInput.EventTypes, needsInput.Problem = choice.EventTypes, choice.Problem
+		case err != nil:
+			return models.BookingRequest{}, err
+		default:
+			params.EventTypeID = eventType.ID
 		}
-		rand.Seed(time.Now().UnixNano())
-		randomType := eventTypes[rand.Intn(len(eventTypes))]
-		params.EventTypeID = randomType.ID
-		log.Printf("[INFO] bookMeeting: selected random event type ID: %d", params.EventTypeID)
+	} else {
+		// The length of the event type is unknown if it can't be found; Cal.com checks it then
+		eventType, _ = c.eventTypeByID(ctx, params.EventTypeID)
 	}
 
-	startTime, err := time.Parse(time.RFC3339, params.StartTime)
-	if err != nil {
-		log.Printf("[ERROR] bookMeeting: invalid start time format: %v", err)
-		return nil, fmt.Errorf("invalid start time format: %v", err)
+	// Without an end time the meeting lasts as long as its event type, or as drafted
+	switch {
+	case strings.TrimSpace(params.EndTime) != "":
+		if endTime, err = parseUserTime(params.EndTime, location); err != nil {
+			slog.ErrorContext(ctx, "bookMeeting: invalid end time format", "error", err)
+			return models.BookingRequest{}, fmt.Errorf("invalid end time format: %v", err)
+		}
+	case !startTime.IsZero() && eventType.Length > 0:
+		endTime = startTime.Add(time.Duration(eventType.Length) * time.Minute)
+	case !startTime.IsZero() && draft.Duration > 0:
+		endTime = startTime.Add(time.Duration(draft.Duration) * time.Minute)
+	case len(needsInput.EventTypes) == 0:
+		needsInput.Missing = append(needsInput.Missing, "endTime")
 	}
 
-	endTime, err := time.Parse(time.RFC3339, params.EndTime)
-	if err != nil {
-		log.Printf("[ERROR] bookMeeting: invalid end time format: %v", err)
-		return nil, fmt.Errorf("invalid end time format: %v", err)
+	updateDraft(ctx, func(draft *models.BookingDraft) {
+		if params.EventTypeID != 0 && params.EventTypeID != draft.EventTypeID {
+			draft.EventTypeID, draft.EventType = params.EventTypeID, eventType.Title
+		}
+		if !startTime.IsZero() {
+			draft.Start = &startTime
+		}
+		if !startTime.IsZero() && endTime.After(startTime) {
+			draft.Duration = int(endTime.Sub(startTime).Minutes())
+		}
+		if params.Name != "" {
+			draft.AttendeeName = params.Name
+		}
+		if validEmail(params.Email) {
+			draft.AttendeeEmail = params.Email
+		}
+		draft.Notes, draft.Location = params.Notes, params.Location
+		draft.Status = models.DraftCollecting
+	})
+	if len(needsInput.Missing) > 0 || len(needsInput.EventTypes) > 0 {
+		return models.BookingRequest{}, needsInput
+	}
+
+	if !endTime.After(startTime) {
+		return models.BookingRequest{}, fmt.Errorf("The meeting would end at %s, before it starts at %s.", endTime.In(location).Format(time.RFC3339), startTime.In(location).Format(time.RFC3339))
+	}
+	if length := int(endTime.Sub(startTime).Minutes()); eventType.Length > 0 && length != eventType.Length {
+		return models.BookingRequest{}, fmt.Errorf("'%s' meetings last %d minutes, not %d; leave out the end time to book one from %s.", eventType.Title, eventType.Length, length, startTime.In(location).Format(time.RFC3339))
+	}
+
+	title := fmt.Sprintf("Meeting with %s", params.Name)
+
+	booking := models.BookingRequest{
+		EventTypeID:    params.EventTypeID,
+		Start:          startTime,
+		End:            endTime,
+		Name:           params.Name,
+		Email:          params.Email,
+		Notes:          params.Notes,
+		Location:       params.Location,
+		Title:          title,
+		TimeZone:       location.String(),
+		IdempotencyKey: params.IdempotencyKey,
+	}
+	if booking.IdempotencyKey == "" {
+		booking.IdempotencyKey = idempotency.BookingKey(booking)
 	}
 
-	// Set a default title if not provided
-	title := "Cal.com Meeting"
-	if params.Name != "" {
-		title = fmt.Sprintf("Meeting with %s", params.Name)
+	// A replayed booking overlaps with itself; booking it again returns the existing event.
+	// Once that booking is cancelled or rescheduled, it is checked like any other.
+	if backend, ok := c.calcomClient.(calcom.IdempotentBackend); ok {
+		if _, booked := backend.BookedEvent(ctx, booking.IdempotencyKey); booked {
+			slog.InfoContext(ctx, "bookMeeting: booking was already made", "idempotency_key", booking.IdempotencyKey)
+			return booking, nil
+		}
 	}
 
 	// Prevent double-booking: check for overlapping events
-	events, err := c.calcomClient.GetEvents(params.Email)
+	events, err := c.calcomClient.GetEvents(ctx, params.Email)
 	if err == nil {
 		for _, event := range events {
+			if event.Cancelled() {
+				continue
+			}
 			if (startTime.Before(event.EndTime) && endTime.After(event.StartTime)) || startTime.Equal(event.StartTime) {
-				return nil, fmt.Errorf("You already have an event scheduled at that time: %s (%s to %s)", event.Title, event.StartTime.Format("2006-01-02 15:04"), event.EndTime.Format("15:04"))
+				return models.BookingRequest{}, fmt.Errorf("You already have an event scheduled at that time: %s (%s)", event.Title, formatEventTime(event.StartTime, event.EndTime, location))
 			}
 		}
 	}
 
-	log.Printf("[INFO] bookMeeting: booking event for %s (%s) from %s to %s", params.Name, params.Email, params.StartTime, params.EndTime)
-	event, err := c.calcomClient.BookEvent(models.BookingRequest{
-		EventTypeID: params.EventTypeID,
-		Start:       startTime,
-		End:         endTime,
-		Name:        params.Name,
-		Email:       params.Email,
-		Notes:       params.Notes,
-		Title:       title,
-	})
+	if err := c.checkSlot(ctx, eventType, booking); err != nil {
+		return models.BookingRequest{}, err
+	}
+	return booking, nil
+}
+
+const (
+	// alternativeSlotsWindow is how far around a requested time alternative slots are looked for
+	alternativeSlotsWindow = 3 * 24 * time.Hour
+	// maxAlternativeSlots is the number of alternative slots offered for a time that isn't available
+	maxAlternativeSlots = 3
+)
+
+// slotUnavailableError means the requested time can't be booked. Alternatives are the
+// available start times nearest to it.
+type slotUnavailableError struct {
+	EventType    string
+	Start        time.Time
+	Alternatives []time.Time
+}
+
+// Error describes the requested time and the alternatives
+func (e *slotUnavailableError) Error() string {
+	message := fmt.Sprintf("%s is not available", e.Start.Format("Monday, 2 January 2006 15:04 MST"))
+	if e.EventType != "" {
+		message += fmt.Sprintf(" for '%s'", e.EventType)
+	}
+	if len(e.Alternatives) == 0 {
+		return message + fmt.Sprintf(", and nothing is available within %d days of it.", int(alternativeSlotsWindow.Hours()/24))
+	}
+	times := make([]string, len(e.Alternatives))
+	for i, alternative := range e.Alternatives {
+		times[i] = alternative.Format("Monday, 2 January 15:04")
+	}
+	return message + ". The nearest available times are " + joinWords(times) + "."
+}
+
+// result is the tool result offering the alternatives
+func (e *slotUnavailableError) result() map[string]interface{} {
+	return map[string]interface{}{
+		"status":         "unavailable",
+		"requestedStart": e.Start,
+		"alternatives":   e.Alternatives,
+		"message":        "Nothing has been booked. " + e.Error() + " Offer the user these times instead.",
+	}
+}
+
+// checkSlot verifies that the booking starts at an available slot of its event type, returning
+// a slotUnavailableError with the nearest available slots if it doesn't. The check is skipped if
+// the slots can't be fetched; Cal.com then rejects an unavailable time itself.
+func (c *Client) checkSlot(ctx context.Context, eventType models.EventType, booking models.BookingRequest) error {
+	location := userLocation(ctx)
+	now := time.Now()
+	from := booking.Start.Add(-alternativeSlotsWindow)
+	if from.Before(now) {
+		from = now
+	}
+	slots, err := c.calcomClient.GetAvailableSlots(ctx, booking.EventTypeID, from.In(location), booking.Start.Add(alternativeSlotsWindow).In(location))
 	if err != nil {
-		log.Printf("[ERROR] bookMeeting: failed to book event: %v", err)
-		return nil, fmt.Errorf("failed to book event: %v", err)
+		slog.WarnContext(ctx, "bookMeeting: failed to check availability, booking unchecked", "event_type_id", booking.EventTypeID, "error", err)
+		return nil
+	}
+	start := booking.Start.Truncate(time.Minute)
+	for _, slot := range slots {
+		if slot.Truncate(time.Minute).Equal(start) {
+			return nil
+		}
 	}
 
-	log.Printf("[INFO] bookMeeting: event booked successfully: %+v", event)
+	// Offer the future slots closest to the requested time, in order
+	var candidates []time.Time
+	for _, slot := range slots {
+		if slot.After(now) {
+			candidates = append(candidates, slot.In(location))
+		}
+	}
+	distance := func(slot time.Time) time.Duration {
+		if d := slot.Sub(start); d >= 0 {
+			return d
+		}
+		return start.Sub(slot)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return distance(candidates[i]) < distance(candidates[j]) })
+	if len(candidates) > maxAlternativeSlots {
+		candidates = candidates[:maxAlternativeSlots]
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	slog.InfoContext(ctx, "bookMeeting: requested time is not available", "event_type_id", booking.EventTypeID, "start", booking.Start, "alternatives", len(candidates))
+	return &slotUnavailableError{EventType: eventType.Title, Start: booking.Start.In(location), Alternatives: candidates}
+}
+
+// validEmail reports whether email is a plain address, without a display name
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+// executeBooking books the event with the calendar backend
+func (c *Client) executeBooking(ctx context.Context, booking models.BookingRequest) (*models.Event, error) {
+	slog.InfoContext(ctx, "bookMeeting: booking event", "email", booking.Email, "start", booking.Start, "end", booking.End)
+	event, err := c.calcomClient.BookEvent(ctx, booking)
+	if err != nil {
+		slog.ErrorContext(ctx, "bookMeeting: failed to book event", "error", err)
+		return nil, calendarError("book event", fmt.Sprintf("event type %d", booking.EventTypeID), err)
+	}
+
+	slog.InfoContext(ctx, "bookMeeting: event booked successfully", "uid", event.UID, "start", event.StartTime)
+	updateDraft(ctx, func(draft *models.BookingDraft) {
+		start := booking.Start
+		if draft.EventTypeID != booking.EventTypeID {
+			draft.EventType = ""
+		}
+		draft.EventTypeID, draft.Start, draft.Duration = booking.EventTypeID, &start, int(booking.End.Sub(booking.Start).Minutes())
+		draft.AttendeeName, draft.AttendeeEmail = booking.Name, booking.Email
+		draft.Notes, draft.Location = booking.Notes, booking.Location
+		draft.Status, draft.BookingID = models.DraftBooked, event.ID
+	})
 	return event, nil
 }
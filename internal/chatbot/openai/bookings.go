@@ -0,0 +1,135 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// getBooking handles the getBooking function call
+func (c *Client) getBooking(ctx context.Context, args string) (interface{}, error) {
+	slog.InfoContext(ctx, "getBooking called", "args", args)
+	var params struct {
+		BookingID string `json:"bookingId"`
+	}
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		slog.ErrorContext(ctx, "getBooking: failed to parse args", "error", err)
+		return nil, fmt.Errorf("failed to parse booking lookup parameters: %v", err)
+	}
+	if params.BookingID == "" {
+		return nil, fmt.Errorf("Please specify the ID of the booking; list the events to find it.")
+	}
+
+	booking, err := c.calcomClient.FindBooking(ctx, params.BookingID)
+	if err != nil {
+		slog.ErrorContext(ctx, "getBooking: failed to fetch booking", "error", err)
+		return nil, calendarError("look up the booking", "booking "+params.BookingID, err)
+	}
+	return booking, nil
+}
+
+// editBooking handles the editBooking function call
+func (c *Client) editBooking(ctx context.Context, args string) (interface{}, error) {
+	slog.InfoContext(ctx, "editBooking called", "args", args)
+	var params struct {
+		BookingID string `json:"bookingId"`
+		Title     string `json:"title"`
+		Notes     string `json:"notes"`
+		Location  string `json:"location"`
+	}
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		slog.ErrorContext(ctx, "editBooking: failed to parse args", "error", err)
+		return nil, fmt.Errorf("failed to parse booking edit parameters: %v", err)
+	}
+	if params.BookingID == "" {
+		return nil, fmt.Errorf("Please specify the ID of the booking to edit; list the events to find it.")
+	}
+
+	update := models.BookingUpdate{Title: params.Title, Description: params.Notes, Location: params.Location}
+	var changes []string
+	if update.Title != "" {
+		changes = append(changes, fmt.Sprintf("title to '%s'", update.Title))
+	}
+	if update.Description != "" {
+		changes = append(changes, fmt.Sprintf("notes to '%s'", update.Description))
+	}
+	if update.Location != "" {
+		changes = append(changes, fmt.Sprintf("location to '%s'", update.Location))
+	}
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("Please specify what to change about the booking: its title, notes or location.")
+	}
+
+	arguments := map[string]interface{}{
+		"bookingId": params.BookingID,
+		"update":    update,
+	}
+	summary := fmt.Sprintf("Change the %s of booking %s", strings.Join(changes, " and "), params.BookingID)
+	return c.proposeAction(ctx, "editBooking", arguments, summary)
+}
+
+// findBookableSlots handles the findBookableSlots function call
+func (c *Client) findBookableSlots(ctx context.Context, args string) (interface{}, error) {
+	slog.InfoContext(ctx, "findBookableSlots called", "args", args)
+	var params struct {
+		StartDate string `json:"startDate"`
+		EndDate   string `json:"endDate"`
+	}
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		slog.ErrorContext(ctx, "findBookableSlots: failed to parse args", "error", err)
+		return nil, fmt.Errorf("failed to parse bookable slot parameters: %v", err)
+	}
+
+	location := userLocation(ctx)
+	startDate, endDate, err := parseUserDateRange(params.StartDate, params.EndDate, location)
+	if err != nil {
+		slog.ErrorContext(ctx, "findBookableSlots: invalid date range", "error", err)
+		return nil, err
+	}
+
+	slots, err := c.calcomClient.GetBookableSlots(ctx, startDate, endDate)
+	if err != nil {
+		slog.ErrorContext(ctx, "findBookableSlots: failed to fetch slots", "error", err)
+		return nil, calendarError("find bookable slots", "the event type list", err)
+	}
+
+	titles := map[int]string{}
+	for _, eventType := range c.cachedEventTypes(ctx) {
+		titles[eventType.ID] = eventType.Title
+	}
+	type eventTypeSlots struct {
+		EventTypeID int         `json:"eventTypeId"`
+		Title       string      `json:"title,omitempty"`
+		Slots       []time.Time `json:"slots"`
+	}
+	// Group the slots by event type, keeping them in order
+	byEventType := map[int]*eventTypeSlots{}
+	for _, slot := range slots {
+		group, ok := byEventType[slot.EventTypeID]
+		if !ok {
+			group = &eventTypeSlots{EventTypeID: slot.EventTypeID, Title: titles[slot.EventTypeID]}
+			byEventType[slot.EventTypeID] = group
+		}
+		group.Slots = append(group.Slots, slot.Start.In(location))
+	}
+	bookable := make([]eventTypeSlots, 0, len(byEventType))
+	for _, group := range byEventType {
+		bookable = append(bookable, *group)
+	}
+	sort.Slice(bookable, func(i, j int) bool { return bookable[i].EventTypeID < bookable[j].EventTypeID })
+
+	result := map[string]interface{}{
+		"eventTypes": bookable,
+		"timeZone":   location.String(),
+	}
+	if len(bookable) == 0 {
+		result["message"] = fmt.Sprintf("Nothing can be booked from %s.", formatEventTime(startDate, endDate, location))
+	}
+	return result, nil
+}
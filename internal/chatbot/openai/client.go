@@ -3,70 +3,89 @@ package openai
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"log"
+	"errors"
+	"log/slog"
+	"text/template"
+	"time"
 
+	"github.com/yourusername/cal-chatbot/internal/actions"
 	"github.com/yourusername/cal-chatbot/internal/calcom"
 	"github.com/yourusername/cal-chatbot/internal/models"
 
-	// NOTE: handleFunctionCall will be implemented in handlers.go and imported here.
-	// import "./handlers"
 	goopenai "github.com/sashabaranov/go-openai"
 )
 
 type Client struct {
-	openaiClient *goopenai.Client
-	calcomClient *calcom.Client
-	model        string
+	openaiClient         *goopenai.Client
+	calcomClient         calcom.CalendarBackend
+	model                string
+	maxIterations        int
+	maxParallelToolCalls int
+	toolCallTimeout      time.Duration
+	systemPrompt         *template.Template
+	username             string
+	eventTypes           eventTypesCache
+	actions              *actions.Store
+	prices               map[string]Price
 }
 
-// ProcessMessage handles a user message and returns a response
-func (c *Client) ProcessMessage(ctx context.Context, messages []models.ChatMessage) (string, error) {
-	log.Printf("[INFO] ProcessMessage called with %d messages", len(messages))
+// ProcessMessage handles a user message and returns a response along with every function executed to produce it
+func (c *Client) ProcessMessage(ctx context.Context, messages []models.ChatMessage) (string, []models.ExecutedFunctionCall, error) {
+	return c.processMessage(ctx, messages, nil)
+}
+
+// processMessage implements ProcessMessage and ProcessMessageStream; handler is nil when not streaming
+func (c *Client) processMessage(ctx context.Context, messages []models.ChatMessage, handler StreamHandler) (string, []models.ExecutedFunctionCall, error) {
+	slog.InfoContext(ctx, "ProcessMessage called", "messages", len(messages))
+	ctx = withTurnID(ctx)
 
 	// Check for direct booking intent in the last user message
 	if len(messages) > 0 {
 		lastMsg := messages[len(messages)-1]
 		if lastMsg.Role == "user" && lastMsg.Booking != nil {
-			log.Printf("[INFO] Direct booking detected in user message, bypassing LLM.")
+			slog.InfoContext(ctx, "ProcessMessage: direct booking detected in user message, bypassing LLM")
 			bookingBytes, err := json.Marshal(lastMsg.Booking)
 			if err != nil {
-				return "Sorry, I couldn't process your booking details.", err
+				return "Sorry, I couldn't process your booking details.", nil, err
+			}
+			record := models.ExecutedFunctionCall{Name: "bookMeeting", Arguments: string(bookingBytes)}
+			if handler != nil {
+				handler(models.StreamEvent{Type: models.StreamEventToolCallStart, ToolCall: &record})
+			}
+			result, err := c.bookDirectly(ctx, string(bookingBytes))
+			// A time that isn't available or a missing detail is told to the user, like in the tool path
+			var toolResult toolResultError
+			if errors.As(err, &toolResult) {
+				slog.InfoContext(ctx, "ProcessMessage: direct booking not bookable as requested", "reason", err)
+				record.Result = toolResult.result()
+				if handler != nil {
+					handler(models.StreamEvent{Type: models.StreamEventToolCallEnd, ToolCall: &record})
+				}
+				return err.Error(), []models.ExecutedFunctionCall{record}, nil
+			}
+			if err != nil {
+				record.Error = err.Error()
+			} else {
+				record.Result = result
+			}
+			if handler != nil {
+				handler(models.StreamEvent{Type: models.StreamEventToolCallEnd, ToolCall: &record})
 			}
-			result, err := c.bookMeeting(string(bookingBytes))
 			if err != nil {
-				return "Sorry, I couldn't book your meeting: " + err.Error(), err
+				return "Sorry, I couldn't book your meeting: " + err.Error(), []models.ExecutedFunctionCall{record}, err
 			}
 			resultJSON, _ := json.Marshal(result)
-			return string(resultJSON), nil
+			return string(resultJSON), []models.ExecutedFunctionCall{record}, nil
 		}
 	}
 
-	openaiMessages := ConvertToOpenAIMessages(messages)
-	functionDefinitions := getFunctionDefinitions()
-
-	// Build the request, only set Functions/FunctionCall if functions are present
-	req := goopenai.ChatCompletionRequest{
-		Model:    c.model,
-		Messages: openaiMessages,
-	}
-	if len(functionDefinitions) > 0 {
-		req.Functions = functionDefinitions
-		req.FunctionCall = "auto"
-	}
-
-	resp, err := c.openaiClient.CreateChatCompletion(ctx, req)
+	systemMessage, err := c.systemMessage(ctx)
 	if err != nil {
-		log.Printf("[ERROR] ProcessMessage: failed to generate response: %v", err)
-		return "", fmt.Errorf("failed to generate response: %v", err)
+		slog.ErrorContext(ctx, "ProcessMessage failed", "error", err)
+		return "", nil, err
 	}
-	assistantMessage := resp.Choices[0].Message
-	if assistantMessage.FunctionCall != nil {
-		log.Printf("[INFO] ProcessMessage: function call detected: %s", assistantMessage.FunctionCall.Name)
-		return HandleFunctionCall(c, ctx, assistantMessage.FunctionCall, openaiMessages)
-	}
-	log.Printf("[INFO] ProcessMessage: returning assistant message content")
-	return assistantMessage.Content, nil
+	openaiMessages := append([]goopenai.ChatCompletionMessage{systemMessage}, ConvertToOpenAIMessages(messages)...)
+	return c.runFunctionLoop(ctx, openaiMessages, handler)
 }
 
 // getFunctionDefinitions returns the OpenAI function definitions
@@ -74,36 +93,44 @@ func getFunctionDefinitions() []goopenai.FunctionDefinition {
 	return []goopenai.FunctionDefinition{
 		{
 			Name:        "bookMeeting",
-			Description: "Book a new meeting or event in the user's Cal.com calendar.",
+			Description: "Propose booking a new meeting or event in the user's Cal.com calendar. Returns a token; the booking is made only after the user confirms it. If the time isn't available, the nearest available times are returned instead to offer the user.",
 			Parameters: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
 					"eventTypeId": map[string]interface{}{
 						"type":        "integer",
-						"description": "The ID of the event type to book.",
+						"description": "The ID of the event type to book, if known.",
+					},
+					"eventType": map[string]interface{}{
+						"type":        "string",
+						"description": "The event type in the user's words when its ID isn't known, e.g. \"30 min intro\", a slug or a duration such as \"an hour\".",
 					},
 					"startTime": map[string]interface{}{
 						"type":        "string",
-						"description": "The start time of the event in RFC3339 format.",
+						"description": "The start time of the event in RFC3339 format, or a phrase such as \"next Tuesday at 3pm\".",
 					},
 					"endTime": map[string]interface{}{
 						"type":        "string",
-						"description": "The end time of the event in RFC3339 format.",
+						"description": "The end time of the event in RFC3339 format, or a phrase such as \"next Tuesday at 3:30pm\". Leave it out to book the event type's length.",
 					},
 					"name": map[string]interface{}{
 						"type":        "string",
-						"description": "The name of the attendee.",
+						"description": "The name of the attendee, as given by the user. Never make it up.",
 					},
 					"email": map[string]interface{}{
 						"type":        "string",
-						"description": "The email of the attendee.",
+						"description": "The email of the attendee, as given by the user. Never make it up; leave it out to use the user's verified email.",
 					},
 					"notes": map[string]interface{}{
 						"type":        "string",
 						"description": "Optional notes or description for the event.",
 					},
+					"location": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional location of the meeting, e.g. an address or a video call link.",
+					},
 				},
-				"required": []string{"eventTypeId", "startTime", "endTime", "name", "email"},
+				"required": []string{"startTime"},
 			},
 		},
 		{
@@ -121,7 +148,7 @@ func getFunctionDefinitions() []goopenai.FunctionDefinition {
 		},
 		{
 			Name:        "cancelEvent",
-			Description: "Cancel an existing event by its event ID.",
+			Description: "Propose cancelling an existing event by its event ID, or by when it takes place. Returns a token; the event is cancelled only after the user confirms it.",
 			Parameters: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -129,13 +156,16 @@ func getFunctionDefinitions() []goopenai.FunctionDefinition {
 						"type":        "string",
 						"description": "The ID of the event to cancel.",
 					},
+					"timeText": map[string]interface{}{
+						"type":        "string",
+						"description": "When the event takes place, as the user said it, e.g. \"tomorrow at 3pm\" or \"Friday morning\". Used when the event ID is unknown.",
+					},
 				},
-				"required": []string{"eventId"},
 			},
 		},
 		{
 			Name:        "rescheduleEvent",
-			Description: "Reschedule an existing event to a new time.",
+			Description: "Propose rescheduling an existing event to a new time. Returns a token; the event is moved only after the user confirms it.",
 			Parameters: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -145,11 +175,11 @@ func getFunctionDefinitions() []goopenai.FunctionDefinition {
 					},
 					"newStartTime": map[string]interface{}{
 						"type":        "string",
-						"description": "The new start time in RFC3339 format.",
+						"description": "The new start time in RFC3339 format, or a phrase such as \"Friday at 10am\".",
 					},
 					"newEndTime": map[string]interface{}{
 						"type":        "string",
-						"description": "The new end time in RFC3339 format.",
+						"description": "The new end time in RFC3339 format, or a phrase such as \"Friday at 10:30am\".",
 					},
 				},
 				"required": []string{"eventId", "newStartTime", "newEndTime"},
@@ -167,14 +197,14 @@ func getFunctionDefinitions() []goopenai.FunctionDefinition {
 					},
 					"startDate": map[string]interface{}{
 						"type":        "string",
-						"description": "The start date (YYYY-MM-DD).",
+						"description": "The start date (YYYY-MM-DD), or a phrase such as \"next week\".",
 					},
 					"endDate": map[string]interface{}{
 						"type":        "string",
-						"description": "The end date (YYYY-MM-DD).",
+						"description": "The end date (YYYY-MM-DD), or a phrase such as \"end of next week\". Defaults to the end of the start date's period.",
 					},
 				},
-				"required": []string{"eventTypeId", "startDate", "endDate"},
+				"required": []string{"eventTypeId", "startDate"},
 			},
 		},
 		{
@@ -207,6 +237,20 @@ func getFunctionDefinitions() []goopenai.FunctionDefinition {
 				"required": []string{"title", "slug", "length", "lengthUnit"},
 			},
 		},
+		{
+			Name:        "confirmAction",
+			Description: "Carry out a calendar change proposed earlier, after the user has explicitly confirmed it in a later message.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"token": map[string]interface{}{
+						"type":        "string",
+						"description": "The token returned when the action was proposed.",
+					},
+				},
+				"required": []string{"token"},
+			},
+		},
 		{
 			Name:        "listEventTypes",
 			Description: "List all event types available for booking.",
@@ -215,6 +259,175 @@ func getFunctionDefinitions() []goopenai.FunctionDefinition {
 				"properties": map[string]interface{}{},
 			},
 		},
+		{
+			Name:        "getBooking",
+			Description: "Look up the details of a booking, e.g. its attendees, location and notes.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"bookingId": map[string]interface{}{
+						"type":        "string",
+						"description": "The ID of the booking, as listed by listEvents.",
+					},
+				},
+				"required": []string{"bookingId"},
+			},
+		},
+		{
+			Name:        "editBooking",
+			Description: "Propose changing the title, notes or location of a booking. Returns a token; the booking is changed only after the user confirms it. Use rescheduleEvent to change its time.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"bookingId": map[string]interface{}{
+						"type":        "string",
+						"description": "The ID of the booking to edit.",
+					},
+					"title": map[string]interface{}{
+						"type":        "string",
+						"description": "The new title.",
+					},
+					"notes": map[string]interface{}{
+						"type":        "string",
+						"description": "The new notes or description.",
+					},
+					"location": map[string]interface{}{
+						"type":        "string",
+						"description": "The new location, e.g. an address or a meeting link.",
+					},
+				},
+				"required": []string{"bookingId"},
+			},
+		},
+		{
+			Name:        "findBookableSlots",
+			Description: "Find the available time slots of every event type, e.g. when the user asks when they can meet without naming an event type.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"startDate": map[string]interface{}{
+						"type":        "string",
+						"description": "The start date (YYYY-MM-DD), or a phrase such as \"next week\".",
+					},
+					"endDate": map[string]interface{}{
+						"type":        "string",
+						"description": "The end date (YYYY-MM-DD), or a phrase such as \"end of next week\". Defaults to the end of the start date's period.",
+					},
+				},
+				"required": []string{"startDate"},
+			},
+		},
+		{
+			Name:        "listSchedules",
+			Description: "List the user's working-hour schedules, which set when event types can be booked.",
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "createSchedule",
+			Description: "Create a new working-hour schedule.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "The name of the schedule, e.g. \"Summer hours\".",
+					},
+					"timeZone": map[string]interface{}{
+						"type":        "string",
+						"description": "The IANA timezone of the schedule, e.g. Europe/Berlin. Defaults to the user's timezone.",
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        "editSchedule",
+			Description: "Propose changing a working-hour schedule: its name, timezone, weekly hours or date overrides, or making it the default. Returns a token; the schedule is changed only after the user confirms it.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"scheduleId": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the schedule, as listed by listSchedules.",
+					},
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "The new name.",
+					},
+					"timeZone": map[string]interface{}{
+						"type":        "string",
+						"description": "The new IANA timezone, e.g. Europe/Berlin.",
+					},
+					"isDefault": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether the schedule is used by event types that don't name one.",
+					},
+					"availability": map[string]interface{}{
+						"type":        "array",
+						"description": "The new weekly hours, replacing all current ones. Include the hours that stay the same.",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"days": map[string]interface{}{
+									"type":        "array",
+									"description": "English weekday names, e.g. Monday.",
+									"items":       map[string]interface{}{"type": "string"},
+								},
+								"startTime": map[string]interface{}{
+									"type":        "string",
+									"description": "Start of the hours, HH:MM in the schedule's timezone.",
+								},
+								"endTime": map[string]interface{}{
+									"type":        "string",
+									"description": "End of the hours, HH:MM in the schedule's timezone.",
+								},
+							},
+							"required": []string{"days", "startTime", "endTime"},
+						},
+					},
+					"overrides": map[string]interface{}{
+						"type":        "array",
+						"description": "Dates with different hours, e.g. a shorter day, replacing all current overrides.",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"date": map[string]interface{}{
+									"type":        "string",
+									"description": "The date, YYYY-MM-DD.",
+								},
+								"startTime": map[string]interface{}{
+									"type":        "string",
+									"description": "Start of the hours on that date, HH:MM.",
+								},
+								"endTime": map[string]interface{}{
+									"type":        "string",
+									"description": "End of the hours on that date, HH:MM.",
+								},
+							},
+							"required": []string{"date", "startTime", "endTime"},
+						},
+					},
+				},
+				"required": []string{"scheduleId"},
+			},
+		},
+		{
+			Name:        "removeSchedule",
+			Description: "Propose deleting a working-hour schedule. Returns a token; the schedule is deleted only after the user confirms it.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"scheduleId": map[string]interface{}{
+						"type":        "integer",
+						"description": "The ID of the schedule, as listed by listSchedules.",
+					},
+				},
+				"required": []string{"scheduleId"},
+			},
+		},
 	}
 }
 
@@ -223,7 +436,7 @@ func ConvertToOpenAIMessages(messages []models.ChatMessage) []goopenai.ChatCompl
 	var openaiMessages []goopenai.ChatCompletionMessage
 	for _, m := range messages {
 		role := m.Role
-		if role != goopenai.ChatMessageRoleSystem && role != goopenai.ChatMessageRoleUser && role != goopenai.ChatMessageRoleAssistant && role != goopenai.ChatMessageRoleFunction {
+		if role != goopenai.ChatMessageRoleSystem && role != goopenai.ChatMessageRoleUser && role != goopenai.ChatMessageRoleAssistant {
 			role = goopenai.ChatMessageRoleUser
 		}
 		openaiMessages = append(openaiMessages, goopenai.ChatCompletionMessage{
@@ -234,12 +447,23 @@ func ConvertToOpenAIMessages(messages []models.ChatMessage) []goopenai.ChatCompl
 	return openaiMessages
 }
 
-// NewClient creates a new OpenAI client wrapper
-func NewClient(apiKey string, calcomClient *calcom.Client, model string) *Client {
+// NewClient creates a new OpenAI client wrapper backed by the given calendar
+func NewClient(apiKey string, calcomClient calcom.CalendarBackend, model string) *Client {
+	return NewClientWithConfig(goopenai.DefaultConfig(apiKey), calcomClient, model)
+}
+
+// NewClientWithConfig creates a new OpenAI client wrapper from a go-openai config,
+// e.g. to point it at a proxy or a compatible API via BaseURL
+func NewClientWithConfig(config goopenai.ClientConfig, calcomClient calcom.CalendarBackend, model string) *Client {
 	return &Client{
-		openaiClient: goopenai.NewClient(apiKey),
-		calcomClient: calcomClient,
-		model:        model,
+		openaiClient:         goopenai.NewClientWithConfig(config),
+		calcomClient:         calcomClient,
+		model:                model,
+		maxIterations:        defaultMaxIterations,
+		maxParallelToolCalls: defaultMaxParallelToolCalls,
+		toolCallTimeout:      defaultToolCallTimeout,
+		systemPrompt:         template.Must(template.New("system").Parse(DefaultSystemPrompt)),
+		actions:              actions.NewStore(actions.DefaultTTL),
 	}
 }
 
@@ -257,7 +481,7 @@ func (c *Client) CheckConnection() error {
 		},
 	)
 	if err != nil {
-		log.Printf("[ERROR] CheckConnection: failed to connect to OpenAI: %v", err)
+		slog.Error("CheckConnection: failed to connect to OpenAI", "error", err)
 	}
 	return err
 }
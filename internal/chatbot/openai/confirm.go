@@ -0,0 +1,195 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/cal-chatbot/internal/actions"
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// turnIDKey is the context key of the ID of the message being processed
+type turnIDKey struct{}
+
+// withTurnID returns a copy of ctx carrying a new turn ID
+func withTurnID(ctx context.Context) context.Context {
+	return context.WithValue(ctx, turnIDKey{}, uuid.New().String())
+}
+
+// turnIDFromContext returns the turn ID stored in ctx, or ""
+func turnIDFromContext(ctx context.Context) string {
+	turnID, _ := ctx.Value(turnIDKey{}).(string)
+	return turnID
+}
+
+// SetPendingActionTTL sets how long proposed calendar changes, e.g. bookings, can be
+// confirmed. Actions proposed before the call are dropped. Values below 1 are ignored.
+func (c *Client) SetPendingActionTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	c.actions = actions.NewStore(ttl)
+}
+
+// proposeAction records a calendar change for the user to confirm and returns the tool result
+// telling the model to ask for confirmation
+func (c *Client) proposeAction(ctx context.Context, name string, arguments interface{}, summary string) (interface{}, error) {
+	encoded, err := json.Marshal(arguments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s arguments: %v", name, err)
+	}
+	action := c.actions.Propose(models.PendingAction{
+		ConversationID: models.ConversationIDFromContext(ctx),
+		Name:           name,
+		Arguments:      string(encoded),
+		Summary:        summary,
+		TurnID:         turnIDFromContext(ctx),
+	})
+	slog.InfoContext(ctx, "proposeAction: awaiting confirmation", "action", name, "summary", summary)
+	return map[string]interface{}{
+		"status":    "confirmation_required",
+		"token":     action.Token,
+		"summary":   action.Summary,
+		"expiresAt": action.ExpiresAt,
+		"message":   "Nothing has been changed yet. Ask the user to confirm: " + action.Summary,
+	}, nil
+}
+
+// ConfirmAction executes the pending action with token in the conversation, e.g. when the
+// user confirms it in the UI, and returns the action with the record of its execution.
+// Returns actions.ErrNotFound or actions.ErrExpired for tokens that can't be confirmed.
+func (c *Client) ConfirmAction(ctx context.Context, conversationID, token string) (models.PendingAction, models.ExecutedFunctionCall, error) {
+	action, err := c.actions.Take(conversationID, token)
+	if err != nil {
+		return action, models.ExecutedFunctionCall{}, err
+	}
+	ctx, cancel := c.withToolCallTimeout(ctx)
+	defer cancel()
+	return action, c.executeAction(ctx, action), nil
+}
+
+// confirmAction handles the confirmAction function call. The model may only confirm actions
+// proposed in an earlier message, after the user had a chance to answer.
+func (c *Client) confirmAction(ctx context.Context, args string) (interface{}, error) {
+	slog.InfoContext(ctx, "confirmAction called", "args", args)
+	var params struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		slog.ErrorContext(ctx, "confirmAction: failed to parse args", "error", err)
+		return nil, fmt.Errorf("failed to parse confirmation parameters: %v", err)
+	}
+
+	conversationID := models.ConversationIDFromContext(ctx)
+	action, err := c.actions.Get(conversationID, params.Token)
+	switch {
+	case err == actions.ErrExpired:
+		c.actions.Take(conversationID, params.Token)
+		return nil, fmt.Errorf("The confirmation for %q expired. Propose the action again.", action.Summary)
+	case err != nil:
+		return nil, fmt.Errorf("There is no pending action with token %q in this conversation.", params.Token)
+	case action.TurnID == turnIDFromContext(ctx):
+		return nil, fmt.Errorf("The user has not confirmed %q yet. Ask them, and call confirmAction only after they agree in their next message.", action.Summary)
+	}
+
+	action, err = c.actions.Take(conversationID, params.Token)
+	if err != nil {
+		return nil, fmt.Errorf("The action %q can no longer be confirmed: %v", action.Summary, err)
+	}
+	record := c.executeAction(ctx, action)
+	if record.Error != "" {
+		return nil, fmt.Errorf("%s", record.Error)
+	}
+	return record.Result, nil
+}
+
+// executeAction runs a confirmed action and records its outcome
+func (c *Client) executeAction(ctx context.Context, action models.PendingAction) models.ExecutedFunctionCall {
+	record := models.ExecutedFunctionCall{Name: action.Name, Arguments: action.Arguments}
+	var result interface{}
+	var err error
+	switch action.Name {
+	case "bookMeeting":
+		var booking models.BookingRequest
+		if err = json.Unmarshal([]byte(action.Arguments), &booking); err == nil {
+			result, err = c.executeBooking(ctx, booking)
+		}
+	case "cancelEvent":
+		var params struct {
+			EventID string `json:"eventId"`
+		}
+		if err = json.Unmarshal([]byte(action.Arguments), &params); err == nil {
+			if err = c.calcomClient.CancelEvent(ctx, params.EventID); err != nil {
+				err = calendarError("cancel event", "event "+params.EventID, err)
+			}
+			result = map[string]interface{}{"success": true, "message": "Done: " + action.Summary}
+		}
+	case "rescheduleEvent":
+		var params struct {
+			EventID      string    `json:"eventId"`
+			NewStartTime time.Time `json:"newStartTime"`
+			NewEndTime   time.Time `json:"newEndTime"`
+		}
+		if err = json.Unmarshal([]byte(action.Arguments), &params); err == nil {
+			if result, err = c.calcomClient.RescheduleEvent(ctx, params.EventID, params.NewStartTime, params.NewEndTime); err != nil {
+				err = calendarError("reschedule event", "event "+params.EventID, err)
+			}
+		}
+	case "editBooking":
+		var params struct {
+			BookingID string               `json:"bookingId"`
+			Update    models.BookingUpdate `json:"update"`
+		}
+		if err = json.Unmarshal([]byte(action.Arguments), &params); err == nil {
+			if result, err = c.calcomClient.EditBooking(ctx, params.BookingID, params.Update); err != nil {
+				err = calendarError("edit booking", "booking "+params.BookingID, err)
+			}
+		}
+	case "editSchedule":
+		var params struct {
+			ScheduleID   string                    `json:"scheduleId"`
+			Name         string                    `json:"name"`
+			TimeZone     string                    `json:"timeZone"`
+			IsDefault    *bool                     `json:"isDefault"`
+			Availability []models.AvailabilityRule `json:"availability"`
+			Overrides    []models.DateOverride     `json:"overrides"`
+		}
+		if err = json.Unmarshal([]byte(action.Arguments), &params); err == nil {
+			update := models.ScheduleUpdate{
+				Name:         params.Name,
+				TimeZone:     params.TimeZone,
+				IsDefault:    params.IsDefault,
+				Availability: params.Availability,
+				Overrides:    params.Overrides,
+			}
+			if result, err = c.calcomClient.EditSchedule(ctx, params.ScheduleID, update); err != nil {
+				err = calendarError("edit schedule", "schedule "+params.ScheduleID, err)
+			}
+		}
+	case "removeSchedule":
+		var params struct {
+			ScheduleID string `json:"scheduleId"`
+		}
+		if err = json.Unmarshal([]byte(action.Arguments), &params); err == nil {
+			if err = c.calcomClient.RemoveSchedule(ctx, params.ScheduleID); err != nil {
+				err = calendarError("delete schedule", "schedule "+params.ScheduleID, err)
+			}
+			result = map[string]interface{}{"success": true, "message": "Done: " + action.Summary}
+		}
+	default:
+		err = fmt.Errorf("unknown action: %s", action.Name)
+	}
+
+	if err != nil {
+		slog.ErrorContext(ctx, "executeAction: action failed", "action", action.Name, "error", err)
+		record.Error = fmt.Sprintf("%s failed: %v", action.Summary, err)
+	} else {
+		slog.InfoContext(ctx, "executeAction: action confirmed and executed", "action", action.Name)
+		record.Result = result
+	}
+	return record
+}
@@ -0,0 +1,53 @@
+package openai
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// bookingDraft returns the booking the conversation in ctx is putting together, or an empty
+// draft if there is none or its booking was already made
+func bookingDraft(ctx context.Context) models.BookingDraft {
+	tracker := models.DraftTrackerFromContext(ctx)
+	if tracker == nil {
+		return models.BookingDraft{}
+	}
+	draft := tracker.Draft()
+	if draft.Status == models.DraftBooked {
+		return models.BookingDraft{}
+	}
+	return draft
+}
+
+// updateDraft updates the booking draft of the conversation in ctx, if it is tracked
+func updateDraft(ctx context.Context, update func(*models.BookingDraft)) {
+	if tracker := models.DraftTrackerFromContext(ctx); tracker != nil {
+		tracker.Update(update)
+	}
+}
+
+// promptDraft returns the booking draft to show in the system prompt, with its start in the
+// user's timezone, or nil if there is none
+func promptDraft(ctx context.Context, location *time.Location) *models.BookingDraft {
+	draft := bookingDraft(ctx)
+	if draft.IsEmpty() {
+		return nil
+	}
+	if draft.Start != nil {
+		start := draft.Start.In(location)
+		draft.Start = &start
+	}
+	return &draft
+}
+
+// eventTypeByID returns the event type with id, and whether it is known
+func (c *Client) eventTypeByID(ctx context.Context, id int) (models.EventType, bool) {
+	for _, eventType := range c.cachedEventTypes(ctx) {
+		if eventType.ID == id {
+			return eventType, true
+		}
+	}
+	return models.EventType{}, false
+}
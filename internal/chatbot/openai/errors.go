@@ -0,0 +1,26 @@
+package openai
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yourusername/cal-chatbot/internal/calcom"
+)
+
+// calendarError turns a calendar backend error into a reply the model can act on. action says
+// what failed, e.g. "book the meeting", and subject what was looked up, e.g. "event abc".
+func calendarError(action, subject string, err error) error {
+	switch {
+	case errors.Is(err, calcom.ErrUnsupported):
+		return fmt.Errorf("Couldn't %s: %v. Tell the user it can be done in Cal.com directly.", action, err)
+	case calcom.IsNotFound(err):
+		return fmt.Errorf("Couldn't %s: %s doesn't exist in the calendar, it may have been cancelled or the ID is wrong. Look it up again, e.g. by listing the events, or ask the user which one they mean.", action, subject)
+	case calcom.IsConflict(err):
+		return fmt.Errorf("Couldn't %s: the time is no longer available. Check availability and offer the user another time.", action)
+	case calcom.IsRateLimited(err):
+		return fmt.Errorf("Couldn't %s: the calendar is receiving too many requests right now. Tell the user to try again in a minute; don't retry now.", action)
+	case calcom.IsAuth(err):
+		return fmt.Errorf("Couldn't %s: the calendar connection isn't authorized, so the calendar can't be accessed right now. Tell the user; retrying won't help.", action)
+	}
+	return fmt.Errorf("failed to %s: %v", action, err)
+}
@@ -0,0 +1,218 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// defaultMaxIterations is the number of model round trips allowed per message when not configured
+const defaultMaxIterations = 5
+
+// defaultMaxParallelToolCalls is the number of tool calls from one assistant turn run at once when not configured
+const defaultMaxParallelToolCalls = 4
+
+// defaultToolCallTimeout is how long a tool call may take, retries included, when not configured
+const defaultToolCallTimeout = 30 * time.Second
+
+// SetMaxIterations sets how many model round trips a single message may take before the
+// model is asked for a final answer. Values below 1 are ignored.
+func (c *Client) SetMaxIterations(n int) {
+	if n < 1 {
+		return
+	}
+	c.maxIterations = n
+}
+
+// SetMaxParallelToolCalls sets how many tool calls from a single assistant turn may run
+// concurrently. Values below 1 are ignored.
+func (c *Client) SetMaxParallelToolCalls(n int) {
+	if n < 1 {
+		return
+	}
+	c.maxParallelToolCalls = n
+}
+
+// SetToolCallTimeout sets how long a tool call and the calendar requests it makes may take.
+// Values below 1 are ignored.
+func (c *Client) SetToolCallTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	c.toolCallTimeout = timeout
+}
+
+// withToolCallTimeout derives the context of a tool call from the request context
+func (c *Client) withToolCallTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, c.toolCallTimeout)
+}
+
+// getTools wraps the function definitions as tools
+func getTools() []goopenai.Tool {
+	functionDefinitions := getFunctionDefinitions()
+	tools := make([]goopenai.Tool, 0, len(functionDefinitions))
+	for _, definition := range functionDefinitions {
+		tools = append(tools, goopenai.Tool{
+			Type:     goopenai.ToolTypeFunction,
+			Function: definition,
+		})
+	}
+	return tools
+}
+
+// runFunctionLoop keeps sending tool results back to the model, with tools enabled, until it
+// answers with plain content. It stops early when the iteration cap is reached or the model
+// only repeats calls it already made, and then asks for a final answer without tools.
+// handler is optional; when set, completions are streamed and tool progress is reported to it.
+func (c *Client) runFunctionLoop(ctx context.Context, messages []goopenai.ChatCompletionMessage, handler StreamHandler) (string, []models.ExecutedFunctionCall, error) {
+	tools := getTools()
+	var executed []models.ExecutedFunctionCall
+	seen := make(map[string]bool)
+
+	for i := 0; i < c.maxIterations; i++ {
+		req := goopenai.ChatCompletionRequest{
+			Model:    c.model,
+			Messages: messages,
+		}
+		if len(tools) > 0 {
+			req.Tools = tools
+			req.ToolChoice = "auto"
+		}
+
+		assistantMessage, err := c.complete(ctx, req, handler)
+		if err != nil {
+			slog.ErrorContext(ctx, "runFunctionLoop: failed to generate response", "error", err)
+			return "", executed, fmt.Errorf("failed to generate response: %v", err)
+		}
+		if len(assistantMessage.ToolCalls) == 0 {
+			slog.InfoContext(ctx, "runFunctionLoop: returning assistant message content", "tool_calls", len(executed))
+			return assistantMessage.Content, executed, nil
+		}
+
+		// Calls already made with the same arguments are not run again
+		repeated := make([]bool, len(assistantMessage.ToolCalls))
+		allRepeated := true
+		for j, toolCall := range assistantMessage.ToolCalls {
+			key := toolCall.Function.Name + ":" + toolCall.Function.Arguments
+			if seen[key] {
+				repeated[j] = true
+				continue
+			}
+			seen[key] = true
+			allRepeated = false
+		}
+		if allRepeated {
+			slog.InfoContext(ctx, "runFunctionLoop: loop detected, every tool call was already made with the same arguments")
+			return c.finalAnswer(ctx, messages, executed, handler)
+		}
+
+		slog.InfoContext(ctx, "runFunctionLoop: tool calls detected", "tool_calls", len(assistantMessage.ToolCalls), "iteration", i+1)
+		records := c.executeToolCalls(ctx, assistantMessage.ToolCalls, repeated, handler)
+
+		messages = append(messages, assistantMessage)
+		for j, record := range records {
+			content := functionResultContent(record)
+			if repeated[j] {
+				content = fmt.Sprintf(`{"error": %q}`, "this call was already made with the same arguments; use the earlier result")
+			} else {
+				executed = append(executed, record)
+			}
+			messages = append(messages, goopenai.ChatCompletionMessage{
+				Role:       goopenai.ChatMessageRoleTool,
+				Content:    content,
+				ToolCallID: record.ID,
+			})
+		}
+	}
+
+	slog.InfoContext(ctx, "runFunctionLoop: reached the iteration limit", "max_iterations", c.maxIterations)
+	return c.finalAnswer(ctx, messages, executed, handler)
+}
+
+// executeToolCalls runs the tool calls of one assistant turn concurrently, at most
+// maxParallelToolCalls at a time, and returns their records in the order they were requested.
+// Calls flagged in skip are not run. Start and end events are reported to handler if set.
+func (c *Client) executeToolCalls(ctx context.Context, toolCalls []goopenai.ToolCall, skip []bool, handler StreamHandler) []models.ExecutedFunctionCall {
+	records := make([]models.ExecutedFunctionCall, len(toolCalls))
+	sem := make(chan struct{}, c.maxParallelToolCalls)
+	var wg sync.WaitGroup
+
+	// Handlers are not required to be safe for concurrent use
+	var handlerMu sync.Mutex
+	report := func(eventType string, record models.ExecutedFunctionCall) {
+		if handler == nil {
+			return
+		}
+		handlerMu.Lock()
+		defer handlerMu.Unlock()
+		handler(models.StreamEvent{Type: eventType, ToolCall: &record})
+	}
+
+	for i, toolCall := range toolCalls {
+		records[i] = models.ExecutedFunctionCall{
+			ID:        toolCall.ID,
+			Name:      toolCall.Function.Name,
+			Arguments: toolCall.Function.Arguments,
+		}
+		if skip[i] {
+			continue
+		}
+		report(models.StreamEventToolCallStart, records[i])
+
+		wg.Add(1)
+		go func(i int, call goopenai.FunctionCall) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result, err := HandleFunctionCall(c, ctx, &call)
+			if err != nil {
+				records[i].Error = err.Error()
+			} else {
+				records[i].Result = result
+			}
+			report(models.StreamEventToolCallEnd, records[i])
+		}(i, toolCall.Function)
+	}
+
+	wg.Wait()
+	return records
+}
+
+// finalAnswer asks the model to answer from the results gathered so far, with tools disabled
+func (c *Client) finalAnswer(ctx context.Context, messages []goopenai.ChatCompletionMessage, executed []models.ExecutedFunctionCall, handler StreamHandler) (string, []models.ExecutedFunctionCall, error) {
+	messages = append(messages, goopenai.ChatCompletionMessage{
+		Role:    goopenai.ChatMessageRoleSystem,
+		Content: "No more tools can be called for this message. Answer the user using the tool results above.",
+	})
+	assistantMessage, err := c.complete(ctx, goopenai.ChatCompletionRequest{
+		Model:    c.model,
+		Messages: messages,
+	}, handler)
+	if err != nil {
+		slog.ErrorContext(ctx, "finalAnswer: failed to generate response", "error", err)
+		return "", executed, fmt.Errorf("failed to generate response after tool calls: %v", err)
+	}
+	return assistantMessage.Content, executed, nil
+}
+
+// functionResultContent renders an executed call as the content of a tool message.
+// Errors are passed back to the model so it can recover, e.g. by picking another slot.
+func functionResultContent(record models.ExecutedFunctionCall) string {
+	var payload interface{} = record.Result
+	if record.Error != "" {
+		payload = map[string]string{"error": record.Error}
+	}
+	content, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("functionResultContent: failed to marshal result", "function", record.Name, "error", err)
+		return fmt.Sprintf(`{"error": %q}`, "failed to marshal function result: "+err.Error())
+	}
+	return string(content)
+}
@@ -2,68 +2,69 @@ package openai
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 
 	"github.com/sashabaranov/go-openai"
+	"github.com/yourusername/cal-chatbot/internal/metrics"
 )
 
-func HandleFunctionCall(c *Client, ctx context.Context, functionCall *openai.FunctionCall, messages []openai.ChatCompletionMessage) (string, error) {
-	log.Printf("[INFO] HandleFunctionCall called for function: %s", functionCall.Name)
+// HandleFunctionCall executes a single function requested by the model and returns its result.
+// The calendar calls it makes are canceled with ctx and limited to the tool call timeout.
+func HandleFunctionCall(c *Client, ctx context.Context, functionCall *openai.FunctionCall) (interface{}, error) {
+	slog.InfoContext(ctx, "HandleFunctionCall called", "function", functionCall.Name)
+	start := time.Now()
 	var result interface{}
 	var err error
 
+	ctx, cancel := c.withToolCallTimeout(ctx)
+	defer cancel()
+
 	switch functionCall.Name {
 	case "bookMeeting":
-		result, err = c.bookMeeting(functionCall.Arguments)
+		result, err = c.bookMeeting(ctx, functionCall.Arguments)
 	case "listEvents":
-		result, err = c.listEvents(functionCall.Arguments)
+		result, err = c.listEvents(ctx, functionCall.Arguments)
 	case "cancelEvent":
-		result, err = c.cancelEvent(functionCall.Arguments)
+		result, err = c.cancelEvent(ctx, functionCall.Arguments)
 	case "checkAvailability":
-		result, err = c.checkAvailability(functionCall.Arguments)
+		result, err = c.checkAvailability(ctx, functionCall.Arguments)
 	case "rescheduleEvent":
-		result, err = c.rescheduleEvent(functionCall.Arguments)
+		result, err = c.rescheduleEvent(ctx, functionCall.Arguments)
+	case "confirmAction":
+		result, err = c.confirmAction(ctx, functionCall.Arguments)
 	case "createEventType":
-		result, err = c.createEventType(functionCall.Arguments)
+		result, err = c.createEventType(ctx, functionCall.Arguments)
 	case "listEventTypes":
-		result, err = c.listEventTypes(functionCall.Arguments)
+		result, err = c.listEventTypes(ctx, functionCall.Arguments)
+	case "getBooking":
+		result, err = c.getBooking(ctx, functionCall.Arguments)
+	case "editBooking":
+		result, err = c.editBooking(ctx, functionCall.Arguments)
+	case "findBookableSlots":
+		result, err = c.findBookableSlots(ctx, functionCall.Arguments)
+	case "listSchedules":
+		result, err = c.listSchedules(ctx, functionCall.Arguments)
+	case "createSchedule":
+		result, err = c.createSchedule(ctx, functionCall.Arguments)
+	case "editSchedule":
+		result, err = c.editSchedule(ctx, functionCall.Arguments)
+	case "removeSchedule":
+		result, err = c.removeSchedule(ctx, functionCall.Arguments)
 	default:
-		log.Printf("[ERROR] HandleFunctionCall: unknown function: %s", functionCall.Name)
-		return "", fmt.Errorf("unknown function: %s", functionCall.Name)
-	}
-
-	if err != nil {
-		log.Printf("[ERROR] HandleFunctionCall: function execution error for %s: %v", functionCall.Name, err)
-		return "", fmt.Errorf("function execution error: %v", err)
+		slog.ErrorContext(ctx, "HandleFunctionCall: unknown function", "function", functionCall.Name)
+		metrics.ToolCalls.WithLabelValues("unknown", "error").Inc()
+		return nil, fmt.Errorf("unknown function: %s", functionCall.Name)
 	}
 
-	resultJSON, err := json.Marshal(result)
-	if err != nil {
-		log.Printf("[ERROR] HandleFunctionCall: failed to marshal result for %s: %v", functionCall.Name, err)
-		return "", fmt.Errorf("failed to marshal function result: %v", err)
-	}
-
-	log.Printf("[INFO] HandleFunctionCall: function %s executed successfully", functionCall.Name)
-	messages = append(messages, openai.ChatCompletionMessage{
-		Role:    openai.ChatMessageRoleFunction,
-		Name:    functionCall.Name,
-		Content: string(resultJSON),
-	})
-
-	resp, err := c.openaiClient.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model:    c.model,
-			Messages: messages,
-		},
-	)
+	metrics.ToolCalls.WithLabelValues(functionCall.Name, metrics.Outcome(err)).Inc()
+	metrics.ToolCallDuration.WithLabelValues(functionCall.Name).Observe(time.Since(start).Seconds())
 	if err != nil {
-		log.Printf("[ERROR] HandleFunctionCall: failed to generate response after function call %s: %v", functionCall.Name, err)
-		return "", fmt.Errorf("failed to generate response after function call: %v", err)
+		slog.ErrorContext(ctx, "HandleFunctionCall: function execution error", "function", functionCall.Name, "error", err)
+		return nil, fmt.Errorf("function execution error: %v", err)
 	}
 
-	log.Printf("[INFO] HandleFunctionCall: returning response for function %s", functionCall.Name)
-	return resp.Choices[0].Message.Content, nil
+	slog.InfoContext(ctx, "HandleFunctionCall: function executed successfully", "function", functionCall.Name)
+	return result, nil
 }
@@ -1,93 +1,90 @@
 package openai
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-	"regexp"
-	"strings"
+	"log/slog"
 	"time"
 
 	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/internal/timeparse"
 )
 
-// parseTimeFromText tries to extract a time (e.g., 3pm, 14:00) from a string and returns it as time.Time (today's date)
-func parseTimeFromText(text string) (time.Time, error) {
-	// This is a simple implementation; you may want to improve it for more formats
-	re := regexp.MustCompile(`(\d{1,2})(?::(\d{2}))?\s*(am|pm)?`)
-	matches := re.FindStringSubmatch(strings.ToLower(text))
-	if len(matches) == 0 {
-		return time.Time{}, fmt.Errorf("no time found")
-	}
-	hour := 0
-	minute := 0
-	fmt.Sscanf(matches[1], "%d", &hour)
-	if matches[2] != "" {
-		fmt.Sscanf(matches[2], "%d", &minute)
-	}
-	if matches[3] == "pm" && hour < 12 {
-		hour += 12
-	}
-	if matches[3] == "am" && hour == 12 {
-		hour = 0
-	}
-	now := time.Now()
-	return time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location()), nil
-}
-
 // cancelEvent handles the cancelEvent function call
-func (c *Client) cancelEvent(args string) (interface{}, error) {
-	log.Printf("[INFO] cancelEvent called with args: %s", args)
+func (c *Client) cancelEvent(ctx context.Context, args string) (interface{}, error) {
+	slog.InfoContext(ctx, "cancelEvent called", "args", args)
 	var params struct {
 		EventID  string `json:"eventId"`
 		TimeText string `json:"timeText,omitempty"`
 	}
 
 	if err := json.Unmarshal([]byte(args), &params); err != nil {
-		log.Printf("[ERROR] cancelEvent: failed to parse args: %v", err)
+		slog.ErrorContext(ctx, "cancelEvent: failed to parse args", "error", err)
 		return nil, fmt.Errorf("failed to parse cancel event parameters: %v", err)
 	}
 
-	// If EventID is provided, cancel directly
+	// If EventID is provided, look the booking up so the user confirms what it actually is
 	if params.EventID != "" {
-		log.Printf("[INFO] cancelEvent: cancelling event with ID: %s", params.EventID)
-		err := c.calcomClient.CancelEvent(params.EventID)
+		booking, err := c.calcomClient.FindBooking(ctx, params.EventID)
 		if err != nil {
-			log.Printf("[ERROR] cancelEvent: failed to cancel event: %v", err)
-			return nil, fmt.Errorf("failed to cancel event: %v", err)
+			slog.ErrorContext(ctx, "cancelEvent: failed to fetch booking", "event_id", params.EventID, "error", err)
+			return nil, calendarError("look up the event to cancel", "event "+params.EventID, err)
+		}
+		event := booking.Event()
+		if event.Cancelled() {
+			return nil, fmt.Errorf("'%s' is already cancelled.", event.Title)
 		}
-		log.Printf("[INFO] cancelEvent: event cancelled successfully: %s", params.EventID)
-		return map[string]bool{"success": true}, nil
+		slog.InfoContext(ctx, "cancelEvent: proposing to cancel event", "event_id", params.EventID)
+		summary := fmt.Sprintf("Cancel '%s' (%s)", event.Title, formatEventTime(event.StartTime, event.EndTime, userLocation(ctx)))
+		return c.proposeAction(ctx, "cancelEvent", map[string]string{"eventId": params.EventID}, summary)
 	}
 
-	// If no EventID, try to parse time from args and find the event at that time
+	// If no EventID, resolve the time from args and find the event at that time (or in that period)
 	if params.TimeText != "" {
-		cancelTime, err := parseTimeFromText(params.TimeText)
+		location := userLocation(ctx)
+		when, err := timeparse.Parse(params.TimeText, time.Now().In(location))
 		if err != nil {
 			return nil, fmt.Errorf("Could not parse time from your request.")
 		}
-		events, err := c.calcomClient.GetEvents("") // Get all events
+		events, err := c.calcomClient.GetEvents(ctx, "") // Get all events
 		if err != nil {
-			return nil, fmt.Errorf("Could not retrieve events to find the one to cancel.")
+			slog.ErrorContext(ctx, "cancelEvent: failed to get events", "error", err)
+			return nil, calendarError("retrieve the events to find the one to cancel", "the event list", err)
 		}
+		var matches []models.Event
 		for _, event := range events {
-			if event.StartTime.Hour() == cancelTime.Hour() && event.StartTime.Minute() == cancelTime.Minute() && event.StartTime.Day() == cancelTime.Day() {
-				err := c.calcomClient.CancelEvent(event.ID)
-				if err != nil {
-					return nil, fmt.Errorf("Failed to cancel event at %s: %v", cancelTime.Format("15:04"), err)
-				}
-				return fmt.Sprintf("Event '%s' at %s has been canceled.", event.Title, cancelTime.Format("15:04")), nil
+			if event.Cancelled() {
+				continue
+			}
+			if when.IsInstant() && event.StartTime.Truncate(time.Minute).Equal(when.Start) ||
+				!when.IsInstant() && !event.StartTime.Before(when.Start) && event.StartTime.Before(when.End) {
+				matches = append(matches, event)
 			}
 		}
-		return nil, fmt.Errorf("I couldn't find an event at %s to cancel.", cancelTime.Format("15:04"))
+		description := describeWhen(when, location)
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("I couldn't find an event %s to cancel.", description)
+		case 1:
+			event := matches[0]
+			summary := fmt.Sprintf("Cancel '%s' (%s)", event.Title, formatEventTime(event.StartTime, event.EndTime, location))
+			return c.proposeAction(ctx, "cancelEvent", map[string]string{"eventId": event.ID}, summary)
+		default:
+			found := fmt.Sprintf("There are %d events %s; which one should be canceled?\n", len(matches), description)
+			for _, event := range matches {
+				found += fmt.Sprintf("- %s (ID %s): %s\n", event.Title, event.ID, formatEventTime(event.StartTime, event.EndTime, location))
+			}
+			return nil, fmt.Errorf("%s", found)
+		}
 	}
 
 	return nil, fmt.Errorf("Please specify the event ID or the time of the event you want to cancel.")
 }
 
 // checkAvailability handles the checkAvailability function call
-func (c *Client) checkAvailability(args string) (interface{}, error) {
-	log.Printf("[INFO] checkAvailability called with args: %s", args)
+func (c *Client) checkAvailability(ctx context.Context, args string) (interface{}, error) {
+	slog.InfoContext(ctx, "checkAvailability called", "args", args)
 	var params struct {
 		EventTypeID int    `json:"eventTypeId"`
 		StartDate   string `json:"startDate"`
@@ -95,40 +92,39 @@ func (c *Client) checkAvailability(args string) (interface{}, error) {
 	}
 
 	if err := json.Unmarshal([]byte(args), &params); err != nil {
-		log.Printf("[ERROR] checkAvailability: failed to parse args: %v", err)
+		slog.ErrorContext(ctx, "checkAvailability: failed to parse args", "error", err)
 		return nil, fmt.Errorf("failed to parse availability parameters: %v", err)
 	}
 
-	startDate, err := time.Parse("2006-01-02", params.StartDate)
+	// Dates are whole days in the user's timezone
+	location := userLocation(ctx)
+	startDate, endDate, err := parseUserDateRange(params.StartDate, params.EndDate, location)
 	if err != nil {
-		log.Printf("[ERROR] checkAvailability: invalid start date format: %v", err)
-		return nil, fmt.Errorf("invalid start date format: %v", err)
+		slog.ErrorContext(ctx, "checkAvailability: invalid date range", "error", err)
+		return nil, err
 	}
 
-	endDate, err := time.Parse("2006-01-02", params.EndDate)
+	slog.InfoContext(ctx, "checkAvailability: checking slots", "event_type_id", params.EventTypeID, "start", params.StartDate, "end", params.EndDate)
+	slots, err := c.calcomClient.GetAvailableSlots(ctx, params.EventTypeID, startDate, endDate)
 	if err != nil {
-		log.Printf("[ERROR] checkAvailability: invalid end date format: %v", err)
-		return nil, fmt.Errorf("invalid end date format: %v", err)
+		slog.ErrorContext(ctx, "checkAvailability: failed to check availability", "error", err)
+		return nil, calendarError("check availability", fmt.Sprintf("event type %d", params.EventTypeID), err)
 	}
 
-	endDate = endDate.Add(24 * time.Hour)
-
-	log.Printf("[INFO] checkAvailability: checking slots for eventTypeId=%d from %s to %s", params.EventTypeID, params.StartDate, params.EndDate)
-	slots, err := c.calcomClient.GetAvailableSlots(params.EventTypeID, startDate, endDate)
-	if err != nil {
-		log.Printf("[ERROR] checkAvailability: failed to check availability: %v", err)
-		return nil, fmt.Errorf("failed to check availability: %v", err)
+	slog.InfoContext(ctx, "checkAvailability: slots fetched successfully", "event_type_id", params.EventTypeID)
+	localSlots := make([]time.Time, len(slots))
+	for i, slot := range slots {
+		localSlots[i] = slot.In(location)
 	}
-
-	log.Printf("[INFO] checkAvailability: slots fetched successfully for eventTypeId=%d", params.EventTypeID)
 	return map[string]interface{}{
-		"availableSlots": slots,
+		"availableSlots": localSlots,
+		"timeZone":       location.String(),
 	}, nil
 }
 
 // rescheduleEvent handles the rescheduleEvent function call
-func (c *Client) rescheduleEvent(args string) (interface{}, error) {
-	log.Printf("[INFO] rescheduleEvent called with args: %s", args)
+func (c *Client) rescheduleEvent(ctx context.Context, args string) (interface{}, error) {
+	slog.InfoContext(ctx, "rescheduleEvent called", "args", args)
 	var params struct {
 		EventID      string `json:"eventId"`
 		NewStartTime string `json:"newStartTime"`
@@ -136,36 +132,36 @@ func (c *Client) rescheduleEvent(args string) (interface{}, error) {
 	}
 
 	if err := json.Unmarshal([]byte(args), &params); err != nil {
-		log.Printf("[ERROR] rescheduleEvent: failed to parse args: %v", err)
+		slog.ErrorContext(ctx, "rescheduleEvent: failed to parse args", "error", err)
 		return nil, fmt.Errorf("failed to parse reschedule parameters: %v", err)
 	}
 
-	newStartTime, err := time.Parse(time.RFC3339, params.NewStartTime)
+	location := userLocation(ctx)
+	newStartTime, err := parseUserTime(params.NewStartTime, location)
 	if err != nil {
-		log.Printf("[ERROR] rescheduleEvent: invalid new start time format: %v", err)
+		slog.ErrorContext(ctx, "rescheduleEvent: invalid new start time format", "error", err)
 		return nil, fmt.Errorf("invalid new start time format: %v", err)
 	}
 
-	newEndTime, err := time.Parse(time.RFC3339, params.NewEndTime)
+	newEndTime, err := parseUserTime(params.NewEndTime, location)
 	if err != nil {
-		log.Printf("[ERROR] rescheduleEvent: invalid new end time format: %v", err)
+		slog.ErrorContext(ctx, "rescheduleEvent: invalid new end time format", "error", err)
 		return nil, fmt.Errorf("invalid new end time format: %v", err)
 	}
 
-	log.Printf("[INFO] rescheduleEvent: rescheduling event %s to %s - %s", params.EventID, params.NewStartTime, params.NewEndTime)
-	event, err := c.calcomClient.RescheduleEvent(params.EventID, newStartTime, newEndTime)
-	if err != nil {
-		log.Printf("[ERROR] rescheduleEvent: failed to reschedule event: %v", err)
-		return nil, fmt.Errorf("failed to reschedule event: %v", err)
+	slog.InfoContext(ctx, "rescheduleEvent: proposing to reschedule event", "event_id", params.EventID, "start", params.NewStartTime, "end", params.NewEndTime)
+	arguments := map[string]interface{}{
+		"eventId":      params.EventID,
+		"newStartTime": newStartTime,
+		"newEndTime":   newEndTime,
 	}
-
-	log.Printf("[INFO] rescheduleEvent: event rescheduled successfully: %+v", event)
-	return event, nil
+	summary := fmt.Sprintf("Move event %s to %s", params.EventID, formatEventTime(newStartTime, newEndTime, location))
+	return c.proposeAction(ctx, "rescheduleEvent", arguments, summary)
 }
 
 // createEventType handles the createEventType function call
-func (c *Client) createEventType(args string) (interface{}, error) {
-	log.Printf("[INFO] createEventType called with args: %s", args)
+func (c *Client) createEventType(ctx context.Context, args string) (interface{}, error) {
+	slog.InfoContext(ctx, "createEventType called", "args", args)
 	var params struct {
 		Title       string `json:"title"`
 		Slug        string `json:"slug"`
@@ -174,7 +170,7 @@ func (c *Client) createEventType(args string) (interface{}, error) {
 		LengthUnit  string `json:"lengthUnit"`
 	}
 	if err := json.Unmarshal([]byte(args), &params); err != nil {
-		log.Printf("[ERROR] createEventType: failed to parse args: %v", err)
+		slog.ErrorContext(ctx, "createEventType: failed to parse args", "error", err)
 		return nil, fmt.Errorf("failed to parse event type creation parameters: %v", err)
 	}
 	request := models.EventTypeCreateRequest{
@@ -184,28 +180,23 @@ func (c *Client) createEventType(args string) (interface{}, error) {
 		Length:      params.Length,
 		LengthUnit:  params.LengthUnit,
 	}
-	log.Printf("[INFO] createEventType: creating event type %+v", request)
-	result, err := c.calcomClient.CreateEventType(request)
+	slog.InfoContext(ctx, "createEventType: creating event type", "title", request.Title, "slug", request.Slug, "length", request.Length)
+	result, err := c.calcomClient.CreateEventType(ctx, request)
 	if err != nil {
-		log.Printf("[ERROR] createEventType: failed to create event type: %v", err)
-		return nil, fmt.Errorf("failed to create event type: %v", err)
+		slog.ErrorContext(ctx, "createEventType: failed to create event type", "error", err)
+		return nil, calendarError("create event type", "the event type list", err)
 	}
-	log.Printf("[INFO] createEventType: event type created successfully: %+v", result)
+	slog.InfoContext(ctx, "createEventType: event type created successfully", "slug", request.Slug)
 	return result, nil
 }
 
-// containsIgnoreCase checks if substr is in s, case-insensitive
-func containsIgnoreCase(s, substr string) bool {
-	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
-}
-
 // listEventTypes handles the listEventTypes function call
-func (c *Client) listEventTypes(args string) (interface{}, error) {
-	log.Printf("[INFO] listEventTypes called")
-	eventTypes, err := c.calcomClient.GetEventTypes()
+func (c *Client) listEventTypes(ctx context.Context, args string) (interface{}, error) {
+	slog.InfoContext(ctx, "listEventTypes called")
+	eventTypes, err := c.calcomClient.GetEventTypes(ctx)
 	if err != nil {
-		log.Printf("[ERROR] listEventTypes: failed to fetch event types: %v", err)
-		return nil, fmt.Errorf("failed to fetch event types: %v", err)
+		slog.ErrorContext(ctx, "listEventTypes: failed to fetch event types", "error", err)
+		return nil, calendarError("fetch event types", "the event type list", err)
 	}
 	if len(eventTypes) == 0 {
 		return "You have no event types set up.", nil
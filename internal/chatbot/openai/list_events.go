@@ -1,31 +1,32 @@
 package openai
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 )
 
 // listEvents handles the listEvents function call
-func (c *Client) listEvents(args string) (interface{}, error) {
-	log.Printf("[INFO] listEvents called with args: %s", args)
+func (c *Client) listEvents(ctx context.Context, args string) (interface{}, error) {
+	slog.InfoContext(ctx, "listEvents called", "args", args)
 	var params struct {
 		Email string `json:"email"`
 	}
 
 	if err := json.Unmarshal([]byte(args), &params); err != nil {
-		log.Printf("[ERROR] listEvents: failed to parse args: %v", err)
+		slog.ErrorContext(ctx, "listEvents: failed to parse args", "error", err)
 		return nil, fmt.Errorf("failed to parse list events parameters: %v", err)
 	}
 
-	log.Printf("[INFO] listEvents: fetching events for email: %s", params.Email)
-	events, err := c.calcomClient.GetEvents(params.Email)
+	slog.InfoContext(ctx, "listEvents: fetching events", "email", params.Email)
+	events, err := c.calcomClient.GetEvents(ctx, params.Email)
 	if err != nil {
-		log.Printf("[ERROR] listEvents: failed to list events: %v", err)
-		return nil, fmt.Errorf("failed to list events: %v", err)
+		slog.ErrorContext(ctx, "listEvents: failed to list events", "error", err)
+		return nil, calendarError("list events", "the event list", err)
 	}
 
-	log.Printf("[INFO] listEvents: events fetched successfully for %s", params.Email)
+	slog.InfoContext(ctx, "listEvents: events fetched successfully", "email", params.Email)
 	if len(events) == 0 {
 		return map[string]interface{}{
 			"events":  []interface{}{},
@@ -33,10 +34,11 @@ func (c *Client) listEvents(args string) (interface{}, error) {
 		}, nil
 	}
 
-	// Format the events for user-friendly display
+	// Format the events for user-friendly display in the user's timezone
+	location := userLocation(ctx)
 	result := "Here are your scheduled events:\n"
 	for _, event := range events {
-		result += fmt.Sprintf("- %s: %s to %s\n  %s\n", event.Title, event.StartTime.Format("2006-01-02 15:04"), event.EndTime.Format("15:04"), event.Description)
+		result += fmt.Sprintf("- %s: %s\n  %s\n", event.Title, formatEventTime(event.StartTime, event.EndTime, location), event.Description)
 	}
 	return map[string]interface{}{
 		"events":  events,
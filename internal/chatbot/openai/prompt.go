@@ -0,0 +1,174 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"text/template"
+	"time"
+
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// DefaultSystemPrompt is the system prompt template used when none is configured
+const DefaultSystemPrompt = `You are a scheduling assistant that manages the Cal.com calendar{{if .Username}} of the Cal.com user "{{.Username}}"{{end}}.
+The current date and time is {{.Now.Format "Monday, 2 January 2006 15:04 MST"}} ({{.Now.Format "2006-01-02T15:04:05Z07:00"}}).
+The user's timezone is {{.Timezone}}. Interpret dates and times the user mentions in that timezone, never book or move events into the past, and pass times to tools in RFC3339 format with the correct offset.
+{{- if .Email}}
+The user's verified email address is {{.Email}}. Use it when they refer to "me" or "my" bookings.
+{{- end}}
+{{- if .EventTypes}}
+The available event types are:
+{{- range .EventTypes}}
+- {{.Title}} (id {{.ID}}{{if .Slug}}, slug "{{.Slug}}"{{end}}{{if .Length}}, {{.Length}} minutes{{end}})
+{{- end}}
+{{- end}}
+{{- with .Draft}}
+The booking being put together in this conversation so far{{if eq .Status "awaiting_confirmation"}}, proposed and awaiting the user's confirmation{{end}}:
+{{- if .EventTypeID}}
+- event type: {{if .EventType}}{{.EventType}} (id {{.EventTypeID}}){{else}}id {{.EventTypeID}}{{end}}
+{{- end}}
+{{- if .Start}}
+- start: {{.Start.Format "2006-01-02T15:04:05Z07:00"}}
+{{- end}}
+{{- if .Duration}}
+- duration: {{.Duration}} minutes
+{{- end}}
+{{- if .AttendeeName}}
+- attendee name: {{.AttendeeName}}
+{{- end}}
+{{- if .AttendeeEmail}}
+- attendee email: {{.AttendeeEmail}}
+{{- end}}
+{{- if .Notes}}
+- notes: {{.Notes}}
+{{- end}}
+{{- if .Location}}
+- location: {{.Location}}
+{{- end}}
+Keep these details unless the user changes them; bookMeeting fills in the ones you leave out.
+{{- if .Missing}} Still missing:{{range $i, $field := .Missing}}{{if $i}},{{end}} {{$field}}{{end}}.{{end}}
+{{- end}}
+Booking, cancelling, rescheduling and editing events and deleting schedules only propose the change and return a token. Describe the proposed change, ask the user to confirm it, and call confirmAction with the token only after they agree in their next message.
+{{- if .PendingActions}}
+Changes proposed earlier and awaiting the user's confirmation:
+{{- range .PendingActions}}
+- {{.Summary}} (token {{.Token}})
+{{- end}}
+If the user agrees to one of them, call confirmAction with its token.
+{{- end}}
+Ask the user for any detail you need instead of guessing it, above all attendee names and emails. When the user describes an event type in their own words, pass them to bookMeeting as eventType.`
+
+// eventTypesCacheTTL is how long the event types listed in the system prompt are reused
+const eventTypesCacheTTL = 5 * time.Minute
+
+// PromptData is the data the system prompt template is rendered with
+type PromptData struct {
+	Now        time.Time
+	Timezone   string
+	Username   string
+	Email      string
+	EventTypes []models.EventType
+	// Draft is the booking the conversation is putting together, or nil
+	Draft *models.BookingDraft
+	// PendingActions are the changes proposed in the conversation that await confirmation, oldest first
+	PendingActions []models.PendingAction
+}
+
+// eventTypesCache keeps the event types between requests so the prompt doesn't hit Cal.com every time
+type eventTypesCache struct {
+	mu         sync.Mutex
+	eventTypes []models.EventType
+	fetchedAt  time.Time
+	// fetching is closed when the fetch in flight finishes; nil when there is none
+	fetching chan struct{}
+}
+
+// SetSystemPrompt parses text as the system prompt template. It is rendered per request with PromptData.
+func (c *Client) SetSystemPrompt(text string) error {
+	tmpl, err := template.New("system").Parse(text)
+	if err != nil {
+		return fmt.Errorf("failed to parse system prompt: %v", err)
+	}
+	c.systemPrompt = tmpl
+	return nil
+}
+
+// SetUsername sets the Cal.com username mentioned in the system prompt
+func (c *Client) SetUsername(username string) {
+	c.username = username
+}
+
+// systemMessage renders the system prompt for the user in ctx
+func (c *Client) systemMessage(ctx context.Context) (goopenai.ChatCompletionMessage, error) {
+	profile := models.UserProfileFromContext(ctx)
+	location := profile.Location()
+
+	data := PromptData{
+		Now:        time.Now().In(location),
+		Timezone:   location.String(),
+		Username:   c.username,
+		Email:      profile.Email,
+		EventTypes: c.cachedEventTypes(ctx),
+		Draft:      promptDraft(ctx, location),
+		// Tool results aren't kept between turns, so the tokens to confirm are listed here
+		PendingActions: c.actions.Pending(models.ConversationIDFromContext(ctx)),
+	}
+	var buf bytes.Buffer
+	if err := c.systemPrompt.Execute(&buf, data); err != nil {
+		return goopenai.ChatCompletionMessage{}, fmt.Errorf("failed to render system prompt: %v", err)
+	}
+	return goopenai.ChatCompletionMessage{
+		Role:    goopenai.ChatMessageRoleSystem,
+		Content: buf.String(),
+	}, nil
+}
+
+// cachedEventTypes returns the calendar's event types, fetching them at most once per eventTypesCacheTTL.
+// Concurrent callers share one fetch, and the lock isn't held while it runs. A failed fetch is logged
+// and the prompt is rendered with the event types fetched before, if any.
+func (c *Client) cachedEventTypes(ctx context.Context) []models.EventType {
+	cache := &c.eventTypes
+	cache.mu.Lock()
+	if cache.eventTypes != nil && time.Since(cache.fetchedAt) < eventTypesCacheTTL {
+		defer cache.mu.Unlock()
+		return cache.eventTypes
+	}
+	if fetching := cache.fetching; fetching != nil {
+		// Serve stale event types rather than wait for the fetch another request started
+		if cache.eventTypes != nil {
+			defer cache.mu.Unlock()
+			return cache.eventTypes
+		}
+		cache.mu.Unlock()
+		select {
+		case <-fetching:
+		case <-ctx.Done():
+		}
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return cache.eventTypes
+	}
+	fetching := make(chan struct{})
+	cache.fetching = fetching
+	cache.mu.Unlock()
+
+	fetchCtx, cancel := c.withToolCallTimeout(ctx)
+	eventTypes, err := c.calcomClient.GetEventTypes(fetchCtx)
+	cancel()
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.fetching = nil
+	close(fetching)
+	if err != nil {
+		slog.ErrorContext(ctx, "cachedEventTypes: failed to fetch event types", "error", err)
+		return cache.eventTypes
+	}
+	cache.eventTypes = eventTypes
+	cache.fetchedAt = time.Now()
+	return eventTypes
+}
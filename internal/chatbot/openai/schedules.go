@@ -0,0 +1,194 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// listSchedules handles the listSchedules function call
+func (c *Client) listSchedules(ctx context.Context, args string) (interface{}, error) {
+	slog.InfoContext(ctx, "listSchedules called")
+	schedules, err := c.calcomClient.FindAllSchedules(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "listSchedules: failed to fetch schedules", "error", err)
+		return nil, calendarError("fetch schedules", "the schedule list", err)
+	}
+	if len(schedules) == 0 {
+		return map[string]interface{}{
+			"schedules": []interface{}{},
+			"message":   "You have no working-hour schedules set up.",
+		}, nil
+	}
+	return map[string]interface{}{"schedules": schedules}, nil
+}
+
+// createSchedule handles the createSchedule function call
+func (c *Client) createSchedule(ctx context.Context, args string) (interface{}, error) {
+	slog.InfoContext(ctx, "createSchedule called", "args", args)
+	var params struct {
+		Name     string `json:"name"`
+		TimeZone string `json:"timeZone"`
+	}
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		slog.ErrorContext(ctx, "createSchedule: failed to parse args", "error", err)
+		return nil, fmt.Errorf("failed to parse schedule creation parameters: %v", err)
+	}
+	if params.Name == "" {
+		return nil, fmt.Errorf("Please specify a name for the schedule.")
+	}
+	if params.TimeZone == "" {
+		params.TimeZone = userLocation(ctx).String()
+	} else if _, err := time.LoadLocation(params.TimeZone); err != nil {
+		return nil, fmt.Errorf("%q is not a valid IANA timezone, e.g. Europe/Berlin.", params.TimeZone)
+	}
+
+	slog.InfoContext(ctx, "createSchedule: creating schedule", "name", params.Name, "time_zone", params.TimeZone)
+	schedule, err := c.calcomClient.CreateSchedule(ctx, params.Name, params.TimeZone)
+	if err != nil {
+		slog.ErrorContext(ctx, "createSchedule: failed to create schedule", "error", err)
+		return nil, calendarError("create schedule", "the schedule list", err)
+	}
+	return schedule, nil
+}
+
+// editSchedule handles the editSchedule function call
+func (c *Client) editSchedule(ctx context.Context, args string) (interface{}, error) {
+	slog.InfoContext(ctx, "editSchedule called", "args", args)
+	var params struct {
+		ScheduleID   int                       `json:"scheduleId"`
+		Name         string                    `json:"name"`
+		TimeZone     string                    `json:"timeZone"`
+		IsDefault    *bool                     `json:"isDefault"`
+		Availability []models.AvailabilityRule `json:"availability"`
+		Overrides    []models.DateOverride     `json:"overrides"`
+	}
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		slog.ErrorContext(ctx, "editSchedule: failed to parse args", "error", err)
+		return nil, fmt.Errorf("failed to parse schedule edit parameters: %v", err)
+	}
+	if params.ScheduleID <= 0 {
+		return nil, fmt.Errorf("Please specify the ID of the schedule to edit; list the schedules to find it.")
+	}
+	if params.TimeZone != "" {
+		if _, err := time.LoadLocation(params.TimeZone); err != nil {
+			return nil, fmt.Errorf("%q is not a valid IANA timezone, e.g. Europe/Berlin.", params.TimeZone)
+		}
+	}
+	if err := validateScheduleHours(params.Availability, params.Overrides); err != nil {
+		return nil, err
+	}
+
+	update := models.ScheduleUpdate{
+		Name:         params.Name,
+		TimeZone:     params.TimeZone,
+		IsDefault:    params.IsDefault,
+		Availability: params.Availability,
+		Overrides:    params.Overrides,
+	}
+	if update.Name == "" && update.TimeZone == "" && update.IsDefault == nil && update.Availability == nil && update.Overrides == nil {
+		return nil, fmt.Errorf("Please specify what to change about the schedule: its name, timezone, hours, date overrides or whether it is the default.")
+	}
+
+	var changes []string
+	if update.Name != "" {
+		changes = append(changes, fmt.Sprintf("rename it to '%s'", update.Name))
+	}
+	if update.TimeZone != "" {
+		changes = append(changes, "set its timezone to "+update.TimeZone)
+	}
+	if update.IsDefault != nil {
+		if *update.IsDefault {
+			changes = append(changes, "make it the default")
+		} else {
+			changes = append(changes, "stop using it as the default")
+		}
+	}
+	if update.Availability != nil {
+		changes = append(changes, fmt.Sprintf("replace its weekly hours with %d rule(s)", len(update.Availability)))
+	}
+	if update.Overrides != nil {
+		changes = append(changes, fmt.Sprintf("replace its date overrides with %d override(s)", len(update.Overrides)))
+	}
+
+	scheduleID := strconv.Itoa(params.ScheduleID)
+	// The fields are stored one by one, as an empty list of hours would be lost to omitempty
+	arguments := map[string]interface{}{
+		"scheduleId":   scheduleID,
+		"name":         update.Name,
+		"timeZone":     update.TimeZone,
+		"isDefault":    update.IsDefault,
+		"availability": update.Availability,
+		"overrides":    update.Overrides,
+	}
+	summary := fmt.Sprintf("Edit schedule %s: %s", scheduleID, strings.Join(changes, ", "))
+	return c.proposeAction(ctx, "editSchedule", arguments, summary)
+}
+
+// validateScheduleHours checks the weekdays, dates and HH:MM times of schedule rules
+func validateScheduleHours(availability []models.AvailabilityRule, overrides []models.DateOverride) error {
+	validHours := func(startTime, endTime string) error {
+		start, err := time.Parse("15:04", startTime)
+		if err != nil {
+			return fmt.Errorf("%q is not a time of day in HH:MM format.", startTime)
+		}
+		end, err := time.Parse("15:04", endTime)
+		if err != nil {
+			return fmt.Errorf("%q is not a time of day in HH:MM format.", endTime)
+		}
+		if !end.After(start) {
+			return fmt.Errorf("The hours from %s to %s end before they start.", startTime, endTime)
+		}
+		return nil
+	}
+	for _, rule := range availability {
+		if len(rule.Days) == 0 {
+			return fmt.Errorf("Every availability rule needs at least one weekday.")
+		}
+		for _, day := range rule.Days {
+			if !weekdays[day] {
+				return fmt.Errorf("%q is not a weekday; use English names such as Monday.", day)
+			}
+		}
+		if err := validHours(rule.StartTime, rule.EndTime); err != nil {
+			return err
+		}
+	}
+	for _, override := range overrides {
+		if _, err := time.Parse("2006-01-02", override.Date); err != nil {
+			return fmt.Errorf("%q is not a date in YYYY-MM-DD format.", override.Date)
+		}
+		if err := validHours(override.StartTime, override.EndTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// weekdays are the day names accepted in availability rules
+var weekdays = map[string]bool{
+	"Monday": true, "Tuesday": true, "Wednesday": true, "Thursday": true, "Friday": true, "Saturday": true, "Sunday": true,
+}
+
+// removeSchedule handles the removeSchedule function call
+func (c *Client) removeSchedule(ctx context.Context, args string) (interface{}, error) {
+	slog.InfoContext(ctx, "removeSchedule called", "args", args)
+	var params struct {
+		ScheduleID int `json:"scheduleId"`
+	}
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		slog.ErrorContext(ctx, "removeSchedule: failed to parse args", "error", err)
+		return nil, fmt.Errorf("failed to parse schedule removal parameters: %v", err)
+	}
+	if params.ScheduleID <= 0 {
+		return nil, fmt.Errorf("Please specify the ID of the schedule to delete; list the schedules to find it.")
+	}
+	scheduleID := strconv.Itoa(params.ScheduleID)
+	return c.proposeAction(ctx, "removeSchedule", map[string]string{"scheduleId": scheduleID}, fmt.Sprintf("Delete schedule %s", scheduleID))
+}
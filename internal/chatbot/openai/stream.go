@@ -0,0 +1,108 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/yourusername/cal-chatbot/internal/metrics"
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// StreamHandler receives events while a message is processed in streaming mode
+type StreamHandler func(event models.StreamEvent)
+
+// ProcessMessageStream works like ProcessMessage but relays token deltas and tool call
+// progress to handler as they happen
+func (c *Client) ProcessMessageStream(ctx context.Context, messages []models.ChatMessage, handler StreamHandler) (string, []models.ExecutedFunctionCall, error) {
+	return c.processMessage(ctx, messages, handler)
+}
+
+// complete sends a chat completion request and returns the assistant message.
+// When handler is set the completion is streamed and content deltas are relayed to it.
+func (c *Client) complete(ctx context.Context, req goopenai.ChatCompletionRequest, handler StreamHandler) (goopenai.ChatCompletionMessage, error) {
+	start := time.Now()
+	if handler != nil {
+		// Streamed completions don't report token usage with this API version, so it is estimated
+		message, err := c.completeStream(ctx, req, handler)
+		if err == nil {
+			c.recordUsage(ctx, req.Model, estimateUsage(req, message), true)
+		}
+		metrics.OpenAIRequestDuration.WithLabelValues(req.Model, metrics.Outcome(err)).Observe(time.Since(start).Seconds())
+		return message, err
+	}
+
+	resp, err := c.openaiClient.CreateChatCompletion(ctx, req)
+	metrics.OpenAIRequestDuration.WithLabelValues(req.Model, metrics.Outcome(err)).Observe(time.Since(start).Seconds())
+	if err != nil {
+		return goopenai.ChatCompletionMessage{}, err
+	}
+	metrics.OpenAITokens.WithLabelValues(req.Model, "prompt").Add(float64(resp.Usage.PromptTokens))
+	metrics.OpenAITokens.WithLabelValues(req.Model, "completion").Add(float64(resp.Usage.CompletionTokens))
+	model := resp.Model
+	if model == "" {
+		model = req.Model
+	}
+	c.recordUsage(ctx, model, resp.Usage, false)
+	if len(resp.Choices) == 0 {
+		return goopenai.ChatCompletionMessage{}, fmt.Errorf("no choices returned")
+	}
+	return resp.Choices[0].Message, nil
+}
+
+// completeStream streams a chat completion, relaying content deltas to handler, and
+// assembles the tool call fragments into a complete assistant message
+func (c *Client) completeStream(ctx context.Context, req goopenai.ChatCompletionRequest, handler StreamHandler) (goopenai.ChatCompletionMessage, error) {
+	stream, err := c.openaiClient.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return goopenai.ChatCompletionMessage{}, err
+	}
+	defer stream.Close()
+
+	message := goopenai.ChatCompletionMessage{Role: goopenai.ChatMessageRoleAssistant}
+	var toolCalls []goopenai.ToolCall
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "completeStream: failed to read stream", "error", err)
+			return goopenai.ChatCompletionMessage{}, err
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta
+		if delta.Content != "" {
+			message.Content += delta.Content
+			handler(models.StreamEvent{Type: models.StreamEventDelta, Content: delta.Content})
+		}
+		for i, fragment := range delta.ToolCalls {
+			// Fragments carry the index of the call they belong to; fall back to position
+			index := i
+			if fragment.Index != nil {
+				index = *fragment.Index
+			}
+			for len(toolCalls) <= index {
+				toolCalls = append(toolCalls, goopenai.ToolCall{Type: goopenai.ToolTypeFunction})
+			}
+			if fragment.ID != "" {
+				toolCalls[index].ID = fragment.ID
+			}
+			if fragment.Type != "" {
+				toolCalls[index].Type = fragment.Type
+			}
+			toolCalls[index].Function.Name += fragment.Function.Name
+			toolCalls[index].Function.Arguments += fragment.Function.Arguments
+		}
+	}
+
+	message.ToolCalls = toolCalls
+	return message, nil
+}
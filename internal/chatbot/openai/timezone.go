@@ -0,0 +1,95 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/internal/timeparse"
+)
+
+// localTimeLayouts are accepted for tool arguments without a UTC offset; they are read in the user's timezone
+var localTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// userLocation returns the timezone of the user the request in ctx is made for
+func userLocation(ctx context.Context) *time.Location {
+	return models.UserProfileFromContext(ctx).Location()
+}
+
+// parseUserTime parses an RFC3339 time, a time without offset in the user's timezone, or a
+// phrase such as "tomorrow at 3pm", and returns it in the user's timezone
+func parseUserTime(value string, location *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(location), nil
+	}
+	for _, layout := range localTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+	result, err := timeparse.Parse(value, time.Now().In(location))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse %q as RFC3339 or a date and time: %v", value, err)
+	}
+	if !result.IsInstant() {
+		return time.Time{}, fmt.Errorf("%q is not a specific time of day", value)
+	}
+	return result.Start, nil
+}
+
+// parseUserPeriod parses a YYYY-MM-DD date or a phrase such as "next week" in the user's timezone
+// and returns the period it covers
+func parseUserPeriod(value string, location *time.Location) (timeparse.Result, error) {
+	if day, err := time.ParseInLocation("2006-01-02", value, location); err == nil {
+		return timeparse.Result{Start: day, End: day.AddDate(0, 0, 1)}, nil
+	}
+	result, err := timeparse.Parse(value, time.Now().In(location))
+	if err != nil {
+		return timeparse.Result{}, fmt.Errorf("cannot parse %q as YYYY-MM-DD or a date: %v", value, err)
+	}
+	return result, nil
+}
+
+// parseUserDateRange parses the start and optional end of a range of whole days in the user's
+// timezone, as taken by checkAvailability and findBookableSlots. Without an end date the range
+// ends with the start date's period, e.g. the end of "next week".
+func parseUserDateRange(startDate, endDate string, location *time.Location) (time.Time, time.Time, error) {
+	start, err := parseUserPeriod(startDate, location)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start date format: %v", err)
+	}
+	if endDate == "" {
+		return start.Start, start.End, nil
+	}
+	end, err := parseUserPeriod(endDate, location)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end date format: %v", err)
+	}
+	return start.Start, end.End, nil
+}
+
+// formatEventTime formats an event's start and end for the user, e.g. "2025-05-20 15:00 to 15:30 CEST"
+func formatEventTime(start, end time.Time, location *time.Location) string {
+	start, end = start.In(location), end.In(location)
+	if start.Format("2006-01-02") == end.Format("2006-01-02") {
+		return fmt.Sprintf("%s to %s", start.Format("2006-01-02 15:04"), end.Format("15:04 MST"))
+	}
+	return fmt.Sprintf("%s to %s", start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04 MST"))
+}
+
+// describeWhen describes a resolved time expression for the user, e.g. "at 2025-05-20 15:00 CEST"
+func describeWhen(when timeparse.Result, location *time.Location) string {
+	start, end := when.Start.In(location), when.End.In(location)
+	if when.IsInstant() {
+		return "at " + start.Format("2006-01-02 15:04 MST")
+	}
+	if start.Hour() == 0 && start.Minute() == 0 && end.Equal(start.AddDate(0, 0, 1)) {
+		return "on " + start.Format("Monday, 2006-01-02")
+	}
+	return "from " + formatEventTime(start, end, location)
+}
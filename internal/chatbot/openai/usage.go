@@ -0,0 +1,112 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// Price is the price of a model in USD per million tokens
+type Price struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// DefaultPrices are the prices used for models without a configured price
+var DefaultPrices = map[string]Price{
+	"gpt-4-turbo":   {Prompt: 10, Completion: 30},
+	"gpt-4o":        {Prompt: 2.5, Completion: 10},
+	"gpt-4o-mini":   {Prompt: 0.15, Completion: 0.6},
+	"gpt-4":         {Prompt: 30, Completion: 60},
+	"gpt-3.5-turbo": {Prompt: 0.5, Completion: 1.5},
+}
+
+// SetPrices sets the prices of models, in addition to and overriding DefaultPrices
+func (c *Client) SetPrices(prices map[string]Price) {
+	c.prices = make(map[string]Price, len(DefaultPrices)+len(prices))
+	for model, price := range DefaultPrices {
+		c.prices[model] = price
+	}
+	for model, price := range prices {
+		c.prices[model] = price
+	}
+}
+
+// priceOf returns the price of a model. Dated snapshots such as gpt-4o-2024-08-06 use the
+// price of the longest model name they start with.
+func (c *Client) priceOf(model string) (Price, bool) {
+	prices := c.prices
+	if prices == nil {
+		prices = DefaultPrices
+	}
+	if price, ok := prices[model]; ok {
+		return price, true
+	}
+	var best string
+	for name := range prices {
+		if strings.HasPrefix(model, name+"-") && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return prices[best], true
+}
+
+// recordUsage adds the usage of a completion to the usage recorder of ctx, if any.
+// estimated marks usage that wasn't reported by the API.
+func (c *Client) recordUsage(ctx context.Context, model string, usage goopenai.Usage, estimated bool) {
+	recorder := models.UsageRecorderFromContext(ctx)
+	if recorder == nil {
+		return
+	}
+	price, ok := c.priceOf(model)
+	recorder.Record(models.Usage{
+		Model:            model,
+		Completions:      1,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		EstimatedCost:    (float64(usage.PromptTokens)*price.Prompt + float64(usage.CompletionTokens)*price.Completion) / 1e6,
+		Unpriced:         !ok,
+		Estimated:        estimated,
+	})
+}
+
+// charsPerToken is the average number of characters of English text per token
+const charsPerToken = 4
+
+// estimateUsage estimates the tokens of a completion from the length of its request and reply,
+// for streamed completions, which don't report their usage
+func estimateUsage(req goopenai.ChatCompletionRequest, reply goopenai.ChatCompletionMessage) goopenai.Usage {
+	prompt := 0
+	for _, message := range req.Messages {
+		// Every message has a few tokens of overhead for its role and delimiters
+		prompt += 4 + estimateTokens(message.Content) + estimateToolCallTokens(message.ToolCalls)
+	}
+	if len(req.Tools) > 0 {
+		tools, _ := json.Marshal(req.Tools)
+		prompt += estimateTokens(string(tools))
+	}
+	completion := estimateTokens(reply.Content) + estimateToolCallTokens(reply.ToolCalls)
+	return goopenai.Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+}
+
+// estimateToolCallTokens estimates the tokens of the names and arguments of tool calls
+func estimateToolCallTokens(calls []goopenai.ToolCall) int {
+	tokens := 0
+	for _, call := range calls {
+		tokens += estimateTokens(call.Function.Name) + estimateTokens(call.Function.Arguments)
+	}
+	return tokens
+}
+
+// estimateTokens estimates the tokens of text, rounding up
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
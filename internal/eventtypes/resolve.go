@@ -0,0 +1,184 @@
+// Package eventtypes resolves the event type a user means from their own words, such as
+// "30 min intro", a slug like "intro-call" or a duration like "an hour".
+//
+// Conventions:
+//   - An exact slug or title wins outright.
+//   - Otherwise event types are ranked by the share of the user's words found in their title or
+//     slug, allowing prefixes ("intro" for "introduction") and single typos ("intor").
+//   - A duration narrows the best matches to the event types of that length; a duration on its
+//     own picks the event types of that length.
+//   - Generic words such as "meeting", "call" or "book" are ignored.
+package eventtypes
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// stopWords say nothing about which event type is meant
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "my": true, "one": true, "for": true, "with": true, "of": true,
+	"book": true, "booking": true, "schedule": true, "meeting": true, "meet": true, "call": true,
+	"event": true, "session": true, "appointment": true, "type": true, "long": true,
+	"min": true, "mins": true, "minute": true, "minutes": true, "hour": true, "hours": true, "hr": true, "hrs": true,
+}
+
+var (
+	// durationPattern matches "30 min", "30-minute", "45m", "1 hour", "1.5 hours" and "2h"
+	durationPattern = regexp.MustCompile(`\b(\d+(?:\.\d+)?)\s*-?\s*(minutes?|mins?|m|hours?|hrs?|h)\b`)
+	// wordDurationPattern matches "half an hour", "half hour", "an hour" and "quarter of an hour"
+	wordDurationPattern = regexp.MustCompile(`\b(half an hour|half hour|(?:a )?quarter(?: of an)? hour|an hour)\b`)
+	nonAlphanumeric     = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// wordDurations are the minutes of durations said in words
+var wordDurations = map[string]int{
+	"half an hour":         30,
+	"half hour":            30,
+	"quarter hour":         15,
+	"a quarter hour":       15,
+	"quarter of an hour":   15,
+	"a quarter of an hour": 15,
+	"an hour":              60,
+}
+
+// Resolve returns the event types query may refer to, best first. A single result is the
+// event type meant; several are equally good and the user has to choose; none means nothing
+// matched.
+func Resolve(query string, eventTypes []models.EventType) []models.EventType {
+	text := strings.ToLower(strings.TrimSpace(query))
+	if text == "" {
+		return nil
+	}
+	normalized := normalize(text)
+	for _, eventType := range eventTypes {
+		if strings.ToLower(eventType.Slug) == text || normalize(eventType.Slug) == normalized || normalize(eventType.Title) == normalized {
+			return []models.EventType{eventType}
+		}
+	}
+
+	minutes, text := extractDuration(text)
+	words := significantWords(text)
+	if len(words) == 0 && minutes == 0 {
+		return nil
+	}
+
+	candidates := eventTypes
+	if len(words) > 0 {
+		candidates = bestWordMatches(words, eventTypes)
+	}
+	if minutes > 0 {
+		var sameLength []models.EventType
+		for _, eventType := range candidates {
+			if eventType.Length == minutes {
+				sameLength = append(sameLength, eventType)
+			}
+		}
+		// Words the user chose outweigh a duration that doesn't fit them
+		if len(sameLength) > 0 || len(words) == 0 {
+			candidates = sameLength
+		}
+	}
+	return candidates
+}
+
+// extractDuration returns the minutes of the first duration in text, or 0, and text without it
+func extractDuration(text string) (int, string) {
+	if match := durationPattern.FindStringSubmatchIndex(text); match != nil {
+		value, _ := strconv.ParseFloat(text[match[2]:match[3]], 64)
+		minutes := value
+		if strings.HasPrefix(text[match[4]:match[5]], "h") {
+			minutes = value * 60
+		}
+		return int(minutes + 0.5), text[:match[0]] + " " + text[match[1]:]
+	}
+	if match := wordDurationPattern.FindStringIndex(text); match != nil {
+		return wordDurations[text[match[0]:match[1]]], text[:match[0]] + " " + text[match[1]:]
+	}
+	return 0, text
+}
+
+// bestWordMatches returns the event types sharing the most of words in their title or slug
+func bestWordMatches(words []string, eventTypes []models.EventType) []models.EventType {
+	var best []models.EventType
+	bestScore := 0
+	for _, eventType := range eventTypes {
+		names := strings.Fields(normalize(eventType.Title + " " + eventType.Slug))
+		score := 0
+		for _, word := range words {
+			for _, name := range names {
+				if wordMatches(word, name) {
+					score++
+					break
+				}
+			}
+		}
+		switch {
+		case score == 0 || score < bestScore:
+		case score > bestScore:
+			best, bestScore = []models.EventType{eventType}, score
+		default:
+			best = append(best, eventType)
+		}
+	}
+	return best
+}
+
+// wordMatches reports whether the user's word refers to a word of an event type's name
+func wordMatches(word, name string) bool {
+	switch {
+	case word == name:
+		return true
+	case len(word) >= 3 && strings.HasPrefix(name, word):
+		return true
+	case len(word) >= 4 && len(name) >= 4:
+		return editDistance(word, name) <= 1
+	}
+	return false
+}
+
+// significantWords returns the words of text that can tell event types apart
+func significantWords(text string) []string {
+	var words []string
+	for _, word := range strings.Fields(normalize(text)) {
+		if !stopWords[word] {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// normalize lowercases s and turns punctuation, dashes and underscores into single spaces
+func normalize(s string) string {
+	return strings.TrimSpace(nonAlphanumeric.ReplaceAllString(strings.ToLower(s), " "))
+}
+
+// editDistance returns the optimal string alignment distance of a and b: the number of
+// insertions, deletions, substitutions and swaps of adjacent letters turning one into the other
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
@@ -0,0 +1,13 @@
+package history
+
+import "github.com/yourusername/cal-chatbot/internal/models"
+
+// LatestDraft returns the booking draft saved last in a conversation, or an empty draft
+func LatestDraft(entries []models.HistoryEntry) models.BookingDraft {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Draft != nil {
+			return *entries[i].Draft
+		}
+	}
+	return models.BookingDraft{}
+}
@@ -0,0 +1,175 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// jsonlExt is the file extension of conversation files written by JSONLStore
+const jsonlExt = ".jsonl"
+
+// JSONLStore stores each conversation as a JSON-lines file, one models.HistoryEntry per line
+type JSONLStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// Ensure JSONLStore satisfies ConversationStore
+var _ ConversationStore = (*JSONLStore)(nil)
+
+// NewJSONLStore creates a JSON-lines conversation store in dir
+func NewJSONLStore(dir string) (*JSONLStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %v", err)
+	}
+	return &JSONLStore{dir: dir}, nil
+}
+
+// path returns the file path of a conversation
+func (s *JSONLStore) path(conversationID string) string {
+	return filepath.Join(s.dir, conversationID+jsonlExt)
+}
+
+// Append adds entries to the end of a conversation file
+func (s *JSONLStore) Append(conversationID string, entries ...models.HistoryEntry) error {
+	if err := ValidateConversationID(conversationID); err != nil {
+		return err
+	}
+
+	// Encode everything first so a bad entry doesn't leave a partial write
+	var lines []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal history entry: %v", err)
+		}
+		lines = append(lines, line...)
+		lines = append(lines, '\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path(conversationID), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(lines)
+	return err
+}
+
+// Load reads all entries of a conversation file
+func (s *JSONLStore) Load(conversationID string) ([]models.HistoryEntry, error) {
+	if err := ValidateConversationID(conversationID); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readJSONLFile(s.path(conversationID))
+}
+
+// Search scans every conversation file for messages containing the term, case-insensitively.
+// Messages mentioning the term more often rank higher.
+func (s *JSONLStore) Search(query SearchQuery) ([]models.HistorySearchResult, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	term := strings.ToLower(query.Term)
+	var results []models.HistorySearchResult
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), jsonlExt) {
+			continue
+		}
+		history, err := readJSONLFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		for _, h := range history {
+			count := strings.Count(strings.ToLower(h.Content), term)
+			if count == 0 || !query.matchesFilters(h) {
+				continue
+			}
+			results = append(results, models.HistorySearchResult{
+				ConversationID: strings.TrimSuffix(entry.Name(), jsonlExt),
+				Role:           h.Role,
+				Snippet:        snippet(h.Content, term),
+				Timestamp:      h.Timestamp,
+				Score:          float64(count),
+			})
+		}
+	}
+	return sortSearchResults(results, query.limit()), nil
+}
+
+// snippetRadius is the number of characters of context kept on each side of a match
+const snippetRadius = 40
+
+// snippet returns the text around the first case-insensitive match of term, with the match in brackets.
+// The content is compared as is, since lowercasing it can change its length, e.g. of "İ".
+func snippet(content, term string) string {
+	runes := []rune(content)
+	termRunes := []rune(term)
+	index := -1
+	for i := 0; i+len(termRunes) <= len(runes); i++ {
+		if strings.EqualFold(string(runes[i:i+len(termRunes)]), term) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return ""
+	}
+
+	start, end := index-snippetRadius, index+len(termRunes)+snippetRadius
+	prefix, suffix := "…", "…"
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	if end >= len(runes) {
+		end, suffix = len(runes), ""
+	}
+	return prefix + string(runes[start:index]) + "[" + string(runes[index:index+len(termRunes)]) + "]" + string(runes[index+len(termRunes):end]) + suffix
+}
+
+// readJSONLFile decodes a conversation file, returning ErrNotFound if it doesn't exist
+func readJSONLFile(path string) ([]models.HistoryEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var history []models.HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var entry models.HistoryEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", filepath.Base(path), err)
+		}
+		history = append(history, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
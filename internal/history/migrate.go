@@ -0,0 +1,99 @@
+package history
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// textLinePattern matches the "[time] [role]: text" lines of the legacy .txt history files
+var textLinePattern = regexp.MustCompile(`^\[([^\]]+)\] \[(\w+)\]: ?(.*)$`)
+
+// ParseTextHistory parses a legacy .txt history file. Lines without the
+// "[time] [role]:" prefix continue the content of the previous message.
+func ParseTextHistory(path string) ([]models.HistoryEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []models.HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if matches := textLinePattern.FindStringSubmatch(line); matches != nil {
+			timestamp, err := time.Parse(time.RFC3339, matches[1])
+			if err == nil {
+				entries = append(entries, models.HistoryEntry{
+					ChatMessage: models.ChatMessage{Role: matches[2], Content: matches[3]},
+					Timestamp:   timestamp,
+					Metadata:    map[string]string{"migratedFrom": filepath.Base(path)},
+				})
+				continue
+			}
+		}
+		if len(entries) == 0 {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			return nil, fmt.Errorf("unexpected content before the first message: %q", line)
+		}
+		entries[len(entries)-1].Content += "\n" + line
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// The files end with a newline after each message, so trim the trailing blank lines
+	for i := range entries {
+		entries[i].Content = strings.TrimRight(entries[i].Content, "\n")
+	}
+	return entries, nil
+}
+
+// MigrateTextHistory converts every legacy .txt history file in dir into store.
+// Conversations that already exist in the store are skipped, so it is safe to run again.
+// It returns the number of conversations migrated.
+func MigrateTextHistory(dir string, store ConversationStore) (int, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".txt") {
+			continue
+		}
+		conversationID := strings.TrimSuffix(file.Name(), ".txt")
+		if err := ValidateConversationID(conversationID); err != nil {
+			return migrated, err
+		}
+		if _, err := store.Load(conversationID); err == nil {
+			continue
+		} else if err != ErrNotFound {
+			return migrated, fmt.Errorf("failed to check %s: %v", conversationID, err)
+		}
+
+		entries, err := ParseTextHistory(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return migrated, fmt.Errorf("failed to parse %s: %v", file.Name(), err)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		if err := store.Append(conversationID, entries...); err != nil {
+			return migrated, fmt.Errorf("failed to store %s: %v", conversationID, err)
+		}
+		migrated++
+	}
+	return migrated, nil
+}
@@ -0,0 +1,216 @@
+package history
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/models"
+
+	// Pure-Go SQLite driver with FTS5 support
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema creates the message table, its full-text index and the attendee lookup table
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS messages (
+	id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	conversation_id TEXT NOT NULL,
+	role            TEXT NOT NULL,
+	content         TEXT NOT NULL,
+	timestamp       INTEGER NOT NULL,
+	entry           TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS messages_conversation ON messages (conversation_id, id);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5 (
+	content,
+	content = 'messages',
+	content_rowid = 'id'
+);
+CREATE TRIGGER IF NOT EXISTS messages_fts_insert AFTER INSERT ON messages BEGIN
+	INSERT INTO messages_fts (rowid, content) VALUES (new.id, new.content);
+END;
+
+CREATE TABLE IF NOT EXISTS message_attendees (
+	message_id INTEGER NOT NULL REFERENCES messages (id),
+	email      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS message_attendees_email ON message_attendees (email, message_id);
+`
+
+// SQLiteStore stores conversations in an embedded SQLite database with a full-text index
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// Ensure SQLiteStore satisfies ConversationStore
+var _ ConversationStore = (*SQLiteStore)(nil)
+
+// NewSQLiteStore opens (or creates) the SQLite history database at path, creating its directory if needed
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %v", err)
+	}
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %v", err)
+	}
+	// SQLite allows a single writer; serialize access instead of retrying on SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create history schema: %v", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Append inserts entries at the end of a conversation in a single transaction
+func (s *SQLiteStore) Append(conversationID string, entries ...models.HistoryEntry) error {
+	if err := ValidateConversationID(conversationID); err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, entry := range entries {
+		encoded, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal history entry: %v", err)
+		}
+		res, err := tx.Exec(
+			`INSERT INTO messages (conversation_id, role, content, timestamp, entry) VALUES (?, ?, ?, ?, ?)`,
+			conversationID, entry.Role, entry.Content, entry.Timestamp.UnixNano(), string(encoded),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert history entry: %v", err)
+		}
+		messageID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, email := range AttendeeEmails(entry) {
+			if _, err := tx.Exec(`INSERT INTO message_attendees (message_id, email) VALUES (?, ?)`, messageID, email); err != nil {
+				return fmt.Errorf("failed to insert attendee: %v", err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// Load returns all entries of a conversation in insertion order
+func (s *SQLiteStore) Load(conversationID string) ([]models.HistoryEntry, error) {
+	if err := ValidateConversationID(conversationID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`SELECT entry FROM messages WHERE conversation_id = ? ORDER BY id`, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.HistoryEntry
+	for rows.Next() {
+		var encoded string
+		if err := rows.Scan(&encoded); err != nil {
+			return nil, err
+		}
+		var entry models.HistoryEntry
+		if err := json.Unmarshal([]byte(encoded), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse history entry: %v", err)
+		}
+		history = append(history, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, ErrNotFound
+	}
+	return history, nil
+}
+
+// Search runs a full-text query over message content, ranked by bm25
+func (s *SQLiteStore) Search(query SearchQuery) ([]models.HistorySearchResult, error) {
+	match := ftsMatchExpression(query.Term)
+	if match == "" {
+		return nil, nil
+	}
+
+	where := []string{"messages_fts MATCH ?"}
+	args := []interface{}{match}
+	if query.Role != "" {
+		where = append(where, "m.role = ?")
+		args = append(args, query.Role)
+	}
+	if !query.From.IsZero() {
+		where = append(where, "m.timestamp >= ?")
+		args = append(args, query.From.UnixNano())
+	}
+	if !query.To.IsZero() {
+		where = append(where, "m.timestamp <= ?")
+		args = append(args, query.To.UnixNano())
+	}
+	if query.AttendeeEmail != "" {
+		where = append(where, "m.id IN (SELECT message_id FROM message_attendees WHERE email = ?)")
+		args = append(args, strings.ToLower(query.AttendeeEmail))
+	}
+	args = append(args, query.limit())
+
+	rows, err := s.db.Query(`
+		SELECT m.conversation_id, m.role, m.timestamp,
+			snippet(messages_fts, 0, '[', ']', '…', 12),
+			bm25(messages_fts)
+		FROM messages_fts
+		JOIN messages m ON m.id = messages_fts.rowid
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY bm25(messages_fts), m.timestamp DESC
+		LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search history: %v", err)
+	}
+	defer rows.Close()
+
+	var results []models.HistorySearchResult
+	for rows.Next() {
+		var result models.HistorySearchResult
+		var timestamp int64
+		var rank float64
+		if err := rows.Scan(&result.ConversationID, &result.Role, &timestamp, &result.Snippet, &rank); err != nil {
+			return nil, err
+		}
+		result.Timestamp = time.Unix(0, timestamp)
+		// bm25 is lower for better matches; flip it so higher scores rank first like the JSONL store
+		result.Score = -rank
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// ftsMatchExpression turns free text into an FTS5 query matching all of its words,
+// quoting each word so user input can't inject FTS5 syntax
+func ftsMatchExpression(term string) string {
+	var tokens []string
+	for _, word := range strings.Fields(term) {
+		tokens = append(tokens, `"`+strings.ReplaceAll(word, `"`, `""`)+`"`)
+	}
+	return strings.Join(tokens, " ")
+}
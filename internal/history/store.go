@@ -0,0 +1,139 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// ErrNotFound is returned when a conversation has no stored history
+var ErrNotFound = errors.New("conversation not found")
+
+// defaultSearchLimit caps the number of search results when the query doesn't set a limit
+const defaultSearchLimit = 50
+
+// ConversationStore persists the messages of chat conversations
+type ConversationStore interface {
+	// Append adds entries to the end of a conversation, creating it if needed
+	Append(conversationID string, entries ...models.HistoryEntry) error
+	// Load returns all entries of a conversation in the order they were appended
+	Load(conversationID string) ([]models.HistoryEntry, error)
+	// Search returns the messages matching query, best matches first
+	Search(query SearchQuery) ([]models.HistorySearchResult, error)
+}
+
+// SearchQuery describes a history search. Term is required; the other fields narrow the results.
+type SearchQuery struct {
+	Term          string
+	Role          string
+	From          time.Time
+	To            time.Time
+	AttendeeEmail string
+	Limit         int
+}
+
+// limit returns the maximum number of results to return
+func (q SearchQuery) limit() int {
+	if q.Limit <= 0 {
+		return defaultSearchLimit
+	}
+	return q.Limit
+}
+
+// matchesFilters reports whether an entry passes the role, date range and attendee filters
+func (q SearchQuery) matchesFilters(entry models.HistoryEntry) bool {
+	if q.Role != "" && entry.Role != q.Role {
+		return false
+	}
+	if !q.From.IsZero() && entry.Timestamp.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && entry.Timestamp.After(q.To) {
+		return false
+	}
+	if q.AttendeeEmail != "" {
+		email := strings.ToLower(q.AttendeeEmail)
+		for _, attendee := range AttendeeEmails(entry) {
+			if attendee == email {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// sortSearchResults orders results by score, then newest first, and applies the limit
+func sortSearchResults(results []models.HistorySearchResult, limit int) []models.HistorySearchResult {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Timestamp.After(results[j].Timestamp)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// Open creates the conversation store of the given kind ("jsonl" or "sqlite") in dir
+func Open(kind, dir string) (ConversationStore, error) {
+	switch kind {
+	case "", "jsonl":
+		return NewJSONLStore(dir)
+	case "sqlite":
+		return NewSQLiteStore(filepath.Join(dir, "history.db"))
+	default:
+		return nil, fmt.Errorf("unknown history store: %q", kind)
+	}
+}
+
+// conversationIDPattern restricts conversation IDs to characters that are safe in file names
+var conversationIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+// ValidateConversationID checks that a conversation ID can be used as a storage key
+func ValidateConversationID(conversationID string) error {
+	if !conversationIDPattern.MatchString(conversationID) {
+		return fmt.Errorf("invalid conversation ID: %q", conversationID)
+	}
+	return nil
+}
+
+// emailPattern finds email addresses in message content and tool call arguments
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+// AttendeeEmails returns the lowercased email addresses mentioned by an entry, in its
+// content, its booking or listEvents payload, or the arguments of its tool calls
+func AttendeeEmails(entry models.HistoryEntry) []string {
+	sources := []string{entry.Content}
+	for _, payload := range []map[string]interface{}{entry.Booking, entry.ListEvents} {
+		if payload != nil {
+			encoded, _ := json.Marshal(payload)
+			sources = append(sources, string(encoded))
+		}
+	}
+	for _, call := range entry.ToolCalls {
+		sources = append(sources, call.Arguments)
+	}
+
+	seen := make(map[string]bool)
+	var emails []string
+	for _, source := range sources {
+		for _, email := range emailPattern.FindAllString(source, -1) {
+			email = strings.ToLower(email)
+			if !seen[email] {
+				seen[email] = true
+				emails = append(emails, email)
+			}
+		}
+	}
+	return emails
+}
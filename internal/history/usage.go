@@ -0,0 +1,16 @@
+package history
+
+import "github.com/yourusername/cal-chatbot/internal/models"
+
+// SummarizeUsage returns the usage of each turn of a conversation with recorded usage, and the total
+func SummarizeUsage(conversationID string, entries []models.HistoryEntry) models.ConversationUsage {
+	summary := models.ConversationUsage{ConversationID: conversationID, Turns: []models.TurnUsage{}}
+	for _, entry := range entries {
+		if entry.Usage == nil {
+			continue
+		}
+		summary.Turns = append(summary.Turns, models.TurnUsage{Timestamp: entry.Timestamp, Usage: *entry.Usage})
+		summary.Total.Add(*entry.Usage)
+	}
+	return summary
+}
@@ -0,0 +1,226 @@
+// Package idempotency remembers the bookings made for idempotency keys so that a retried or
+// double-submitted booking returns the existing booking instead of creating a duplicate.
+package idempotency
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// DefaultTTL is how long bookings are remembered by idempotency key when not configured
+const DefaultTTL = 24 * time.Hour
+
+// Record is a booking made for an idempotency key
+type Record struct {
+	Key        string       `json:"key"`
+	BookingUID string       `json:"bookingUid"`
+	Event      models.Event `json:"event"`
+	// Email is the attendee's, to look up whether the booking is still active
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Removed marks a record forgotten after it was written to a file
+	Removed bool `json:"removed,omitempty"`
+}
+
+// Store maps idempotency keys to the bookings made for them
+type Store interface {
+	// Get returns the record of key, if any and not expired
+	Get(key string) (Record, bool)
+	// Put records the booking made for a key, replacing any earlier record
+	Put(record Record) error
+	// Forget removes the records of a booking, e.g. once it is cancelled or rescheduled
+	Forget(bookingID string) error
+}
+
+// matches reports whether the record is of the booking with the given ID or UID
+func (r Record) matches(bookingID string) bool {
+	return bookingID != "" && (r.BookingUID == bookingID || r.Event.UID == bookingID || r.Event.ID == bookingID)
+}
+
+// BookingKey derives the idempotency key of a booking from what is booked, when and for whom,
+// so that submitting the same booking twice yields the same key
+func BookingKey(booking models.BookingRequest) string {
+	fields := []string{
+		fmt.Sprint(booking.EventTypeID),
+		booking.Start.UTC().Format(time.RFC3339),
+		booking.End.UTC().Format(time.RFC3339),
+		strings.ToLower(strings.TrimSpace(booking.Email)),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// MemoryStore keeps records in memory; they are lost on restart
+type MemoryStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	records map[string]Record
+}
+
+// Ensure MemoryStore satisfies Store
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty in-memory store whose records expire after ttl
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &MemoryStore{ttl: ttl, records: make(map[string]Record)}
+}
+
+// Get returns the record of key, if any and not expired
+func (s *MemoryStore) Get(key string) (Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[key]
+	if ok && s.expired(record, time.Now()) {
+		delete(s.records, key)
+		return Record{}, false
+	}
+	return record, ok
+}
+
+// Put records the booking made for a key
+func (s *MemoryStore) Put(record Record) error {
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.Key] = record
+	return nil
+}
+
+// Forget removes the records of a booking
+func (s *MemoryStore) Forget(bookingID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(bookingID)
+	return nil
+}
+
+// remove deletes and returns the records of a booking; the caller must hold mu
+func (s *MemoryStore) remove(bookingID string) []Record {
+	var removed []Record
+	for key, record := range s.records {
+		if record.matches(bookingID) {
+			delete(s.records, key)
+			removed = append(removed, record)
+		}
+	}
+	return removed
+}
+
+// expired reports whether a record is older than the TTL
+func (s *MemoryStore) expired(record Record, now time.Time) bool {
+	return now.Sub(record.CreatedAt) > s.ttl
+}
+
+// FileStore keeps records in memory and appends them to a JSON-lines file, one Record per line,
+// so they survive restarts
+type FileStore struct {
+	MemoryStore
+	path string
+}
+
+// Ensure FileStore satisfies Store
+var _ Store = (*FileStore)(nil)
+
+// NewFileStore opens the store file at path, creating it and its directory if needed,
+// and loads the records in it that haven't expired after ttl
+func NewFileStore(path string, ttl time.Duration) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create idempotency store directory: %v", err)
+	}
+	s := &FileStore{MemoryStore: *NewMemoryStore(ttl), path: path}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open idempotency store: %v", err)
+	}
+	defer f.Close()
+
+	now := time.Now()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("failed to parse idempotency record: %v", err)
+		}
+		if record.Removed || s.expired(record, now) {
+			delete(s.records, record.Key)
+			continue
+		}
+		s.records[record.Key] = record
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read idempotency store: %v", err)
+	}
+	return s, nil
+}
+
+// Put records the booking made for a key and appends it to the file
+func (s *FileStore) Put(record Record) error {
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.append(record); err != nil {
+		return err
+	}
+	s.records[record.Key] = record
+	return nil
+}
+
+// Forget removes the records of a booking and appends their removal to the file
+func (s *FileStore) Forget(bookingID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := s.remove(bookingID)
+	for i := range removed {
+		removed[i].Removed = true
+	}
+	return s.append(removed...)
+}
+
+// append writes records to the end of the file; the caller must hold mu
+func (s *FileStore) append(records ...Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	var lines []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to marshal idempotency record: %v", err)
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open idempotency store: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(lines); err != nil {
+		return fmt.Errorf("failed to write idempotency record: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Package logging configures structured logging with log/slog
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/internal/redact"
+)
+
+// New creates a logger writing to w at level ("debug", "info", "warn" or "error") in format
+// ("text" or "json"). Records carry the request and conversation IDs of their context, and
+// email addresses, API keys and bearer tokens are masked.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var minLevel slog.Level
+	if level != "" {
+		if err := minLevel.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q: %v", level, err)
+		}
+	}
+	options := &slog.HandlerOptions{Level: minLevel, ReplaceAttr: redactAttr}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text":
+		handler = slog.NewTextHandler(w, options)
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return nil, fmt.Errorf("invalid log format %q, expected text or json", format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// Setup makes the logger configured by LOG_LEVEL and LOG_FORMAT the default, for log/slog
+// as well as the log package
+func Setup() error {
+	logger, err := New(os.Stderr, os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// redactAttr masks secrets and email addresses in the message and in string and error attributes
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(redact.String(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			a.Value = slog.StringValue(redact.String(err.Error()))
+		}
+	}
+	return a
+}
+
+// contextHandler adds the request and conversation IDs of the context to records
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle adds the IDs and passes the record on
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if requestID := models.RequestIDFromContext(ctx); requestID != "" {
+		r.AddAttrs(slog.String("request_id", requestID))
+	}
+	if conversationID := models.ConversationIDFromContext(ctx); conversationID != "" {
+		r.AddAttrs(slog.String("conversation_id", conversationID))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a contextHandler whose handler has the attributes
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a contextHandler whose handler has the group
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,97 @@
+// Package metrics defines the Prometheus metrics of the chatbot, served on /metrics
+package metrics
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// HTTPRequests counts served HTTP requests by route, method and status
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbot_http_requests_total",
+		Help: "HTTP requests served, by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	// HTTPRequestDuration observes how long HTTP requests took to serve by route and method
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chatbot_http_request_duration_seconds",
+		Help:    "Time taken to serve HTTP requests, by route and method.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 40},
+	}, []string{"route", "method"})
+
+	// ToolCalls counts tool calls by tool and outcome, "success" or "error"
+	ToolCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbot_tool_calls_total",
+		Help: "Tool calls made by the model, by tool and outcome.",
+	}, []string{"tool", "outcome"})
+
+	// ToolCallDuration observes how long tool calls took by tool
+	ToolCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chatbot_tool_call_duration_seconds",
+		Help:    "Time taken to execute tool calls, by tool.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"tool"})
+
+	// CalcomRequestDuration observes Cal.com request latency by endpoint, method and status
+	CalcomRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chatbot_calcom_request_duration_seconds",
+		Help:    "Latency of Cal.com API requests, by endpoint, method and status code (\"error\" if no response).",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint", "method", "status"})
+
+	// OpenAIRequestDuration observes OpenAI chat completion latency by model and outcome
+	OpenAIRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chatbot_openai_request_duration_seconds",
+		Help:    "Latency of OpenAI chat completions, by model and outcome.",
+		Buckets: []float64{0.25, 0.5, 1, 2, 4, 8, 16, 32, 64},
+	}, []string{"model", "outcome"})
+
+	// OpenAITokens counts tokens used by OpenAI chat completions by model and type, "prompt" or "completion"
+	OpenAITokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chatbot_openai_tokens_total",
+		Help: "Tokens used by OpenAI chat completions, by model and type.",
+	}, []string{"model", "type"})
+)
+
+// Outcome returns the outcome label of an operation that returned err
+func Outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// ObserveCalcomRequest records a Cal.com request. status is 0 if no response was received.
+func ObserveCalcomRequest(method, path string, status int, duration time.Duration) {
+	statusLabel := "error"
+	if status != 0 {
+		statusLabel = strconv.Itoa(status)
+	}
+	CalcomRequestDuration.WithLabelValues(CalcomEndpoint(path), method, statusLabel).Observe(duration.Seconds())
+}
+
+// calcomPathNames are the segments of Cal.com API paths that name resources rather than identify them
+var calcomPathNames = map[string]bool{
+	"v1": true, "v2": true, "bookings": true, "cancel": true, "reschedule": true, "slots": true,
+	"availability": true, "event-types": true, "schedules": true, "me": true,
+}
+
+// CalcomEndpoint returns the endpoint of a Cal.com API path for use as a label, with the query
+// dropped and IDs, UIDs and usernames replaced by {id}, e.g. /v2/bookings/{id}/cancel
+func CalcomEndpoint(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment != "" && !calcomPathNames[segment] {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// PendingAction is a calendar change proposed by a tool that only runs once the user confirms it
+type PendingAction struct {
+	Token          string `json:"token"`
+	ConversationID string `json:"conversationId,omitempty"`
+	// Name is the tool that proposed the action and Arguments its resolved arguments (JSON)
+	Name      string    `json:"name"`
+	Arguments string    `json:"arguments"`
+	Summary   string    `json:"summary"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// TurnID identifies the request that proposed the action; it can't be confirmed in the same request
+	TurnID string `json:"-"`
+}
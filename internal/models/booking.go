@@ -0,0 +1,64 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// Attendee is a person taking part in a booking, as attendee or organizer
+type Attendee struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// Booking is a Cal.com booking with everything known about it. ID is the numeric ID the v1 API
+// identifies bookings by; the v2 API uses UID.
+type Booking struct {
+	ID          int        `json:"id,omitempty"`
+	UID         string     `json:"uid"`
+	EventTypeID int        `json:"eventTypeId,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	StartTime   time.Time  `json:"startTime"`
+	EndTime     time.Time  `json:"endTime"`
+	Status      string     `json:"status"`
+	Location    string     `json:"location,omitempty"`
+	Attendees   []Attendee `json:"attendees,omitempty"`
+	Organizer   *Attendee  `json:"organizer,omitempty"`
+}
+
+// Event returns the booking as an Event, identified by its numeric ID, or its UID if it has none
+func (b Booking) Event() Event {
+	id := b.UID
+	if b.ID != 0 {
+		id = strconv.Itoa(b.ID)
+	}
+	return Event{
+		ID:          id,
+		UID:         b.UID,
+		Title:       b.Title,
+		Description: b.Description,
+		StartTime:   b.StartTime,
+		EndTime:     b.EndTime,
+		Status:      b.Status,
+		Location:    b.Location,
+		EventTypeID: b.EventTypeID,
+		Attendees:   b.Attendees,
+		Organizer:   b.Organizer,
+	}
+}
+
+// BookingUpdate changes the details of a booking. Empty fields are left unchanged.
+type BookingUpdate struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Location    string `json:"location,omitempty"`
+}
+
+// Slot is a time an event type can be booked at
+type Slot struct {
+	EventTypeID int       `json:"eventTypeId"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+}
@@ -16,9 +16,15 @@ type ChatMessage struct {
 type ChatRequest struct {
 	Messages []ChatMessage `json:"messages"`
 	UserID   string        `json:"userId,omitempty"`
+	// Timezone is the user's IANA timezone, e.g. "Europe/Berlin"
+	Timezone string `json:"timezone,omitempty"`
 }
 
 // ChatResponse represents the chatbot's response
 type ChatResponse struct {
-	Message string `json:"message"`
+	Message        string                 `json:"message"`
+	FunctionCalls  []ExecutedFunctionCall `json:"functionCalls,omitempty"`
+	ConversationID string                 `json:"conversationId"`
+	// Draft is the booking the conversation is putting together, if any
+	Draft *BookingDraft `json:"draft,omitempty"`
 }
@@ -0,0 +1,31 @@
+package models
+
+import "context"
+
+// conversationIDKey is the context key of the conversation a request belongs to
+type conversationIDKey struct{}
+
+// WithConversationID returns a copy of ctx carrying the conversation ID
+func WithConversationID(ctx context.Context, conversationID string) context.Context {
+	return context.WithValue(ctx, conversationIDKey{}, conversationID)
+}
+
+// ConversationIDFromContext returns the conversation ID stored in ctx, or ""
+func ConversationIDFromContext(ctx context.Context) string {
+	conversationID, _ := ctx.Value(conversationIDKey{}).(string)
+	return conversationID
+}
+
+// requestIDKey is the context key of the ID of the HTTP request being served
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or ""
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Booking draft statuses
+const (
+	DraftCollecting           = "collecting"
+	DraftAwaitingConfirmation = "awaiting_confirmation"
+	DraftBooked               = "booked"
+)
+
+// BookingDraft is the booking a conversation is putting together, so details the user gives
+// over several turns are kept track of. Empty fields haven't been given yet.
+type BookingDraft struct {
+	EventTypeID int `json:"eventTypeId,omitempty"`
+	// EventType is the title of the event type
+	EventType string     `json:"eventType,omitempty"`
+	Start     *time.Time `json:"start,omitempty"`
+	// Duration is the length of the meeting in minutes
+	Duration      int    `json:"duration,omitempty"`
+	AttendeeName  string `json:"attendeeName,omitempty"`
+	AttendeeEmail string `json:"attendeeEmail,omitempty"`
+	Notes         string `json:"notes,omitempty"`
+	Location      string `json:"location,omitempty"`
+	// Missing are the fields still needed to book, e.g. "attendeeEmail"
+	Missing []string `json:"missing"`
+	// Status is DraftCollecting, DraftAwaitingConfirmation or DraftBooked
+	Status string `json:"status"`
+	// BookingID identifies the booking once it is made
+	BookingID string    `json:"bookingId,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// IsEmpty reports whether nothing has been recorded in the draft
+func (d BookingDraft) IsEmpty() bool {
+	return d.Status == ""
+}
+
+// End returns the end of the meeting, or the zero time if the start or duration is unknown
+func (d BookingDraft) End() time.Time {
+	if d.Start == nil || d.Duration == 0 {
+		return time.Time{}
+	}
+	return d.Start.Add(time.Duration(d.Duration) * time.Minute)
+}
+
+// missingFields returns the fields a booking needs that the draft doesn't have yet
+func (d BookingDraft) missingFields() []string {
+	missing := []string{}
+	if d.EventTypeID == 0 {
+		missing = append(missing, "eventType")
+	}
+	if d.Start == nil {
+		missing = append(missing, "start")
+	}
+	if d.Duration == 0 {
+		missing = append(missing, "duration")
+	}
+	if d.AttendeeName == "" {
+		missing = append(missing, "attendeeName")
+	}
+	if d.AttendeeEmail == "" {
+		missing = append(missing, "attendeeEmail")
+	}
+	return missing
+}
+
+// DraftTracker keeps the booking draft of a conversation up to date during a turn. It is safe
+// for concurrent use.
+type DraftTracker struct {
+	mu      sync.Mutex
+	draft   BookingDraft
+	changed bool
+}
+
+// NewDraftTracker returns a tracker starting from the stored draft of the conversation
+func NewDraftTracker(draft BookingDraft) *DraftTracker {
+	return &DraftTracker{draft: draft}
+}
+
+// Update changes the draft with update. Once a booking is made, the next update starts a new draft.
+func (t *DraftTracker) Update(update func(*BookingDraft)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draft.Status == DraftBooked {
+		t.draft = BookingDraft{}
+	}
+	update(&t.draft)
+	t.draft.Missing = t.draft.missingFields()
+	if t.draft.Status == "" {
+		t.draft.Status = DraftCollecting
+	}
+	t.draft.UpdatedAt = time.Now()
+	t.changed = true
+}
+
+// Draft returns the current draft
+func (t *DraftTracker) Draft() BookingDraft {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.draft
+}
+
+// Changed reports whether the draft was updated since the tracker was created
+func (t *DraftTracker) Changed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.changed
+}
+
+// draftTrackerKey is the context key of the booking draft tracker of a conversation
+type draftTrackerKey struct{}
+
+// WithDraftTracker returns a copy of ctx carrying the tracker of the conversation's booking draft
+func WithDraftTracker(ctx context.Context, tracker *DraftTracker) context.Context {
+	return context.WithValue(ctx, draftTrackerKey{}, tracker)
+}
+
+// DraftTrackerFromContext returns the booking draft tracker stored in ctx, or nil
+func DraftTrackerFromContext(ctx context.Context) *DraftTracker {
+	tracker, _ := ctx.Value(draftTrackerKey{}).(*DraftTracker)
+	return tracker
+}
@@ -1,16 +1,28 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Event represents a Cal.com event
 type Event struct {
-	ID          string    `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description,omitempty"`
-	StartTime   time.Time `json:"startTime"`
-	EndTime     time.Time `json:"endTime"`
-	Status      string    `json:"status"`
-	Location    string    `json:"location,omitempty"`
+	ID          string     `json:"id"`
+	UID         string     `json:"uid,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	StartTime   time.Time  `json:"startTime"`
+	EndTime     time.Time  `json:"endTime"`
+	Status      string     `json:"status"`
+	Location    string     `json:"location,omitempty"`
+	EventTypeID int        `json:"eventTypeId,omitempty"`
+	Attendees   []Attendee `json:"attendees,omitempty"`
+	Organizer   *Attendee  `json:"organizer,omitempty"`
+}
+
+// Cancelled reports whether the event was cancelled or rejected, so it no longer takes place
+func (e Event) Cancelled() bool {
+	return strings.EqualFold(e.Status, "cancelled") || strings.EqualFold(e.Status, "rejected")
 }
 
 // BookingRequest represents the parameters needed to book a new event
@@ -23,6 +35,10 @@ type BookingRequest struct {
 	Notes       string    `json:"notes,omitempty"`
 	Location    string    `json:"location,omitempty"`
 	Title       string    `json:"title,omitempty"`
+	// TimeZone is the attendee's IANA timezone; Cal.com uses it in confirmations
+	TimeZone string `json:"timeZone,omitempty"`
+	// IdempotencyKey identifies the booking across retries; derived from the booking when empty
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
 }
 
 // AvailabilityRequest represents the parameters to check availability
@@ -41,7 +57,7 @@ type EventTypeCreateRequest struct {
 	LengthUnit  string `json:"lengthUnit"`
 }
 
-// EventType represents event types fetched from Cal.com for random selection
+// EventType represents event types fetched from Cal.com
 type EventType struct {
 	ID          int    `json:"id"`
 	Title       string `json:"title"`
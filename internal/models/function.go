@@ -12,3 +12,12 @@ type FunctionCall struct {
 	Name      string `json:"name"`
 	Arguments string `json:"arguments"`
 }
+
+// ExecutedFunctionCall records a function the chatbot ran while answering a message
+type ExecutedFunctionCall struct {
+	ID        string      `json:"id,omitempty"`
+	Name      string      `json:"name"`
+	Arguments string      `json:"arguments"`
+	Result    interface{} `json:"result,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
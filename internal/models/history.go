@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// HistoryEntry is a single stored message of a conversation
+type HistoryEntry struct {
+	ChatMessage
+	ToolCalls []ExecutedFunctionCall `json:"toolCalls,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+	Metadata  map[string]string      `json:"metadata,omitempty"`
+	// Usage is the token usage of the completions behind an assistant message
+	Usage *Usage `json:"usage,omitempty"`
+	// Draft is the booking draft of the conversation after an assistant message that changed it
+	Draft *BookingDraft `json:"draft,omitempty"`
+}
+
+// HistorySearchResult is a message matching a history search
+type HistorySearchResult struct {
+	ConversationID string    `json:"conversationId"`
+	Role           string    `json:"role"`
+	Snippet        string    `json:"snippet"`
+	Timestamp      time.Time `json:"timestamp"`
+	Score          float64   `json:"score"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// UserProfile identifies the user a chat request is made for
+type UserProfile struct {
+	UserID   string `json:"userId,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Location returns the user's timezone, or UTC if it is unset or unknown
+func (p UserProfile) Location() *time.Location {
+	if p.Timezone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// userProfileKey is the context key of the request's UserProfile
+type userProfileKey struct{}
+
+// WithUserProfile returns a copy of ctx carrying the user's profile
+func WithUserProfile(ctx context.Context, profile UserProfile) context.Context {
+	return context.WithValue(ctx, userProfileKey{}, profile)
+}
+
+// UserProfileFromContext returns the profile stored in ctx, or an empty profile
+func UserProfileFromContext(ctx context.Context) UserProfile {
+	profile, _ := ctx.Value(userProfileKey{}).(UserProfile)
+	return profile
+}
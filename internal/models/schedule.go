@@ -0,0 +1,38 @@
+package models
+
+// Schedule is a set of working hours event types can be booked in
+type Schedule struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	TimeZone  string `json:"timeZone"`
+	IsDefault bool   `json:"isDefault"`
+	// Availability are the weekly hours of the schedule
+	Availability []AvailabilityRule `json:"availability"`
+	// Overrides replace the hours of single dates, e.g. holidays
+	Overrides []DateOverride `json:"overrides"`
+}
+
+// AvailabilityRule makes the hours from StartTime to EndTime available on every one of Days.
+// Days are English weekday names, e.g. "Monday"; times are HH:MM in the schedule's timezone.
+type AvailabilityRule struct {
+	Days      []string `json:"days"`
+	StartTime string   `json:"startTime"`
+	EndTime   string   `json:"endTime"`
+}
+
+// DateOverride makes only the hours from StartTime to EndTime available on Date, a YYYY-MM-DD date
+type DateOverride struct {
+	Date      string `json:"date"`
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+}
+
+// ScheduleUpdate changes a schedule. Empty fields are left unchanged; Availability and Overrides
+// replace the schedule's rules when set.
+type ScheduleUpdate struct {
+	Name         string             `json:"name,omitempty"`
+	TimeZone     string             `json:"timeZone,omitempty"`
+	IsDefault    *bool              `json:"isDefault,omitempty"`
+	Availability []AvailabilityRule `json:"availability,omitempty"`
+	Overrides    []DateOverride     `json:"overrides,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// Stream event types sent to clients of the streaming chat endpoint
+const (
+	StreamEventDelta         = "delta"
+	StreamEventToolCallStart = "tool_call_start"
+	StreamEventToolCallEnd   = "tool_call_end"
+	StreamEventMessage       = "message"
+	StreamEventError         = "error"
+)
+
+// StreamEvent is a single event of a streamed chat response
+type StreamEvent struct {
+	Type           string                 `json:"type"`
+	Content        string                 `json:"content,omitempty"`
+	ToolCall       *ExecutedFunctionCall  `json:"toolCall,omitempty"`
+	FunctionCalls  []ExecutedFunctionCall `json:"functionCalls,omitempty"`
+	ConversationID string                 `json:"conversationId,omitempty"`
+	Error          string                 `json:"error,omitempty"`
+	Draft          *BookingDraft          `json:"draft,omitempty"`
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Usage is the token usage of OpenAI chat completions and its estimated cost in USD
+type Usage struct {
+	// Model is the model of the last completion
+	Model            string  `json:"model,omitempty"`
+	Completions      int     `json:"completions"`
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	TotalTokens      int     `json:"totalTokens"`
+	EstimatedCost    float64 `json:"estimatedCost"`
+	// Unpriced is set if a completion's model has no price, so EstimatedCost is too low
+	Unpriced bool `json:"unpriced,omitempty"`
+	// Estimated is set if a completion didn't report its tokens, e.g. a streamed one, so they
+	// were estimated from the length of its messages
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// Add adds other to u
+func (u *Usage) Add(other Usage) {
+	if other.Model != "" {
+		u.Model = other.Model
+	}
+	u.Completions += other.Completions
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+	u.EstimatedCost += other.EstimatedCost
+	u.Unpriced = u.Unpriced || other.Unpriced
+	u.Estimated = u.Estimated || other.Estimated
+}
+
+// TurnUsage is the usage of one turn of a conversation
+type TurnUsage struct {
+	Timestamp time.Time `json:"timestamp"`
+	Usage     Usage     `json:"usage"`
+}
+
+// ConversationUsage is the usage of a conversation, turn by turn and in total
+type ConversationUsage struct {
+	ConversationID string      `json:"conversationId"`
+	Turns          []TurnUsage `json:"turns"`
+	Total          Usage       `json:"total"`
+}
+
+// UsageRecorder adds up the usage of the completions made for a turn. It is safe for concurrent use.
+type UsageRecorder struct {
+	mu    sync.Mutex
+	usage Usage
+}
+
+// Record adds the usage of a completion
+func (r *UsageRecorder) Record(usage Usage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.usage.Add(usage)
+}
+
+// Usage returns the usage recorded so far
+func (r *UsageRecorder) Usage() Usage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.usage
+}
+
+// usageRecorderKey is the context key of the usage recorder of a turn
+type usageRecorderKey struct{}
+
+// WithUsageRecorder returns a copy of ctx carrying the usage recorder completions are recorded in
+func WithUsageRecorder(ctx context.Context, recorder *UsageRecorder) context.Context {
+	return context.WithValue(ctx, usageRecorderKey{}, recorder)
+}
+
+// UsageRecorderFromContext returns the usage recorder stored in ctx, or nil
+func UsageRecorderFromContext(ctx context.Context) *UsageRecorder {
+	recorder, _ := ctx.Value(usageRecorderKey{}).(*UsageRecorder)
+	return recorder
+}
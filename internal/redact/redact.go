@@ -0,0 +1,69 @@
+// Package redact masks secrets and personal data before they are logged or returned in errors
+package redact
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces a secret
+const Mask = "[REDACTED]"
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// secretParamPattern matches credentials in query strings and JSON, e.g. apiKey=... or "apiKey":"..."
+	secretParamPattern = regexp.MustCompile(`(?i)((?:api_?key|access_?token|token|secret|password)"?\s*[=:]\s*"?)[^&"\s,}]+`)
+	bearerPattern      = regexp.MustCompile(`(?i)(bearer\s+)[^\s"]+`)
+	// keyPattern matches Cal.com and OpenAI API keys
+	keyPattern = regexp.MustCompile(`\b(?:cal_(?:live|test)_|sk-)[A-Za-z0-9_\-]+`)
+)
+
+// sensitiveParams are query parameters whose values are always masked
+var sensitiveParams = map[string]bool{
+	"apikey":       true,
+	"api_key":      true,
+	"token":        true,
+	"access_token": true,
+	"secret":       true,
+	"password":     true,
+}
+
+// Email masks an email address, keeping its first character and domain: j***@example.com
+func Email(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return Mask
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// String masks email addresses, API keys and bearer tokens in s
+func String(s string) string {
+	s = secretParamPattern.ReplaceAllString(s, "${1}"+Mask)
+	s = bearerPattern.ReplaceAllString(s, "${1}"+Mask)
+	s = keyPattern.ReplaceAllString(s, Mask)
+	return emailPattern.ReplaceAllStringFunc(s, Email)
+}
+
+// URL masks credentials and email addresses in the query of a URL or path
+func URL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return String(rawURL)
+	}
+	query := u.Query()
+	for name, values := range query {
+		for i, value := range values {
+			if sensitiveParams[strings.ToLower(name)] {
+				values[i] = Mask
+			} else {
+				values[i] = String(value)
+			}
+		}
+		query[name] = values
+	}
+	u.RawQuery = query.Encode()
+	// Keep the mask readable instead of percent-encoded
+	return strings.ReplaceAll(u.String(), url.QueryEscape(Mask), Mask)
+}
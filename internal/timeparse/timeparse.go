@@ -0,0 +1,614 @@
+// Package timeparse resolves natural-language date and time expressions such as
+// "next Tuesday at 3", "tomorrow morning", "in two hours", "the 14th",
+// "end of next week" or "between 2 and 4pm Friday" against a reference time.
+//
+// Conventions:
+//   - Results are in the location of the reference time.
+//   - A weekday on its own ("Friday", "this Friday") is its next occurrence, today included;
+//     "next Friday" is the Friday of next week (weeks start on Monday); "last Friday" is the
+//     most recent Friday before today.
+//   - A day of the month or a date without a year is the next occurrence, today included.
+//   - An hour from 1 to 7 without am/pm ("at 3", "3:30") is in the afternoon, unless it is
+//     zero-padded ("03:30") or said to be in the morning ("at 7:30 in the morning").
+//   - "10.30" is read as "10:30".
+//   - Weekday abbreviations that are also words ("sat", "sun", "mon", "wed") only count after
+//     "on" or "this"/"next"/"last"/"coming".
+//   - An offset in minutes or hours ("in 2 hours") can't be combined with a date or part of the day.
+//   - Numbers left over that aren't part of a known expression, such as "10/20", are an error
+//     rather than being ignored.
+//   - A time without a date is on the reference day.
+package timeparse
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoMatch is returned when the text contains no date or time expression
+var ErrNoMatch = errors.New("no date or time found")
+
+// Result is a resolved expression: a single instant when Start equals End,
+// otherwise the period from Start (inclusive) to End (exclusive)
+type Result struct {
+	Start time.Time
+	End   time.Time
+}
+
+// IsInstant reports whether the expression named a single point in time
+func (r Result) IsInstant() bool {
+	return r.Start.Equal(r.End)
+}
+
+// Parse resolves the date and time expression in text against ref.
+// Words that are not part of an expression are ignored, but numbers are not.
+func Parse(text string, ref time.Time) (Result, error) {
+	p := &parser{text: normalize(text), ref: ref}
+
+	// Order matters: longer expressions are consumed first so their parts aren't matched again
+	p.parseTimeRange()
+	p.parseTimeOfDay()
+	p.parseOffset()
+	p.parseDate()
+	p.parsePartOfDay()
+
+	if !p.found {
+		return Result{}, ErrNoMatch
+	}
+	if p.instant != nil && (p.date != nil || p.hasPart) {
+		p.fail(errors.New("an offset in minutes or hours can't be combined with a date or part of the day"))
+	}
+	if leftover := leftoverNumberPattern.FindString(p.text); leftover != "" {
+		p.fail(fmt.Errorf("unrecognized date or time %q", strings.TrimSpace(leftover)))
+	}
+	if p.err != nil {
+		return Result{}, p.err
+	}
+	return p.result(), nil
+}
+
+// clock is a time of day in minutes after midnight
+type clock int
+
+// parser holds the parts of an expression found so far. Each parse step removes
+// the text it consumes.
+type parser struct {
+	text  string
+	ref   time.Time
+	found bool
+	err   error
+
+	// date is the start of the day the expression refers to, if it named one
+	date    *time.Time
+	days    int // length of the period starting at date, in days (0 means one day)
+	instant *time.Time
+
+	start, end *clock
+	// guessedPM is set when a bare hour was moved to the afternoon by default
+	guessedPM bool
+	partStart clock
+	partEnd   clock
+	hasPart   bool
+}
+
+// consume removes the first match of re from the text and returns its submatches, or nil
+func (p *parser) consume(re *regexp.Regexp) []string {
+	return p.consumeIf(re, nil)
+}
+
+// consumeIf is like consume but only takes the first match accept agrees to
+func (p *parser) consumeIf(re *regexp.Regexp, accept func(matches []string) bool) []string {
+	for _, loc := range re.FindAllStringSubmatchIndex(p.text, -1) {
+		matches := make([]string, len(loc)/2)
+		for i := range matches {
+			if loc[2*i] >= 0 {
+				matches[i] = p.text[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		if accept != nil && !accept(matches) {
+			continue
+		}
+		p.text = p.text[:loc[0]] + " " + p.text[loc[1]:]
+		p.found = true
+		return matches
+	}
+	return nil
+}
+
+// fail records the first error found while parsing
+func (p *parser) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// setDate sets the day the expression refers to, spanning days days
+func (p *parser) setDate(date time.Time, days int) {
+	if p.date != nil {
+		p.fail(fmt.Errorf("more than one date in %q", strings.TrimSpace(p.text)))
+		return
+	}
+	date = startOfDay(date)
+	p.date, p.days = &date, days
+}
+
+// result combines the parts found into a Result
+func (p *parser) result() Result {
+	if p.instant != nil {
+		return Result{Start: *p.instant, End: *p.instant}
+	}
+
+	day := startOfDay(p.ref)
+	if p.date != nil {
+		day = *p.date
+	}
+	switch {
+	case p.start != nil && p.end != nil:
+		return Result{Start: p.start.on(day), End: p.end.on(day)}
+	case p.start != nil:
+		t := p.start.on(day)
+		return Result{Start: t, End: t}
+	case p.hasPart:
+		return Result{Start: p.partStart.on(day), End: p.partEnd.on(day)}
+	}
+	days := p.days
+	if days < 1 {
+		days = 1
+	}
+	return Result{Start: day, End: day.AddDate(0, 0, days)}
+}
+
+// on returns the clock time on day. It is built from the wall clock rather than added to
+// midnight, so it is right on days when daylight saving time starts or ends.
+func (c clock) on(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(c)/60, int(c)%60, 0, 0, day.Location())
+}
+
+// movedToAfternoon reports whether the bare hour in hourText was read as c in the afternoon
+func movedToAfternoon(hourText string, c clock) bool {
+	hour, _ := strconv.Atoi(hourText)
+	return hour < 12 && c >= 12*60
+}
+
+// startOfDay returns midnight of t's day in t's location
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// numberWords maps spelled-out numbers to their value
+var numberWords = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+	"fifteen": 15, "twenty": 20, "thirty": 30, "forty five": 45, "couple of": 2, "few": 3,
+}
+
+// decimalTimePattern matches times written with a period, such as "10.30"
+var decimalTimePattern = regexp.MustCompile(`\b(\d{1,2})\.(\d{2})\b`)
+
+// normalize lowercases text and collapses punctuation and whitespace
+func normalize(text string) string {
+	text = strings.ToLower(text)
+	text = decimalTimePattern.ReplaceAllString(text, "$1:$2")
+	text = strings.NewReplacer(",", " ", ".", " ", "!", " ", "?", " ", "–", "-", "—", "-").Replace(text)
+	return " " + strings.Join(strings.Fields(text), " ") + " "
+}
+
+const clockPattern = `(\d{1,2})(?::(\d{2}))?\s*(am|pm|a m|p m)?|(noon|midday)`
+
+var (
+	timeRangePattern = regexp.MustCompile(`\s(?:between|from)?\s*(?:` + clockPattern + `)\s*(?:-|to|and|until|till)\s*(?:` + clockPattern + `)\s`)
+	// leftoverNumberPattern finds a word with digits that no parse step consumed
+	leftoverNumberPattern = regexp.MustCompile(`\S*\d\S*`)
+	timeOfDayPattern      = regexp.MustCompile(`\s(?:at\s+)?(?:(\d{1,2})(?::(\d{2}))?\s*(am|pm|a m|p m)|(\d{1,2}):(\d{2})|(noon|midday))\s|\sat\s+(\d{1,2})\s`)
+)
+
+// parseTimeRange finds ranges such as "between 2 and 4pm" or "2-4pm"
+func (p *parser) parseTimeRange() {
+	// Two bare numbers like "10-11" are only a time range after "between" or "from"
+	m := p.consumeIf(timeRangePattern, func(m []string) bool {
+		return strings.Contains(m[0], "between") || strings.Contains(m[0], "from") ||
+			strings.Join(m[2:5], "") != "" || strings.Join(m[6:9], "") != ""
+	})
+	if m == nil {
+		return
+	}
+	end, err := clockFromParts(m[5], m[6], m[7], m[8], "")
+	if err != nil {
+		p.fail(err)
+		return
+	}
+	// The start inherits the meridiem of the end unless that would put it after the end
+	start, err := clockFromParts(m[1], m[2], m[3], m[4], meridiemOf(m[7]))
+	if err != nil {
+		p.fail(err)
+		return
+	}
+	if m[3] == "" && m[4] == "" && start >= end && start >= 12*60 {
+		start -= 12 * 60
+	}
+	if m[3] == "" && m[4] == "" && m[7] == "" && m[8] == "" {
+		p.guessedPM = movedToAfternoon(m[1], start) || movedToAfternoon(m[5], end)
+	}
+	if end <= start {
+		p.fail(fmt.Errorf("the time range %q ends before it starts", strings.TrimSpace(m[0])))
+		return
+	}
+	p.start, p.end = &start, &end
+}
+
+// parseTimeOfDay finds a single time such as "3pm", "15:30", "at 3" or "noon"
+func (p *parser) parseTimeOfDay() {
+	if p.start != nil {
+		return
+	}
+	m := p.consume(timeOfDayPattern)
+	if m == nil {
+		return
+	}
+	var c clock
+	var err error
+	switch {
+	case m[1] != "":
+		c, err = clockFromParts(m[1], m[2], m[3], "", "")
+	case m[4] != "":
+		// "15:30" and "03:30" are on a 24-hour clock, while "3:30" is read like "at 3"
+		defaultMeridiem := ""
+		if strings.HasPrefix(m[4], "0") {
+			defaultMeridiem = "24h"
+		}
+		c, err = clockFromParts(m[4], m[5], "", "", defaultMeridiem)
+		p.guessedPM = movedToAfternoon(m[4], c)
+	case m[6] != "":
+		c, err = clockFromParts("", "", "", m[6], "")
+	default:
+		c, err = clockFromParts(m[7], "", "", "", "")
+		p.guessedPM = movedToAfternoon(m[7], c)
+	}
+	if err != nil {
+		p.fail(err)
+		return
+	}
+	p.start = &c
+}
+
+// meridiemOf normalizes "a m"/"p m" to "am"/"pm"
+func meridiemOf(s string) string {
+	return strings.ReplaceAll(s, " ", "")
+}
+
+// clockFromParts builds a clock time from hour, minute, am/pm and noon parts.
+// defaultMeridiem applies when meridiem is empty; "24h" means the hour is on a 24-hour clock.
+func clockFromParts(hourText, minuteText, meridiem, noon, defaultMeridiem string) (clock, error) {
+	if noon != "" {
+		return 12 * 60, nil
+	}
+	hour, _ := strconv.Atoi(hourText)
+	minute := 0
+	if minuteText != "" {
+		minute, _ = strconv.Atoi(minuteText)
+	}
+	if minute > 59 {
+		return 0, fmt.Errorf("invalid time %s:%s", hourText, minuteText)
+	}
+
+	meridiem = meridiemOf(meridiem)
+	if meridiem == "" {
+		meridiem = defaultMeridiem
+	}
+	switch meridiem {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0, fmt.Errorf("invalid time %s%s", hourText, meridiem)
+		}
+		hour %= 12
+		if meridiem == "pm" {
+			hour += 12
+		}
+	default:
+		if hour > 23 {
+			return 0, fmt.Errorf("invalid time %s:%02d", hourText, minute)
+		}
+		// Without am/pm, early hours are assumed to be in the afternoon
+		if meridiem != "24h" && hour >= 1 && hour <= 7 {
+			hour += 12
+		}
+	}
+	return clock(hour*60 + minute), nil
+}
+
+var offsetPattern = regexp.MustCompile(`\sin\s+(\d+|a|an|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve|fifteen|twenty|thirty|forty five|a couple of|couple of|a few|few)\s+(minute|min|hour|hr|day|week|month)s?\s|\sin\s+half\s+an\s+hour\s`)
+
+// parseOffset finds offsets from the reference time such as "in two hours" or "in 3 days"
+func (p *parser) parseOffset() {
+	m := p.consume(offsetPattern)
+	if m == nil {
+		return
+	}
+	if m[1] == "" {
+		t := p.ref.Add(30 * time.Minute)
+		p.instant = &t
+		return
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		n = numberWords[strings.TrimPrefix(m[1], "a ")]
+	}
+	switch m[2] {
+	case "minute", "min":
+		t := p.ref.Add(time.Duration(n) * time.Minute)
+		p.instant = &t
+	case "hour", "hr":
+		t := p.ref.Add(time.Duration(n) * time.Hour)
+		p.instant = &t
+	case "day":
+		p.setDate(p.ref.AddDate(0, 0, n), 1)
+	case "week":
+		p.setDate(p.ref.AddDate(0, 0, 7*n), 1)
+	case "month":
+		p.setDate(p.ref.AddDate(0, n, 0), 1)
+	}
+	if p.instant != nil && p.start != nil {
+		p.fail(errors.New("a time of day can't be combined with an offset in minutes or hours"))
+	}
+}
+
+// ambiguousWeekdays are weekday abbreviations that are also ordinary words
+var ambiguousWeekdays = map[string]bool{"sat": true, "sun": true, "mon": true, "wed": true}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+const (
+	weekdayNames = `monday|mon|tuesday|tues|tue|wednesday|wed|thursday|thurs|thur|thu|friday|fri|saturday|sat|sunday|sun`
+	monthNames   = `january|jan|february|feb|march|mar|april|apr|may|june|jun|july|jul|august|aug|september|sept|sep|october|oct|november|nov|december|dec`
+	ordinal      = `(\d{1,2})(?:st|nd|rd|th)?`
+)
+
+var (
+	isoDatePattern     = regexp.MustCompile(`\s(\d{4})-(\d{2})-(\d{2})\s`)
+	relativeDayPattern = regexp.MustCompile(`\s(day after tomorrow|today|tonight|tomorrow|tmrw|yesterday)\s`)
+	weekPartPattern    = regexp.MustCompile(`\s(end|start|beginning)\s+of\s+(?:(the|this|next)\s+)?(week|month)\s`)
+	weekPattern        = regexp.MustCompile(`\s(this|next)\s+(week|month)\s`)
+	weekdayPattern     = regexp.MustCompile(`\s(on\s+)?(?:(this|next|last|coming)\s+)?(` + weekdayNames + `)\s`)
+	monthDayPattern    = regexp.MustCompile(`\s(?:on\s+)?(` + monthNames + `)\s+` + ordinal + `(?:\s+(\d{4}))?\s`)
+	dayMonthPattern    = regexp.MustCompile(`\s(?:on\s+)?(?:the\s+)?` + ordinal + `\s+(?:of\s+)?(` + monthNames + `)(?:\s+(\d{4}))?\s`)
+	dayOfMonthPattern  = regexp.MustCompile(`\s(?:on\s+)?(?:the\s+)?(\d{1,2})(?:st|nd|rd|th)\s`)
+	partOfDayPattern   = regexp.MustCompile(`\s(?:(?:this|in the)\s+)?(morning|afternoon|evening|night)\s`)
+)
+
+// partsOfDay are the hours each part of the day spans
+var partsOfDay = map[string][2]clock{
+	"morning":   {9 * 60, 12 * 60},
+	"afternoon": {12 * 60, 17 * 60},
+	"evening":   {17 * 60, 21 * 60},
+	"night":     {20 * 60, 24 * 60},
+}
+
+// parseDate finds the day or period the expression refers to
+func (p *parser) parseDate() {
+	today := startOfDay(p.ref)
+	p.parseCalendarDate(today)
+	p.parseRelativeDate(today)
+}
+
+// parseCalendarDate finds dates given by day and month, such as "2025-06-14", "June 14th" or "the 14th"
+func (p *parser) parseCalendarDate(today time.Time) {
+	if m := p.consume(isoDatePattern); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		if !validDate(year, time.Month(month), day) {
+			p.fail(fmt.Errorf("invalid date %s-%s-%s", m[1], m[2], m[3]))
+			return
+		}
+		p.setDate(time.Date(year, time.Month(month), day, 0, 0, 0, 0, today.Location()), 1)
+		return
+	}
+
+	if m := p.consume(monthDayPattern); m != nil {
+		p.setMonthDay(months[m[1]], m[2], m[3], today)
+		return
+	}
+	if m := p.consume(dayMonthPattern); m != nil {
+		p.setMonthDay(months[m[2]], m[1], m[3], today)
+		return
+	}
+	if m := p.consume(dayOfMonthPattern); m != nil {
+		day, _ := strconv.Atoi(m[1])
+		if day < 1 || day > 31 {
+			p.fail(fmt.Errorf("invalid day of the month %q", m[1]))
+			return
+		}
+		// The first month, starting with this one, that has the day and where it isn't past
+		for i := 0; i < 12; i++ {
+			month := time.Date(today.Year(), today.Month()+time.Month(i), 1, 0, 0, 0, 0, today.Location())
+			if !validDate(month.Year(), month.Month(), day) {
+				continue
+			}
+			date := time.Date(month.Year(), month.Month(), day, 0, 0, 0, 0, today.Location())
+			if !date.Before(today) {
+				p.setDate(date, 1)
+				return
+			}
+		}
+	}
+}
+
+// parseRelativeDate finds dates relative to today, such as "tomorrow", "next Tuesday" or "end of next week"
+func (p *parser) parseRelativeDate(today time.Time) {
+	if m := p.consume(relativeDayPattern); m != nil {
+		switch m[1] {
+		case "today":
+			p.setDate(today, 1)
+		case "tonight":
+			p.setDate(today, 1)
+			p.setPartOfDay("evening")
+		case "tomorrow", "tmrw":
+			p.setDate(today.AddDate(0, 0, 1), 1)
+		case "day after tomorrow":
+			p.setDate(today.AddDate(0, 0, 2), 1)
+		case "yesterday":
+			p.setDate(today.AddDate(0, 0, -1), 1)
+		}
+	}
+
+	if m := p.consume(weekPartPattern); m != nil {
+		if m[3] == "week" {
+			monday := startOfWeek(today)
+			if m[2] == "next" {
+				monday = monday.AddDate(0, 0, 7)
+			}
+			if m[1] == "end" {
+				p.setDate(monday.AddDate(0, 0, 4), 1) // Friday, the end of the working week
+			} else {
+				p.setDate(monday, 1)
+			}
+		} else {
+			first := startOfMonth(today)
+			if m[2] == "next" {
+				first = first.AddDate(0, 1, 0)
+			}
+			if m[1] == "end" {
+				p.setDate(first.AddDate(0, 1, -1), 1)
+			} else {
+				p.setDate(first, 1)
+			}
+		}
+	} else if m := p.consume(weekPattern); m != nil {
+		if m[2] == "week" {
+			monday := startOfWeek(today)
+			if m[1] == "next" {
+				monday = monday.AddDate(0, 0, 7)
+			}
+			p.setDate(monday, 7)
+		} else {
+			first := startOfMonth(today)
+			if m[1] == "next" {
+				first = first.AddDate(0, 1, 0)
+			}
+			p.setDate(first, first.AddDate(0, 1, -1).Day())
+		}
+	}
+
+	// "I sat down" doesn't mean Saturday, but "on sat" and "next sat" do
+	m := p.consumeIf(weekdayPattern, func(m []string) bool {
+		return !ambiguousWeekdays[m[3]] || m[1] != "" || m[2] != ""
+	})
+	if m != nil && p.date == nil {
+		weekday := weekdays[m[3]]
+		ahead := (int(weekday) - int(today.Weekday()) + 7) % 7
+		switch m[2] {
+		case "next":
+			// The weekday in next week, counting weeks from Monday
+			monday := startOfWeek(today).AddDate(0, 0, 7)
+			p.setDate(monday.AddDate(0, 0, (int(weekday)+6)%7), 1)
+		case "last":
+			if ahead == 0 {
+				ahead = 7
+			}
+			p.setDate(today.AddDate(0, 0, ahead-7), 1)
+		default:
+			p.setDate(today.AddDate(0, 0, ahead), 1)
+		}
+	}
+}
+
+// setMonthDay sets a date given by month name and day, with an optional year.
+// Without a year it is the next occurrence of the date.
+func (p *parser) setMonthDay(month time.Month, dayText, yearText string, today time.Time) {
+	day, _ := strconv.Atoi(dayText)
+	year := today.Year()
+	if yearText != "" {
+		year, _ = strconv.Atoi(yearText)
+	}
+	if !validDate(year, month, day) && yearText == "" && validDate(year+1, month, day) {
+		year++
+	}
+	if !validDate(year, month, day) {
+		p.fail(fmt.Errorf("invalid date %s %s", month, dayText))
+		return
+	}
+	date := time.Date(year, month, day, 0, 0, 0, 0, today.Location())
+	if yearText == "" && date.Before(today) {
+		date = date.AddDate(1, 0, 0)
+	}
+	p.setDate(date, 1)
+}
+
+// parsePartOfDay finds parts of the day such as "morning" or "this afternoon"
+func (p *parser) parsePartOfDay() {
+	if m := p.consume(partOfDayPattern); m != nil {
+		p.setPartOfDay(m[1])
+	}
+}
+
+// setPartOfDay narrows the expression to a part of the day. A morning time of day
+// that falls in the part when moved to the afternoon ("at 8 tonight") is moved into it,
+// and a bare hour read as in the afternoon is moved back in the morning ("at 7 in the morning").
+func (p *parser) setPartOfDay(part string) {
+	span := partsOfDay[part]
+	p.partStart, p.partEnd, p.hasPart = span[0], span[1], true
+
+	if span[1] <= 12*60 && p.guessedPM {
+		for _, c := range []*clock{p.start, p.end} {
+			if c != nil && *c >= 13*60 {
+				*c -= 12 * 60
+			}
+		}
+		return
+	}
+
+	// The evening runs into the night, so "at 10 tonight" is 22:00 rather than past the evening
+	end := span[1]
+	if part == "evening" || part == "night" {
+		end = 24 * 60
+	}
+	if p.start != nil && p.end == nil && *p.start < 12*60 && *p.start < span[0] {
+		if shifted := *p.start + 12*60; shifted >= span[0] && shifted < end {
+			p.start = &shifted
+		}
+	}
+}
+
+// startOfWeek returns the Monday of t's week
+func startOfWeek(t time.Time) time.Time {
+	return t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+}
+
+// startOfMonth returns the first day of t's month
+func startOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// validDate reports whether the day exists in the month
+func validDate(year int, month time.Month, day int) bool {
+	if month < time.January || month > time.December || day < 1 {
+		return false
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Month() == month
+}
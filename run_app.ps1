@@ -20,6 +20,7 @@ OPENAI_MODEL=gpt-4-turbo
 
 # Cal.com API Configuration
 CALCOM_API_KEY=your_calcom_api_key
+CALCOM_API_VERSION=v1
 CALCOM_API_URL=https://api.cal.com/v1
 CALCOM_USERNAME=your_calcom_username
 
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -17,21 +16,19 @@ import (
 // TestAPIHandlers is a test suite for the API handlers
 func TestAPIHandlers(t *testing.T) {
 	// Skip tests if environment variables are not set
-	if os.Getenv("OPENAI_API_KEY") == "" || os.Getenv("CALCOM_API_KEY") == "" || os.Getenv("CALCOM_USERNAME") == "" {
-		t.Skip("Required environment variables not set, skipping API tests")
-	}
+	requireLiveEnv(t, "OPENAI_API_KEY", "CALCOM_API_KEY", "CALCOM_USERNAME")
 
 	// Set Gin to test mode
 	gin.SetMode(gin.TestMode)
 
 	// Create a new chatbot instance
-	bot, err := chatbot.NewChatbot()
+	bot, err := chatbot.NewChatbot(nil)
 	if err != nil {
 		t.Fatalf("Failed to create chatbot: %v", err)
 	}
 
 	// Create API handlers
-	handler := api.NewHandler(bot)
+	handler := api.NewHandler(bot, newTestHistoryStore(t))
 
 	t.Run("HandleHealth", func(t *testing.T) {
 		// Create a test router and register the health route
@@ -70,7 +67,7 @@ func TestAPIHandlers(t *testing.T) {
 
 		// Create a test request with a chat message
 		chatRequest := models.ChatRequest{
-			Message: "Hello, how are you?",
+			Messages: []models.ChatMessage{{Role: "user", Content: "Hello, how are you?"}},
 		}
 		requestBody, _ := json.Marshal(chatRequest)
 		req, _ := http.NewRequest("POST", "/api/chat", bytes.NewBuffer(requestBody))
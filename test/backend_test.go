@@ -0,0 +1,51 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/chatbot"
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/test/mocks"
+)
+
+// TestCalendarBackendInjection checks that the chatbot runs against an injected calendar backend
+func TestCalendarBackendInjection(t *testing.T) {
+	mock := mocks.NewMockCalcomClient()
+
+	bot, err := chatbot.NewChatbot(mock)
+	if err != nil {
+		t.Fatalf("Failed to create chatbot with mock backend: %v", err)
+	}
+
+	t.Run("DirectBooking", func(t *testing.T) {
+		// A direct booking payload bypasses the LLM and goes straight to the backend
+		start := time.Now().Add(200 * time.Hour).UTC().Truncate(time.Minute)
+		mock.AvailableSlots = append(mock.AvailableSlots, start)
+		messages := []models.ChatMessage{{
+			Role: "user",
+			Booking: map[string]interface{}{
+				"eventTypeId": 1,
+				"startTime":   start.Format(time.RFC3339),
+				"endTime":     start.Add(30 * time.Minute).Format(time.RFC3339),
+				"name":        "Test User",
+				"email":       "test@example.com",
+			},
+		}}
+
+		response, _, err := bot.ProcessMessage(context.Background(), messages)
+		if err != nil {
+			t.Fatalf("Direct booking failed: %v", err)
+		}
+
+		var event models.Event
+		if err := json.Unmarshal([]byte(response), &event); err != nil {
+			t.Fatalf("Failed to parse booking response: %v", err)
+		}
+		if event.ID != mock.BookedEvent.ID {
+			t.Errorf("Expected booked event %s, got %s", mock.BookedEvent.ID, event.ID)
+		}
+	})
+}
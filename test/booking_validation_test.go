@@ -0,0 +1,86 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// TestBookingValidation tests checking bookings against event type lengths and available slots before proposing them
+func TestBookingValidation(t *testing.T) {
+	bot, server, calendar := newToolTestBot(t)
+	calendar.EventTypes = testEventTypes
+	at := func(day, hour int) time.Time { return time.Date(2030, 1, day, hour, 0, 0, 0, time.UTC) }
+	calendar.AvailableSlots = []time.Time{at(7, 10), at(7, 15), at(8, 9), at(10, 10)}
+	attendee := `"name":"Jane","email":"jane@example.com"`
+
+	t.Run("EndTimeFromEventType", func(t *testing.T) {
+		call := callTool(t, bot, server, "bookMeeting", `{"eventTypeId":3,"startTime":"2030-01-07T10:00:00Z",`+attendee+`}`)
+		if _, _, err := bot.ConfirmAction(context.Background(), "", pendingToken(t, call)); err != nil {
+			t.Fatalf("ConfirmAction failed: %v", err)
+		}
+		if booking := calendar.BookingRequest; booking == nil || !booking.End.Equal(at(7, 11)) {
+			t.Errorf("Expected the hour-long event type to end at 11:00, got %+v", booking)
+		}
+	})
+
+	t.Run("LengthMismatch", func(t *testing.T) {
+		call := callTool(t, bot, server, "bookMeeting", `{"eventTypeId":2,"startTime":"2030-01-07T10:00:00Z","endTime":"2030-01-07T11:00:00Z",`+attendee+`}`)
+		if !strings.Contains(call.Error, "last 15 minutes, not 60") {
+			t.Errorf("Expected the length of the event type to be enforced, got %+v (error %q)", call.Result, call.Error)
+		}
+	})
+
+	t.Run("EndBeforeStart", func(t *testing.T) {
+		call := callTool(t, bot, server, "bookMeeting", `{"eventTypeId":3,"startTime":"2030-01-07T10:00:00Z","endTime":"2030-01-07T09:00:00Z",`+attendee+`}`)
+		if !strings.Contains(call.Error, "before it starts") {
+			t.Errorf("Expected the end before the start to be rejected, got %+v (error %q)", call.Result, call.Error)
+		}
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		call := callTool(t, bot, server, "bookMeeting", `{"eventTypeId":1,"startTime":"2030-01-07T12:00:00Z",`+attendee+`}`)
+		result, ok := call.Result.(map[string]interface{})
+		if !ok || result["status"] != "unavailable" {
+			t.Fatalf("Expected the time to be unavailable, got %+v (error %q)", call.Result, call.Error)
+		}
+		alternatives, _ := result["alternatives"].([]time.Time)
+		want := []time.Time{at(7, 10), at(7, 15), at(8, 9)}
+		if len(alternatives) != len(want) {
+			t.Fatalf("Expected the alternatives %v, got %v", want, alternatives)
+		}
+		for i := range want {
+			if !alternatives[i].Equal(want[i]) {
+				t.Fatalf("Expected the alternatives %v, got %v", want, alternatives)
+			}
+		}
+		if !calendar.SlotsStart.Equal(at(4, 12)) || !calendar.SlotsEnd.Equal(at(10, 12)) {
+			t.Errorf("Expected slots to be looked up 3 days around the requested time, got %v to %v", calendar.SlotsStart, calendar.SlotsEnd)
+		}
+	})
+
+	t.Run("DirectBookingUnavailable", func(t *testing.T) {
+		calendar.BookingRequest = nil
+		response, calls, err := bot.ProcessMessage(context.Background(), []models.ChatMessage{{Role: "user", Booking: map[string]interface{}{
+			"eventTypeId": 1,
+			"startTime":   "2030-01-07T12:00:00Z",
+			"name":        "Jane",
+			"email":       "jane@example.com",
+		}}})
+		if err != nil {
+			t.Fatalf("Expected the alternatives as the reply, got %v", err)
+		}
+		if !strings.Contains(response, "nearest available times") || len(calls) != 1 {
+			t.Errorf("Expected the nearest available times in the reply, got %q", response)
+		}
+		if result, _ := calls[0].Result.(map[string]interface{}); result["status"] != "unavailable" {
+			t.Errorf("Expected the unavailable result to be recorded, got %+v", calls[0])
+		}
+		if calendar.BookingRequest != nil {
+			t.Errorf("Expected nothing to be booked, got %+v", calendar.BookingRequest)
+		}
+	})
+}
@@ -1,7 +1,7 @@
 package test
 
 import (
-	"os"
+	"context"
 	"testing"
 	"time"
 
@@ -11,9 +11,7 @@ import (
 // TestCalcomClient is a test suite for the Cal.com API client
 func TestCalcomClient(t *testing.T) {
 	// Skip tests if environment variables are not set
-	if os.Getenv("CALCOM_API_KEY") == "" || os.Getenv("CALCOM_USERNAME") == "" {
-		t.Skip("CALCOM_API_KEY or CALCOM_USERNAME not set, skipping Cal.com API tests")
-	}
+	requireLiveEnv(t, "CALCOM_API_KEY", "CALCOM_USERNAME")
 
 	t.Run("NewClient", func(t *testing.T) {
 		client, err := calcom.NewClient()
@@ -32,7 +30,7 @@ func TestCalcomClient(t *testing.T) {
 		}
 
 		// Use a test email
-		events, err := client.GetEvents("test@example.com")
+		events, err := client.GetEvents(context.Background(), "test@example.com")
 		if err != nil {
 			t.Fatalf("Failed to get events: %v", err)
 		}
@@ -51,7 +49,7 @@ func TestCalcomClient(t *testing.T) {
 		// Use event type ID 1 (default) and a range of dates
 		now := time.Now()
 		end := now.Add(24 * 7 * time.Hour) // One week from now
-		slots, err := client.GetAvailableSlots(1, now, end)
+		slots, err := client.GetAvailableSlots(context.Background(), 1, now, end)
 		if err != nil {
 			t.Fatalf("Failed to get available slots: %v", err)
 		}
@@ -0,0 +1,113 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/cal-chatbot/internal/calcom"
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/test/mocks"
+)
+
+// TestCalcomAPIError tests that Cal.com error responses are returned as typed errors
+func TestCalcomAPIError(t *testing.T) {
+	testCases := []struct {
+		name      string
+		status    int
+		body      string
+		code      string
+		message   string
+		check     func(error) bool
+		checkName string
+	}{
+		{"V1NotFound", http.StatusNotFound, `{"message":"Booking not found"}`, "", "Booking not found", calcom.IsNotFound, "IsNotFound"},
+		{"V1SlotTaken", http.StatusBadRequest, `{"message":"no_available_users_found_error"}`, "no_available_users_found_error", "no_available_users_found_error", calcom.IsConflict, "IsConflict"},
+		{"V2Conflict", http.StatusConflict, `{"status":"error","error":{"code":"ConflictException","message":"Slot taken"}}`, "ConflictException", "Slot taken", calcom.IsConflict, "IsConflict"},
+		{"Unauthorized", http.StatusUnauthorized, `{"error":"Your API key is not valid."}`, "", "Your API key is not valid.", calcom.IsAuth, "IsAuth"},
+		{"Forbidden", http.StatusForbidden, `not json`, "", "Forbidden", calcom.IsAuth, "IsAuth"},
+		{"RateLimited", http.StatusTooManyRequests, `{"message":"Too many requests"}`, "", "Too many requests", calcom.IsRateLimited, "IsRateLimited"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Request-Id", "req-123")
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+			t.Setenv("CALCOM_API_KEY", "test-key")
+			t.Setenv("CALCOM_API_URL", server.URL)
+			t.Setenv("CALCOM_MAX_RETRIES", "0")
+			client, err := calcom.NewClient()
+			if err != nil {
+				t.Fatalf("Failed to create Cal.com client: %v", err)
+			}
+
+			_, err = client.CreateSchedule(context.Background(), "Working Hours", "UTC")
+			var apiErr *calcom.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected an APIError, got %v", err)
+			}
+			if apiErr.StatusCode != tc.status || apiErr.Code != tc.code || apiErr.Message != tc.message || apiErr.RequestID != "req-123" {
+				t.Errorf("Unexpected error fields: %+v", apiErr)
+			}
+			if !tc.check(err) {
+				t.Errorf("Expected %s to report %v", tc.checkName, err)
+			}
+			for _, other := range []func(error) bool{calcom.IsNotFound, calcom.IsConflict, calcom.IsRateLimited, calcom.IsAuth} {
+				if other(err) && !tc.check(err) {
+					t.Errorf("Unexpected check matched %v", err)
+				}
+			}
+		})
+	}
+
+	if calcom.IsNotFound(errors.New("failed to make request: timeout")) {
+		t.Error("Expected a network error not to be an APIError")
+	}
+}
+
+// TestCalendarErrorReplies tests that tool handlers explain calendar errors in a way the model can act on
+func TestCalendarErrorReplies(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"NotFound", &calcom.APIError{StatusCode: http.StatusNotFound, Message: "Not Found"}, "doesn't exist in the calendar"},
+		{"Conflict", &calcom.APIError{StatusCode: http.StatusConflict, Message: "Conflict"}, "no longer available"},
+		{"RateLimited", &calcom.APIError{StatusCode: http.StatusTooManyRequests, Message: "Too Many Requests"}, "try again in a minute"},
+		{"Auth", &calcom.APIError{StatusCode: http.StatusUnauthorized, Message: "Unauthorized"}, "isn't authorized"},
+		{"Other", errors.New("connection reset"), "failed to reschedule event: connection reset"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := mocks.NewMockOpenAIServer(
+				toolCallMessage(toolCall("call_1", "rescheduleEvent", `{"eventId":"event-1","newStartTime":"2030-01-01T10:00:00Z","newEndTime":"2030-01-01T10:30:00Z"}`)),
+				contentMessage("Shall I move it?"),
+			)
+			defer server.Close()
+			calendar := mocks.NewMockCalcomClient()
+			bot := newLoopTestBot(t, server, calendar)
+
+			_, calls, err := bot.ProcessMessage(context.Background(), []models.ChatMessage{{Role: "user", Content: "move event-1"}})
+			if err != nil {
+				t.Fatalf("ProcessMessage failed: %v", err)
+			}
+			calendar.Err = tc.err
+			_, record, err := bot.ConfirmAction(context.Background(), "", pendingToken(t, calls[0]))
+			if err != nil {
+				t.Fatalf("ConfirmAction failed: %v", err)
+			}
+			if !strings.Contains(record.Error, tc.expected) {
+				t.Errorf("Expected the error to contain %q, got %q", tc.expected, record.Error)
+			}
+		})
+	}
+}
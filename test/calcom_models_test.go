@@ -0,0 +1,171 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/calcom"
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// calcomV1Responses are v1 API responses by method and path
+var calcomV1Responses = map[string]string{
+	"GET /bookings": `{"bookings":[{"id":5,"uid":"uid-5","eventTypeId":7,"title":"Intro","status":"ACCEPTED",
+		"startTime":"2050-09-05T09:00:00.000Z","endTime":"2050-09-05T09:30:00.000Z","location":null,
+		"attendees":[{"id":3,"name":"Jane","email":"jane@example.com","timeZone":"Europe/Berlin","locale":"en"}],
+		"user":{"name":"Sam","email":"sam@example.com","timeZone":"UTC"}}]}`,
+	"GET /bookings/5": `{"booking":{"id":5,"uid":"uid-5","eventTypeId":7,"title":"Intro","status":"ACCEPTED",
+		"attendees":[{"name":"Jane","email":"jane@example.com"}],"user":{"name":"Sam","email":"sam@example.com"}}}`,
+	"GET /schedules": `{"schedules":[{"id":1,"userId":2,"name":"Working Hours","timeZone":"Europe/Berlin","availability":[
+		{"id":1,"days":[1,2,3,4,5],"startTime":"1970-01-01T09:00:00.000Z","endTime":"1970-01-01T17:00:00.000Z","date":null},
+		{"id":2,"days":[],"startTime":"1970-01-01T10:00:00.000Z","endTime":"1970-01-01T12:00:00.000Z","date":"2050-12-24"}]}]}`,
+	"POST /event-types": `{"event_type":{"id":8,"title":"Deep dive","slug":"deep-dive","length":60}}`,
+}
+
+// TestCalcomV1Models tests decoding v1 responses into typed models
+func TestCalcomV1Models(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		response, ok := calcomV1Responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+	t.Setenv("CALCOM_API_KEY", "test-key")
+	t.Setenv("CALCOM_API_URL", server.URL)
+	t.Setenv("CALCOM_IDEMPOTENCY_FILE", "")
+	client, err := calcom.NewClient()
+	if err != nil {
+		t.Fatalf("Failed to create Cal.com client: %v", err)
+	}
+	ctx := context.Background()
+
+	t.Run("Bookings", func(t *testing.T) {
+		events, err := client.GetEvents(ctx, "")
+		if err != nil {
+			t.Fatalf("GetEvents failed: %v", err)
+		}
+		if len(events) != 1 {
+			t.Fatalf("Expected 1 event, got %+v", events)
+		}
+		event := events[0]
+		if event.ID != "5" || event.UID != "uid-5" || event.EventTypeID != 7 || len(event.Attendees) != 1 ||
+			event.Attendees[0].TimeZone != "Europe/Berlin" || event.Organizer == nil || event.Organizer.Name != "Sam" {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+
+		booking, err := client.FindBooking(ctx, "5")
+		if err != nil {
+			t.Fatalf("FindBooking failed: %v", err)
+		}
+		if booking.ID != 5 || booking.Attendees[0].Email != "jane@example.com" || booking.Organizer.Email != "sam@example.com" {
+			t.Errorf("Unexpected booking: %+v", booking)
+		}
+	})
+
+	t.Run("Schedules", func(t *testing.T) {
+		schedules, err := client.FindAllSchedules(ctx)
+		if err != nil {
+			t.Fatalf("FindAllSchedules failed: %v", err)
+		}
+		if len(schedules) != 1 {
+			t.Fatalf("Expected 1 schedule, got %+v", schedules)
+		}
+		schedule := schedules[0]
+		want := models.AvailabilityRule{Days: []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}, StartTime: "09:00", EndTime: "17:00"}
+		if len(schedule.Availability) != 1 || len(schedule.Availability[0].Days) != 5 || schedule.Availability[0].Days[0] != want.Days[0] ||
+			schedule.Availability[0].StartTime != want.StartTime || schedule.Availability[0].EndTime != want.EndTime {
+			t.Errorf("Expected weekday hours %+v, got %+v", want, schedule.Availability)
+		}
+		if len(schedule.Overrides) != 1 || schedule.Overrides[0] != (models.DateOverride{Date: "2050-12-24", StartTime: "10:00", EndTime: "12:00"}) {
+			t.Errorf("Expected the date override on 2050-12-24, got %+v", schedule.Overrides)
+		}
+
+		before := requests
+		isDefault := true
+		if _, err := client.EditSchedule(ctx, "1", models.ScheduleUpdate{IsDefault: &isDefault}); !errors.Is(err, calcom.ErrUnsupported) {
+			t.Errorf("Expected making a schedule the default to be unsupported in v1, got %v", err)
+		}
+		if requests != before {
+			t.Error("Expected no request for an unsupported edit")
+		}
+	})
+
+	t.Run("EventTypes", func(t *testing.T) {
+		eventType, err := client.CreateEventType(ctx, models.EventTypeCreateRequest{Title: "Deep dive", Slug: "deep-dive", Length: 60, LengthUnit: "minutes"})
+		if err != nil {
+			t.Fatalf("CreateEventType failed: %v", err)
+		}
+		if eventType.ID != 8 || eventType.Length != 60 {
+			t.Errorf("Unexpected event type: %+v", eventType)
+		}
+	})
+}
+
+// TestCalcomV1PathEscaping tests that IDs can't change the endpoint a v1 request goes to
+func TestCalcomV1PathEscaping(t *testing.T) {
+	var paths []string
+	client := newTestCalcomClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.EscapedPath())
+		w.Write([]byte(`{"booking":{}}`))
+	}), calcomTestOptions{})
+	ctx := context.Background()
+	id := "../event-types/1"
+
+	client.CancelEvent(ctx, id)
+	client.RescheduleEvent(ctx, id, time.Now(), time.Now().Add(time.Hour))
+	client.RemoveSchedule(ctx, id)
+	client.CancelBooking(ctx, id)
+
+	expected := []string{
+		"POST /bookings/..%2Fevent-types%2F1/cancel",
+		"POST /bookings/..%2Fevent-types%2F1/reschedule",
+		"DELETE /schedules/..%2Fevent-types%2F1",
+		"POST /bookings/..%2Fevent-types%2F1/cancel",
+	}
+	if strings.Join(paths, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected the IDs to be escaped in the paths %v, got %v", expected, paths)
+	}
+}
+
+// TestCalcomV1BookableSlots tests that an event type whose slots can't be fetched is left out
+func TestCalcomV1BookableSlots(t *testing.T) {
+	t.Setenv("CALCOM_USERNAME", "sam")
+	t.Setenv("CALCOM_MAX_RETRIES", "0")
+	failing := map[string]bool{"/availability/sam/1": true, "/availability/sam/2": false}
+	client := newTestCalcomClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch fail, ok := failing[r.URL.Path]; {
+		case r.URL.Path == "/event-types":
+			w.Write([]byte(`{"eventTypes":[{"id":1,"length":30},{"id":2,"length":60}]}`))
+		case ok && fail:
+			w.WriteHeader(http.StatusInternalServerError)
+		case ok:
+			w.Write([]byte(`{"available":["2050-09-05T09:00:00Z","2050-09-05T10:00:00Z"]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}), calcomTestOptions{})
+	from := time.Date(2050, 9, 5, 0, 0, 0, 0, time.UTC)
+
+	slots, err := client.GetBookableSlots(context.Background(), from, from.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("Expected the slots of the other event type, got %v", err)
+	}
+	if len(slots) != 2 || slots[0].EventTypeID != 2 || slots[0].End.Sub(slots[0].Start) != time.Hour {
+		t.Errorf("Expected the 2 slots of event type 2, got %+v", slots)
+	}
+
+	failing["/availability/sam/2"] = true
+	if _, err := client.GetBookableSlots(context.Background(), from, from.AddDate(0, 0, 1)); err == nil {
+		t.Error("Expected an error when no event type's slots can be fetched")
+	}
+}
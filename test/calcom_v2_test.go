@@ -0,0 +1,263 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/calcom"
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// fakeCalcomV2 is a fake Cal.com API v2. It records the requests that were missing the expected
+// cal-api-version header or bearer token, or that sent the API key as a query parameter.
+type fakeCalcomV2 struct {
+	mu       sync.Mutex
+	problems []string
+	bodies   map[string]map[string]interface{}
+}
+
+// apiVersions are the cal-api-version headers expected by path prefix
+var apiVersions = map[string]string{
+	"/bookings":    "2024-08-13",
+	"/slots":       "2024-09-04",
+	"/event-types": "2024-06-14",
+	"/schedules":   "2024-06-11",
+}
+
+// ServeHTTP answers with v2 response envelopes
+func (f *fakeCalcomV2) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for prefix, version := range apiVersions {
+		if strings.HasPrefix(r.URL.Path, prefix) && r.Header.Get("cal-api-version") != version {
+			f.problems = append(f.problems, r.URL.Path+": cal-api-version "+r.Header.Get("cal-api-version"))
+		}
+	}
+	if r.Header.Get("Authorization") != "Bearer test-key" {
+		f.problems = append(f.problems, r.URL.Path+": missing bearer token")
+	}
+	if r.URL.Query().Has("apiKey") {
+		f.problems = append(f.problems, r.URL.Path+": API key in query")
+	}
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	f.bodies[r.Method+" "+r.URL.Path] = body
+
+	var data interface{}
+	switch r.Method + " " + r.URL.Path {
+	case "GET /bookings":
+		data = []map[string]interface{}{{
+			"uid": "uid-1", "title": "Intro", "status": "accepted",
+			"start": "2050-09-05T09:00:00.000Z", "end": "2050-09-05T09:30:00.000Z",
+		}}
+	case "POST /bookings":
+		data = map[string]interface{}{
+			"uid": "uid-2", "title": "Intro", "status": "accepted",
+			"start": body["start"], "end": "2050-09-05T10:30:00.000Z",
+		}
+	case "POST /bookings/uid-1/reschedule":
+		data = map[string]interface{}{
+			"uid": "uid-3", "title": "Intro", "status": "accepted",
+			"start": body["start"], "end": "2050-09-06T09:30:00.000Z",
+		}
+	case "GET /slots":
+		data = map[string]interface{}{
+			"2050-09-06": []map[string]string{{"start": "2050-09-06T11:00:00.000+02:00"}},
+			"2050-09-05": []map[string]string{{"start": "2050-09-05T11:00:00.000+02:00"}, {"start": "2050-09-05T11:30:00.000+02:00"}},
+		}
+	case "GET /bookings/uid-1":
+		data = map[string]interface{}{
+			"id": 11, "uid": "uid-1", "title": "Intro", "status": "accepted", "eventTypeId": 7,
+			"start": "2050-09-05T09:00:00.000Z", "end": "2050-09-05T09:30:00.000Z",
+			"attendees": []map[string]string{{"name": "Jane", "email": "jane@example.com", "timeZone": "Europe/Berlin"}},
+			"hosts":     []map[string]interface{}{{"id": 1, "name": "Sam", "email": "sam@example.com"}},
+		}
+	case "GET /event-types":
+		data = []map[string]interface{}{{"id": 7, "title": "Intro", "slug": "intro", "lengthInMinutes": 30}}
+	case "GET /schedules":
+		data = []map[string]interface{}{{
+			"id": 1, "name": "Working Hours", "timeZone": "Europe/Berlin", "isDefault": true,
+			"availability": []map[string]interface{}{{"days": []string{"Monday", "Friday"}, "startTime": "09:00", "endTime": "17:00"}},
+			"overrides":    []map[string]string{{"date": "2050-12-24", "startTime": "09:00", "endTime": "12:00"}},
+		}}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "error",
+			"error":  map[string]string{"code": "NotFoundException", "message": "Not found"},
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "data": data})
+}
+
+// Problems returns the header problems seen so far
+func (f *fakeCalcomV2) Problems() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.problems
+}
+
+// Body returns the JSON body of the last request to method and path
+func (f *fakeCalcomV2) Body(method, path string) map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.bodies[method+" "+path]
+}
+
+// TestCalcomV2Client tests the Cal.com API v2 client against a fake v2 API
+func TestCalcomV2Client(t *testing.T) {
+	fake := &fakeCalcomV2{bodies: map[string]map[string]interface{}{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	t.Setenv("CALCOM_API_KEY", "test-key")
+	t.Setenv("CALCOM_API_URL", server.URL)
+	t.Setenv("CALCOM_API_VERSION", "v2")
+	t.Setenv("CALCOM_IDEMPOTENCY_FILE", "")
+	t.Setenv("CALCOM_MAX_RETRIES", "0")
+	backend, err := calcom.NewBackend()
+	if err != nil {
+		t.Fatalf("Failed to create Cal.com backend: %v", err)
+	}
+	client, ok := backend.(*calcom.V2Client)
+	if !ok {
+		t.Fatalf("Expected CALCOM_API_VERSION=v2 to create a V2Client, got %T", backend)
+	}
+	ctx := context.Background()
+
+	t.Run("Bookings", func(t *testing.T) {
+		events, err := client.GetEvents(ctx, "jane@example.com")
+		if err != nil {
+			t.Fatalf("GetEvents failed: %v", err)
+		}
+		if len(events) != 1 || events[0].ID != "uid-1" || events[0].StartTime.Hour() != 9 {
+			t.Errorf("Unexpected events: %+v", events)
+		}
+
+		start := time.Date(2050, 9, 5, 10, 0, 0, 0, time.UTC)
+		event, err := client.BookEvent(ctx, models.BookingRequest{
+			EventTypeID: 7,
+			Start:       start,
+			End:         start.Add(30 * time.Minute),
+			Name:        "Jane",
+			Email:       "jane@example.com",
+			Notes:       "Agenda attached",
+			Location:    "Room 4",
+			Title:       "Meeting with Jane",
+			TimeZone:    "Europe/Berlin",
+		})
+		if err != nil {
+			t.Fatalf("BookEvent failed: %v", err)
+		}
+		if event.UID != "uid-2" || !event.StartTime.Equal(start) {
+			t.Errorf("Unexpected booked event: %+v", event)
+		}
+		body := fake.Body("POST", "/bookings")
+		attendee, _ := body["attendee"].(map[string]interface{})
+		if body["start"] != "2050-09-05T10:00:00Z" || attendee["email"] != "jane@example.com" || attendee["timeZone"] != "Europe/Berlin" {
+			t.Errorf("Unexpected booking payload: %v", body)
+		}
+		responses, _ := body["bookingFieldsResponses"].(map[string]interface{})
+		location, _ := body["location"].(map[string]interface{})
+		if responses["title"] != "Meeting with Jane" || responses["notes"] != "Agenda attached" || location["type"] != "attendeeDefined" || location["location"] != "Room 4" {
+			t.Errorf("Expected the title, notes and location to be sent, got %v", body)
+		}
+
+		rescheduled, err := client.RescheduleEvent(ctx, "uid-1", start.Add(23*time.Hour), start.Add(23*time.Hour+30*time.Minute))
+		if err != nil {
+			t.Fatalf("RescheduleEvent failed: %v", err)
+		}
+		if rescheduled.ID != "uid-3" {
+			t.Errorf("Expected the rescheduled booking uid-3, got %q", rescheduled.ID)
+		}
+
+		booking, err := client.FindBooking(ctx, "uid-1")
+		if err != nil {
+			t.Fatalf("FindBooking failed: %v", err)
+		}
+		if booking.ID != 11 || booking.UID != "uid-1" || booking.EventTypeID != 7 || len(booking.Attendees) != 1 ||
+			booking.Attendees[0].TimeZone != "Europe/Berlin" || booking.Organizer == nil || booking.Organizer.Email != "sam@example.com" {
+			t.Errorf("Unexpected booking: %+v", booking)
+		}
+	})
+
+	t.Run("Slots", func(t *testing.T) {
+		berlin, _ := time.LoadLocation("Europe/Berlin")
+		from := time.Date(2050, 9, 5, 0, 0, 0, 0, berlin)
+		slots, err := client.GetAvailableSlots(ctx, 7, from, from.AddDate(0, 0, 2))
+		if err != nil {
+			t.Fatalf("GetAvailableSlots failed: %v", err)
+		}
+		if len(slots) != 3 || slots[0].UTC().Hour() != 9 || !slots[0].Before(slots[1]) || !slots[1].Before(slots[2]) {
+			t.Errorf("Expected 3 slots in order, got %v", slots)
+		}
+
+		bookable, err := client.GetBookableSlots(ctx, from, from.AddDate(0, 0, 2))
+		if err != nil {
+			t.Fatalf("GetBookableSlots failed: %v", err)
+		}
+		if len(bookable) != 3 || bookable[0].EventTypeID != 7 || bookable[0].End.Sub(bookable[0].Start) != 30*time.Minute {
+			t.Errorf("Expected the 3 slots of event type 7, lasting 30 minutes, got %+v", bookable)
+		}
+	})
+
+	t.Run("EventTypesAndSchedules", func(t *testing.T) {
+		eventTypes, err := client.GetEventTypes(ctx)
+		if err != nil {
+			t.Fatalf("GetEventTypes failed: %v", err)
+		}
+		if len(eventTypes) != 1 || eventTypes[0].ID != 7 || eventTypes[0].Length != 30 {
+			t.Errorf("Unexpected event types: %+v", eventTypes)
+		}
+		schedules, err := client.FindAllSchedules(ctx)
+		if err != nil {
+			t.Fatalf("FindAllSchedules failed: %v", err)
+		}
+		if len(schedules) != 1 || schedules[0].Name != "Working Hours" || !schedules[0].IsDefault ||
+			len(schedules[0].Availability) != 1 || schedules[0].Availability[0].Days[1] != "Friday" ||
+			len(schedules[0].Overrides) != 1 || schedules[0].Overrides[0].Date != "2050-12-24" {
+			t.Errorf("Unexpected schedules: %+v", schedules)
+		}
+	})
+
+	t.Run("ErrorEnvelope", func(t *testing.T) {
+		err := client.CancelEvent(ctx, "missing")
+		if !calcom.IsNotFound(err) {
+			t.Errorf("Expected a not found error, got %v", err)
+		}
+		if _, err := client.EditBooking(ctx, "uid-1", models.BookingUpdate{Title: "Kickoff"}); !errors.Is(err, calcom.ErrUnsupported) {
+			t.Errorf("Expected editing a booking to be unsupported, got %v", err)
+		}
+	})
+
+	if problems := fake.Problems(); len(problems) > 0 {
+		t.Errorf("Unexpected requests: %v", problems)
+	}
+}
+
+// TestCalcomAPIVersion tests choosing the Cal.com API version
+func TestCalcomAPIVersion(t *testing.T) {
+	t.Setenv("CALCOM_API_KEY", "test-key")
+	t.Setenv("CALCOM_IDEMPOTENCY_FILE", "")
+
+	t.Setenv("CALCOM_API_VERSION", "")
+	backend, err := calcom.NewBackend()
+	if err != nil {
+		t.Fatalf("Failed to create Cal.com backend: %v", err)
+	}
+	if _, ok := backend.(*calcom.Client); !ok {
+		t.Errorf("Expected v1 by default, got %T", backend)
+	}
+
+	t.Setenv("CALCOM_API_VERSION", "v3")
+	if _, err := calcom.NewBackend(); err == nil {
+		t.Error("Expected an unknown API version to be rejected")
+	}
+}
@@ -0,0 +1,198 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/cal-chatbot/internal/chatbot"
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/test/mocks"
+)
+
+// callTool makes the model call a single tool and returns the record of the call
+func callTool(t *testing.T, bot *chatbot.Chatbot, server *mocks.MockOpenAIServer, name, arguments string) models.ExecutedFunctionCall {
+	t.Helper()
+	server.SetResponses(toolCallMessage(toolCall("call_1", name, arguments)), contentMessage("Done."))
+	_, calls, err := bot.ProcessMessage(context.Background(), []models.ChatMessage{{Role: "user", Content: "go ahead"}})
+	if err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+	if len(calls) != 1 || calls[0].Name != name {
+		t.Fatalf("Expected a single %s call, got %+v", name, calls)
+	}
+	return calls[0]
+}
+
+// newToolTestBot creates a chatbot on the mock calendar with a fake OpenAI server to script tool calls
+func newToolTestBot(t *testing.T) (*chatbot.Chatbot, *mocks.MockOpenAIServer, *mocks.MockCalcomClient) {
+	t.Helper()
+	server := mocks.NewMockOpenAIServer()
+	t.Cleanup(server.Close)
+	calendar := mocks.NewMockCalcomClient()
+	return newLoopTestBot(t, server, calendar), server, calendar
+}
+
+// TestScheduleTools tests viewing and editing working-hour schedules through tool calls
+func TestScheduleTools(t *testing.T) {
+	bot, server, calendar := newToolTestBot(t)
+
+	t.Run("List", func(t *testing.T) {
+		call := callTool(t, bot, server, "listSchedules", `{}`)
+		result, _ := call.Result.(map[string]interface{})
+		schedules, _ := result["schedules"].([]models.Schedule)
+		if len(schedules) != 1 || schedules[0].Name != "Working Hours" {
+			t.Errorf("Expected the mock schedule, got %+v (error %q)", call.Result, call.Error)
+		}
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		call := callTool(t, bot, server, "createSchedule", `{"name":"Summer hours"}`)
+		schedule, _ := call.Result.(*models.Schedule)
+		if schedule == nil || schedule.Name != "Summer hours" || schedule.TimeZone != "UTC" {
+			t.Errorf("Expected the schedule in the user's timezone, got %+v (error %q)", call.Result, call.Error)
+		}
+
+		call = callTool(t, bot, server, "createSchedule", `{"name":"Summer hours","timeZone":"Mars/Olympus"}`)
+		if !strings.Contains(call.Error, "not a valid IANA timezone") {
+			t.Errorf("Expected an invalid timezone to be rejected, got %+v", call)
+		}
+	})
+
+	t.Run("EditNeedsConfirmation", func(t *testing.T) {
+		confirm := func(args string) {
+			t.Helper()
+			calendar.ScheduleUpdate = models.ScheduleUpdate{}
+			token := pendingToken(t, callTool(t, bot, server, "editSchedule", args))
+			if update := calendar.ScheduleUpdate; update.Name != "" || update.Availability != nil {
+				t.Fatalf("Expected nothing to be edited before confirmation, got %+v", update)
+			}
+			if _, record, err := bot.ConfirmAction(context.Background(), "", token); err != nil || record.Error != "" {
+				t.Fatalf("Confirmation failed: %v %q", err, record.Error)
+			}
+		}
+
+		confirm(`{"scheduleId":1,"name":"Office hours","isDefault":false}`)
+		update := calendar.ScheduleUpdate
+		if update.Name != "Office hours" || update.IsDefault == nil || *update.IsDefault || update.TimeZone != "" || update.Availability != nil {
+			t.Errorf("Expected only the given fields to be updated, got %+v", update)
+		}
+
+		confirm(`{"scheduleId":1,"availability":[{"days":["Monday","Tuesday"],"startTime":"08:00","endTime":"12:00"}],"overrides":[{"date":"2030-12-24","startTime":"09:00","endTime":"11:00"}]}`)
+		update = calendar.ScheduleUpdate
+		if len(update.Availability) != 1 || update.Availability[0].StartTime != "08:00" || len(update.Overrides) != 1 || update.Overrides[0].Date != "2030-12-24" {
+			t.Errorf("Expected the hours and overrides to be updated, got %+v", update)
+		}
+
+		confirm(`{"scheduleId":1,"availability":[]}`)
+		if update := calendar.ScheduleUpdate; update.Availability == nil || len(update.Availability) != 0 {
+			t.Errorf("Expected the weekly hours to be cleared, got %+v", update)
+		}
+
+		for _, args := range []string{
+			`{"scheduleId":1}`,
+			`{"name":"Office hours"}`,
+			`{"scheduleId":"one"}`,
+			`{"scheduleId":1,"availability":[{"days":["Funday"],"startTime":"08:00","endTime":"12:00"}]}`,
+			`{"scheduleId":1,"availability":[{"days":["Monday"],"startTime":"12:00","endTime":"8am"}]}`,
+			`{"scheduleId":1,"overrides":[{"date":"24.12.2030","startTime":"09:00","endTime":"11:00"}]}`,
+		} {
+			if call := callTool(t, bot, server, "editSchedule", args); call.Error == "" {
+				t.Errorf("Expected %s to be rejected, got %+v", args, call.Result)
+			}
+		}
+	})
+
+	t.Run("RemoveNeedsConfirmation", func(t *testing.T) {
+		call := callTool(t, bot, server, "removeSchedule", `{"scheduleId":1}`)
+		token := pendingToken(t, call)
+		if calendar.RemovedSchedule != "" {
+			t.Fatalf("Expected nothing to be deleted before confirmation, got %q", calendar.RemovedSchedule)
+		}
+		if _, record, err := bot.ConfirmAction(context.Background(), "", token); err != nil || record.Error != "" {
+			t.Fatalf("Confirmation failed: %v %q", err, record.Error)
+		}
+		if calendar.RemovedSchedule != "1" {
+			t.Errorf("Expected schedule 1 to be deleted, got %q", calendar.RemovedSchedule)
+		}
+	})
+}
+
+// TestCancelEventByID tests that cancelling an event by ID looks the booking up before proposing it
+func TestCancelEventByID(t *testing.T) {
+	bot, server, calendar := newToolTestBot(t)
+
+	call := callTool(t, bot, server, "cancelEvent", `{"eventId":"event-2"}`)
+	pendingToken(t, call)
+	if result, _ := call.Result.(map[string]interface{}); !strings.Contains(fmt.Sprint(result["summary"]), "Test Meeting 2") {
+		t.Errorf("Expected the summary to name the booking, got %+v", call.Result)
+	}
+
+	if call := callTool(t, bot, server, "cancelEvent", `{"eventId":"event-9"}`); !strings.Contains(call.Error, "doesn't exist") {
+		t.Errorf("Expected an unknown event to be rejected, got %+v", call)
+	}
+
+	calendar.Booking.Status = "CANCELLED"
+	if call := callTool(t, bot, server, "cancelEvent", `{"eventId":"event-1"}`); !strings.Contains(call.Error, "already cancelled") {
+		t.Errorf("Expected a cancelled event to be rejected, got %+v", call)
+	}
+}
+
+// TestBookingTools tests looking up and editing bookings and finding bookable slots through tool calls
+func TestBookingTools(t *testing.T) {
+	bot, server, calendar := newToolTestBot(t)
+
+	t.Run("Get", func(t *testing.T) {
+		call := callTool(t, bot, server, "getBooking", `{"bookingId":"event-1"}`)
+		booking, _ := call.Result.(*models.Booking)
+		if booking == nil || booking.UID != "event-1" || len(booking.Attendees) != 1 {
+			t.Errorf("Expected the booking, got %+v (error %q)", call.Result, call.Error)
+		}
+		if call := callTool(t, bot, server, "getBooking", `{}`); call.Error == "" {
+			t.Errorf("Expected a missing booking ID to be rejected, got %+v", call.Result)
+		}
+	})
+
+	t.Run("EditNeedsConfirmation", func(t *testing.T) {
+		call := callTool(t, bot, server, "editBooking", `{"bookingId":"event-1","title":"Kickoff","location":"Room 4"}`)
+		token := pendingToken(t, call)
+		if calendar.EditedBookingID != "" {
+			t.Fatalf("Expected nothing to be edited before confirmation, got %q", calendar.EditedBookingID)
+		}
+		if _, record, err := bot.ConfirmAction(context.Background(), "", token); err != nil || record.Error != "" {
+			t.Fatalf("Confirmation failed: %v %q", err, record.Error)
+		}
+		want := models.BookingUpdate{Title: "Kickoff", Location: "Room 4"}
+		if calendar.EditedBookingID != "event-1" || calendar.BookingUpdate != want {
+			t.Errorf("Expected the title and location of event-1 to be edited, got %q %+v", calendar.EditedBookingID, calendar.BookingUpdate)
+		}
+
+		if call := callTool(t, bot, server, "editBooking", `{"bookingId":"event-1"}`); call.Error == "" {
+			t.Errorf("Expected an edit without changes to be rejected, got %+v", call.Result)
+		}
+	})
+
+	t.Run("FindBookableSlots", func(t *testing.T) {
+		call := callTool(t, bot, server, "findBookableSlots", `{"startDate":"2030-01-07","endDate":"2030-01-08"}`)
+		if call.Error != "" {
+			t.Fatalf("findBookableSlots failed: %s", call.Error)
+		}
+		encoded, _ := json.Marshal(call.Result)
+		var result struct {
+			EventTypes []struct {
+				EventTypeID int      `json:"eventTypeId"`
+				Title       string   `json:"title"`
+				Slots       []string `json:"slots"`
+			} `json:"eventTypes"`
+		}
+		json.Unmarshal(encoded, &result)
+		if len(result.EventTypes) != 1 || result.EventTypes[0].Title != "30 Min Meeting" || len(result.EventTypes[0].Slots) != 2 {
+			t.Errorf("Expected the slots of the mock event type, got %s", encoded)
+		}
+		if calendar.SlotsStart.Format("2006-01-02") != "2030-01-07" || calendar.SlotsEnd.Format("2006-01-02") != "2030-01-09" {
+			t.Errorf("Expected whole days from 2030-01-07 to 2030-01-08, got %v to %v", calendar.SlotsStart, calendar.SlotsEnd)
+		}
+	})
+}
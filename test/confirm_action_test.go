@@ -0,0 +1,267 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/yourusername/cal-chatbot/internal/actions"
+	"github.com/yourusername/cal-chatbot/internal/api"
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/test/mocks"
+)
+
+// pendingToken returns the confirmation token of a proposed action
+func pendingToken(t *testing.T, call models.ExecutedFunctionCall) string {
+	t.Helper()
+	result, ok := call.Result.(map[string]interface{})
+	if !ok || result["status"] != "confirmation_required" {
+		t.Fatalf("Expected %s to await confirmation, got %+v (error %q)", call.Name, call.Result, call.Error)
+	}
+	token, _ := result["token"].(string)
+	if token == "" {
+		t.Fatalf("Expected a confirmation token, got %+v", result)
+	}
+	return token
+}
+
+// pendingTokenPattern finds the tokens of pending actions listed in the system prompt
+var pendingTokenPattern = regexp.MustCompile(`\(token ([0-9a-f-]+)\)`)
+
+// confirmFromPrompt scripts a model that confirms the first pending action listed in the
+// system prompt when the user says yes, as the only source of the token
+func confirmFromPrompt() func(goopenai.ChatCompletionRequest) goopenai.ChatCompletionMessage {
+	return func(req goopenai.ChatCompletionRequest) goopenai.ChatCompletionMessage {
+		last := req.Messages[len(req.Messages)-1]
+		match := pendingTokenPattern.FindStringSubmatch(req.Messages[0].Content)
+		if last.Role != goopenai.ChatMessageRoleUser || last.Content != "yes" || match == nil {
+			return contentMessage("Done.")
+		}
+		return toolCallMessage(toolCall("call_2", "confirmAction", `{"token":"`+match[1]+`"}`))
+	}
+}
+
+// TestConfirmAction tests that calendar changes are only made once the user confirms them
+func TestConfirmAction(t *testing.T) {
+	cancelMessage := []models.ChatMessage{{Role: "user", Content: "cancel event-1"}}
+	yesMessage := []models.ChatMessage{{Role: "user", Content: "yes"}}
+	cancel := toolCallMessage(toolCall("call_1", "cancelEvent", `{"eventId":"event-1"}`))
+	confirm := func(token string) goopenai.ChatCompletionMessage {
+		return toolCallMessage(toolCall("call_2", "confirmAction", `{"token":"`+token+`"}`))
+	}
+
+	t.Run("InALaterTurn", func(t *testing.T) {
+		server := mocks.NewMockOpenAIServer(cancel, contentMessage("Shall I cancel it?"))
+		defer server.Close()
+		calendar := mocks.NewMockCalcomClient()
+		bot := newLoopTestBot(t, server, calendar)
+		ctx := models.WithConversationID(context.Background(), "conv-1")
+
+		_, calls, err := bot.ProcessMessage(ctx, cancelMessage)
+		if err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		token := pendingToken(t, calls[0])
+		if calendar.CanceledEventID != "" {
+			t.Fatalf("Expected nothing to be cancelled before confirmation, got %q", calendar.CanceledEventID)
+		}
+
+		// The next turn only has the user's "yes"; the token comes from the system prompt
+		server.Respond = confirmFromPrompt()
+		_, calls, err = bot.ProcessMessage(ctx, yesMessage)
+		if err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		if len(calls) != 1 || calls[0].Name != "confirmAction" || calls[0].Error != "" {
+			t.Fatalf("Expected the confirmation to succeed, got %+v", calls)
+		}
+		if calendar.CanceledEventID != "event-1" {
+			t.Errorf("Expected event-1 to be cancelled, got %q", calendar.CanceledEventID)
+		}
+
+		// A token can only be used once, and is no longer offered to the model
+		_, calls, _ = bot.ProcessMessage(ctx, yesMessage)
+		if len(calls) != 0 {
+			t.Errorf("Expected no confirmation once the action is done, got %+v", calls)
+		}
+		if _, _, err := bot.ConfirmAction(ctx, "conv-1", token); err != actions.ErrNotFound {
+			t.Errorf("Expected a used token to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("NotInTheSameTurn", func(t *testing.T) {
+		server := mocks.NewMockOpenAIServer()
+		// Propose, then confirm with the token from the tool result without asking the user
+		server.Respond = func(req goopenai.ChatCompletionRequest) goopenai.ChatCompletionMessage {
+			last := req.Messages[len(req.Messages)-1]
+			if last.Role != goopenai.ChatMessageRoleTool {
+				return cancel
+			}
+			var result map[string]interface{}
+			if json.Unmarshal([]byte(last.Content), &result) == nil && result["token"] != nil {
+				return confirm(result["token"].(string))
+			}
+			return contentMessage("Shall I cancel it?")
+		}
+		defer server.Close()
+		calendar := mocks.NewMockCalcomClient()
+		bot := newLoopTestBot(t, server, calendar)
+
+		_, calls, err := bot.ProcessMessage(context.Background(), cancelMessage)
+		if err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		if len(calls) != 2 || !strings.Contains(calls[1].Error, "has not confirmed") {
+			t.Fatalf("Expected the confirmation to be refused, got %+v", calls)
+		}
+		if calendar.CanceledEventID != "" {
+			t.Errorf("Expected nothing to be cancelled, got %q", calendar.CanceledEventID)
+		}
+
+		// The action stays pending for the user to confirm
+		if _, record, err := bot.ConfirmAction(context.Background(), "", pendingToken(t, calls[0])); err != nil || record.Error != "" {
+			t.Fatalf("Expected the action to still be pending, got %v %q", err, record.Error)
+		}
+		if calendar.CanceledEventID != "event-1" {
+			t.Errorf("Expected event-1 to be cancelled, got %q", calendar.CanceledEventID)
+		}
+	})
+
+	t.Run("ScopedToConversation", func(t *testing.T) {
+		server := mocks.NewMockOpenAIServer(cancel, contentMessage("Shall I cancel it?"))
+		defer server.Close()
+		calendar := mocks.NewMockCalcomClient()
+		bot := newLoopTestBot(t, server, calendar)
+
+		_, calls, err := bot.ProcessMessage(models.WithConversationID(context.Background(), "conv-1"), cancelMessage)
+		if err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		if _, _, err := bot.ConfirmAction(context.Background(), "conv-2", pendingToken(t, calls[0])); err != actions.ErrNotFound {
+			t.Errorf("Expected ErrNotFound from another conversation, got %v", err)
+		}
+		if calendar.CanceledEventID != "" {
+			t.Errorf("Expected nothing to be cancelled, got %q", calendar.CanceledEventID)
+		}
+	})
+
+	t.Run("Expires", func(t *testing.T) {
+		t.Setenv("PENDING_ACTION_TTL", "10ms")
+		server := mocks.NewMockOpenAIServer(cancel, contentMessage("Shall I cancel it?"))
+		defer server.Close()
+		calendar := mocks.NewMockCalcomClient()
+		bot := newLoopTestBot(t, server, calendar)
+
+		_, calls, err := bot.ProcessMessage(context.Background(), cancelMessage)
+		if err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		token := pendingToken(t, calls[0])
+		time.Sleep(20 * time.Millisecond)
+		if _, _, err := bot.ConfirmAction(context.Background(), "", token); err != actions.ErrExpired {
+			t.Errorf("Expected ErrExpired, got %v", err)
+		}
+		if calendar.CanceledEventID != "" {
+			t.Errorf("Expected nothing to be cancelled, got %q", calendar.CanceledEventID)
+		}
+	})
+}
+
+// TestConfirmActionEndpoint tests confirming a proposed action through the API
+func TestConfirmActionEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := mocks.NewMockOpenAIServer(
+		toolCallMessage(toolCall("call_1", "cancelEvent", `{"eventId":"event-2"}`)),
+		contentMessage("Shall I cancel Test Meeting 2?"),
+	)
+	defer server.Close()
+	calendar := mocks.NewMockCalcomClient()
+	bot := newLoopTestBot(t, server, calendar)
+	store := newTestHistoryStore(t)
+	router := gin.New()
+	api.NewHandler(bot, store).SetupRoutes(router)
+
+	send := func(path, conversationID string, body interface{}) *httptest.ResponseRecorder {
+		requestBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(requestBody))
+		req.Header.Set("Content-Type", "application/json")
+		if conversationID != "" {
+			req.Header.Set("X-Conversation-Id", conversationID)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := send("/api/chat", "conv-1", models.ChatRequest{Messages: []models.ChatMessage{{Role: "user", Content: "cancel my meeting"}}})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+	var response models.ChatResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.FunctionCalls) != 1 {
+		t.Fatalf("Expected one function call, got %+v", response.FunctionCalls)
+	}
+	token := pendingToken(t, response.FunctionCalls[0])
+
+	if resp := send("/api/actions/"+token+"/confirm", "", nil); resp.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d without a conversation, got %d", http.StatusBadRequest, resp.Code)
+	}
+	if resp := send("/api/actions/"+token+"/confirm", "conv-2", nil); resp.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for another conversation, got %d", http.StatusNotFound, resp.Code)
+	}
+
+	resp = send("/api/actions/"+token+"/confirm", "conv-1", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+	if calendar.CanceledEventID != "event-2" {
+		t.Errorf("Expected event-2 to be cancelled, got %q", calendar.CanceledEventID)
+	}
+	entries, err := store.Load("conv-1")
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	if last := entries[len(entries)-1]; last.Role != "assistant" || !strings.HasPrefix(last.Content, "Confirmed: ") {
+		t.Errorf("Expected the confirmation in the history, got %+v", last)
+	}
+
+	if resp := send("/api/actions/"+token+"/confirm", "conv-1", nil); resp.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a used token, got %d", http.StatusNotFound, resp.Code)
+	}
+}
+
+// TestConfirmActionByReply tests confirming a proposed action by answering "yes" in the next chat
+// turn, whose stored history doesn't carry the tool result with the token
+func TestConfirmActionByReply(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := mocks.NewMockOpenAIServer(
+		toolCallMessage(toolCall("call_1", "cancelEvent", `{"eventId":"event-2"}`)),
+		contentMessage("Shall I cancel Test Meeting 2?"),
+	)
+	defer server.Close()
+	calendar := mocks.NewMockCalcomClient()
+	router := gin.New()
+	api.NewHandler(newLoopTestBot(t, server, calendar), newTestHistoryStore(t)).SetupRoutes(router)
+
+	chatTurn(t, router, "conv-yes", "cancel my meeting")
+	if calendar.CanceledEventID != "" {
+		t.Fatalf("Expected nothing to be cancelled before confirmation, got %q", calendar.CanceledEventID)
+	}
+
+	server.Respond = confirmFromPrompt()
+	chatTurn(t, router, "conv-yes", "yes")
+	if calendar.CanceledEventID != "event-2" {
+		t.Errorf("Expected event-2 to be cancelled after the user agreed, got %q", calendar.CanceledEventID)
+	}
+}
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/chatbot"
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/test/mocks"
+)
+
+// hangingHandler only answers once the request is canceled. requests counts the requests received.
+func hangingHandler(requests *int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+}
+
+// TestCalcomContext tests that canceling the context stops in-flight Cal.com requests
+func TestCalcomContext(t *testing.T) {
+	t.Run("CancelStopsRequest", func(t *testing.T) {
+		var requests int32
+		client := newTestCalcomClient(t, hangingHandler(&requests), calcomTestOptions{})
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		start := time.Now()
+		_, err := client.GetEventTypes(ctx)
+		if err == nil || !strings.Contains(err.Error(), "context canceled") {
+			t.Fatalf("Expected the request to be canceled, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected the request to stop right away, took %v", elapsed)
+		}
+		if n := atomic.LoadInt32(&requests); n != 1 {
+			t.Errorf("Expected a canceled request not to be retried, got %d requests", n)
+		}
+	})
+
+	t.Run("ToolCallTimeout", func(t *testing.T) {
+		var requests int32
+		calendar := newTestCalcomClient(t, hangingHandler(&requests), calcomTestOptions{})
+		server := mocks.NewMockOpenAIServer(
+			toolCallMessage(toolCall("call_1", "listEventTypes", `{}`)),
+			contentMessage("The calendar didn't answer."),
+		)
+		defer server.Close()
+		t.Setenv("OPENAI_BASE_URL", server.URL())
+		t.Setenv("TOOL_CALL_TIMEOUT", "100ms")
+		bot, err := chatbot.NewChatbot(calendar)
+		if err != nil {
+			t.Fatalf("Failed to create chatbot: %v", err)
+		}
+
+		start := time.Now()
+		_, calls, err := bot.ProcessMessage(context.Background(), []models.ChatMessage{{Role: "user", Content: "what can I book?"}})
+		if err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		if len(calls) != 1 || !strings.Contains(calls[0].Error, "deadline exceeded") {
+			t.Fatalf("Expected the tool call to time out, got %+v", calls)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("Expected the tool call deadline to cut the request short, took %v", elapsed)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/cal-chatbot/internal/api"
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// RecordingChatbot is a mock chatbot that records the conversation it is asked to answer
+type RecordingChatbot struct {
+	Received [][]models.ChatMessage
+	Profiles []models.UserProfile
+}
+
+// ProcessMessage records the messages and returns a fixed reply
+func (r *RecordingChatbot) ProcessMessage(ctx context.Context, messages []models.ChatMessage) (string, []models.ExecutedFunctionCall, error) {
+	r.Received = append(r.Received, messages)
+	r.Profiles = append(r.Profiles, models.UserProfileFromContext(ctx))
+	return "Noted.", nil, nil
+}
+
+// TestConversationMemory tests that the server keeps conversation context between requests
+func TestConversationMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bot := &RecordingChatbot{}
+	store := newTestHistoryStore(t)
+	router := gin.New()
+	router.POST("/api/chat", api.NewHandler(bot, store).HandleChat)
+
+	send := func(conversationID string, messages ...models.ChatMessage) *httptest.ResponseRecorder {
+		requestBody, _ := json.Marshal(models.ChatRequest{Messages: messages})
+		req, _ := http.NewRequest("POST", "/api/chat", bytes.NewBuffer(requestBody))
+		req.Header.Set("Content-Type", "application/json")
+		if conversationID != "" {
+			req.Header.Set("X-Conversation-Id", conversationID)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	hi := models.ChatMessage{Role: "user", Content: "Hi"}
+	whatCanYouDo := models.ChatMessage{Role: "user", Content: "What can you do?"}
+	noted := models.ChatMessage{Role: "assistant", Content: "Noted."}
+	book := models.ChatMessage{Role: "user", Content: "Book a meeting tomorrow"}
+
+	// A new conversation stores every message in the request
+	resp := send("", hi, whatCanYouDo)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, resp.Code)
+	}
+	var response models.ChatResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.ConversationID == "" || response.ConversationID != resp.Header().Get("X-Conversation-Id") {
+		t.Fatalf("Expected the conversation ID in the body and header, got %q and %q", response.ConversationID, resp.Header().Get("X-Conversation-Id"))
+	}
+	conversationID := response.ConversationID
+
+	// A follow-up only adds the latest message, even if the client resends everything
+	resp = send(conversationID, hi, whatCanYouDo, noted, book)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, resp.Code)
+	}
+	received := bot.Received[len(bot.Received)-1]
+	if len(received) != 4 || received[2].Role != "assistant" || received[3].Content != book.Content {
+		t.Fatalf("Expected the stored turns plus the new message, got %+v", received)
+	}
+
+	entries, err := store.Load(conversationID)
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	var contents []string
+	for _, entry := range entries {
+		contents = append(contents, entry.Role+": "+entry.Content)
+	}
+	expected := []string{"user: Hi", "user: What can you do?", "assistant: Noted.", "user: Book a meeting tomorrow", "assistant: Noted."}
+	if len(contents) != len(expected) {
+		t.Fatalf("Expected history %v, got %v", expected, contents)
+	}
+	for i := range expected {
+		if contents[i] != expected[i] {
+			t.Errorf("Expected history entry %d to be %q, got %q", i, expected[i], contents[i])
+		}
+	}
+
+	// The latest message of a stored conversation must come from the user
+	resp = send(conversationID, noted)
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, resp.Code)
+	}
+}
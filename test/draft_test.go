@@ -0,0 +1,122 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/cal-chatbot/internal/api"
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/test/mocks"
+)
+
+// draftTurn sends a message in a conversation and returns the chatbot's response
+func draftTurn(t *testing.T, router *gin.Engine, conversationID string, message models.ChatMessage) models.ChatResponse {
+	t.Helper()
+	body, _ := json.Marshal(models.ChatRequest{Messages: []models.ChatMessage{message}})
+	req := httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Conversation-Id", conversationID)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var response models.ChatResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	return response
+}
+
+// TestBookingDraft tests keeping track of a booking put together over several turns
+func TestBookingDraft(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := mocks.NewMockOpenAIServer()
+	defer server.Close()
+	calendar := mocks.NewMockCalcomClient()
+	router := gin.New()
+	api.NewHandler(newLoopTestBot(t, server, calendar), newTestHistoryStore(t)).SetupRoutes(router)
+	start := time.Date(2030, 1, 7, 10, 0, 0, 0, time.UTC)
+	calendar.AvailableSlots = []time.Time{start, time.Date(2030, 1, 8, 9, 0, 0, 0, time.UTC)}
+
+	t.Run("AcrossTurns", func(t *testing.T) {
+		server.SetResponses(
+			toolCallMessage(toolCall("call_1", "bookMeeting", `{"eventType":"30 min","startTime":"2030-01-07T10:00:00Z","endTime":"2030-01-07T10:30:00Z"}`)),
+			contentMessage("Who is the meeting with?"),
+		)
+		response := draftTurn(t, router, "draft-1", models.ChatMessage{Role: "user", Content: "book 30 min on Jan 7 at 10"})
+		draft := response.Draft
+		if draft == nil || draft.Status != models.DraftCollecting || draft.EventTypeID != 1 || draft.EventType != "30 Min Meeting" ||
+			draft.Start == nil || !draft.Start.Equal(start) || draft.Duration != 30 {
+			t.Fatalf("Expected the event type and time in the draft, got %+v", draft)
+		}
+		if strings.Join(draft.Missing, ",") != "attendeeName,attendeeEmail" {
+			t.Errorf("Expected the attendee to be missing, got %v", draft.Missing)
+		}
+
+		// The attendee comes in a later turn; the rest is taken from the draft
+		server.SetResponses(
+			toolCallMessage(toolCall("call_2", "bookMeeting", `{"name":"Jane","email":"jane@example.com"}`)),
+			contentMessage("Shall I book it?"),
+		)
+		response = draftTurn(t, router, "draft-1", models.ChatMessage{Role: "user", Content: "with Jane, jane@example.com"})
+		if system := server.Requests[0].Messages[0].Content; !strings.Contains(system, "duration: 30 minutes") || !strings.Contains(system, "attendeeName") {
+			t.Errorf("Expected the draft in the system prompt, got %q", system)
+		}
+		draft = response.Draft
+		if draft == nil || draft.Status != models.DraftAwaitingConfirmation || len(draft.Missing) != 0 || draft.AttendeeEmail != "jane@example.com" {
+			t.Fatalf("Expected a complete draft awaiting confirmation, got %+v", draft)
+		}
+		token := pendingToken(t, response.FunctionCalls[0])
+
+		req := httptest.NewRequest(http.MethodPost, "/api/actions/"+token+"/confirm", nil)
+		req.Header.Set("X-Conversation-Id", "draft-1")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		var confirmed models.ChatResponse
+		if err := json.Unmarshal(resp.Body.Bytes(), &confirmed); err != nil || resp.Code != http.StatusOK {
+			t.Fatalf("Expected the booking to be confirmed, got %d: %s", resp.Code, resp.Body.String())
+		}
+		if confirmed.Draft == nil || confirmed.Draft.Status != models.DraftBooked || confirmed.Draft.BookingID == "" {
+			t.Errorf("Expected the draft to be booked, got %+v", confirmed.Draft)
+		}
+		if booking := calendar.BookingRequest; booking == nil || booking.EventTypeID != 1 || !booking.Start.Equal(start) || !booking.End.Equal(start.Add(30*time.Minute)) {
+			t.Errorf("Expected the drafted booking to be made, got %+v", booking)
+		}
+
+		// The draft is kept with the conversation, but a booked draft isn't shown to the model
+		server.SetResponses(contentMessage("You're welcome!"))
+		response = draftTurn(t, router, "draft-1", models.ChatMessage{Role: "user", Content: "thanks"})
+		if response.Draft == nil || response.Draft.Status != models.DraftBooked {
+			t.Errorf("Expected the booked draft, got %+v", response.Draft)
+		}
+		if system := server.LastRequest().Messages[0].Content; strings.Contains(system, "being put together") {
+			t.Errorf("Expected no draft in the system prompt, got %q", system)
+		}
+	})
+
+	t.Run("DirectBooking", func(t *testing.T) {
+		response := draftTurn(t, router, "draft-2", models.ChatMessage{Role: "user", Booking: map[string]interface{}{
+			"eventTypeId": 1,
+			"startTime":   "2030-01-08T09:00:00Z",
+			"endTime":     "2030-01-08T09:30:00Z",
+			"name":        "Sam",
+			"email":       "sam@example.com",
+		}})
+		if response.Draft == nil || response.Draft.Status != models.DraftBooked || response.Draft.AttendeeName != "Sam" || response.Draft.Duration != 30 {
+			t.Errorf("Expected the booked draft of the booking form, got %+v", response.Draft)
+		}
+	})
+
+	t.Run("NoDraft", func(t *testing.T) {
+		server.SetResponses(contentMessage("Hello!"))
+		if response := draftTurn(t, router, "draft-3", models.ChatMessage{Role: "user", Content: "hi"}); response.Draft != nil {
+			t.Errorf("Expected no draft, got %+v", response.Draft)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/eventtypes"
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// testEventTypes are event types with overlapping names and lengths
+var testEventTypes = []models.EventType{
+	{ID: 1, Title: "30 Min Meeting", Slug: "30min", Length: 30},
+	{ID: 2, Title: "Intro Call", Slug: "intro", Length: 15},
+	{ID: 3, Title: "Intro Deep Dive", Slug: "intro-deep-dive", Length: 60},
+	{ID: 4, Title: "Consultation", Slug: "consultation", Length: 60},
+}
+
+// TestResolveEventType tests finding the event types the user's words refer to
+func TestResolveEventType(t *testing.T) {
+	testCases := []struct {
+		name  string
+		query string
+		want  []int
+	}{
+		{"Slug", "intro-deep-dive", []int{3}},
+		{"Title", "intro call", []int{2}},
+		{"WordsAndDuration", "30 min intro", []int{2, 3}},
+		{"WordsNarrowedByDuration", "1 hour intro", []int{3}},
+		{"DurationOnly", "half an hour", []int{1}},
+		{"DurationInHours", "an hour", []int{3, 4}},
+		{"Prefix", "consult", []int{4}},
+		{"Typo", "consultaton", []int{4}},
+		{"Ambiguous", "intro session", []int{2, 3}},
+		{"StopWordsOnly", "a meeting", nil},
+		{"NoMatch", "board review", nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matches := eventtypes.Resolve(tc.query, testEventTypes)
+			var got []int
+			for _, eventType := range matches {
+				got = append(got, eventType.ID)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("Expected event types %v for %q, got %v", tc.want, tc.query, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("Expected event types %v for %q, got %v", tc.want, tc.query, got)
+				}
+			}
+		})
+	}
+}
+
+// TestBookMeetingInput tests that bookMeeting asks for details it can't work out instead of making them up
+func TestBookMeetingInput(t *testing.T) {
+	bot, server, calendar := newToolTestBot(t)
+	calendar.EventTypes = testEventTypes
+	calendar.AvailableSlots = []time.Time{time.Date(2030, 1, 7, 10, 0, 0, 0, time.UTC)}
+	times := `"startTime":"2030-01-07T10:00:00Z","endTime":"2030-01-07T11:00:00Z"`
+
+	needsInput := func(t *testing.T, call models.ExecutedFunctionCall) map[string]interface{} {
+		t.Helper()
+		result, ok := call.Result.(map[string]interface{})
+		if !ok || result["status"] != "needs_input" {
+			t.Fatalf("Expected bookMeeting to ask for input, got %+v (error %q)", call.Result, call.Error)
+		}
+		return result
+	}
+
+	t.Run("MissingAttendee", func(t *testing.T) {
+		result := needsInput(t, callTool(t, bot, server, "bookMeeting", `{"eventTypeId":3,`+times+`}`))
+		missing, _ := result["missing"].([]string)
+		if len(missing) != 2 || missing[0] != "name" || missing[1] != "email" {
+			t.Errorf("Expected the name and email to be asked for, got %+v", result)
+		}
+	})
+
+	t.Run("InvalidEmail", func(t *testing.T) {
+		result := needsInput(t, callTool(t, bot, server, "bookMeeting", `{"eventTypeId":3,"name":"Jane","email":"jane at example.com",`+times+`}`))
+		if missing, _ := result["missing"].([]string); len(missing) != 1 || missing[0] != "email" {
+			t.Errorf("Expected the email to be asked for, got %+v", result)
+		}
+	})
+
+	t.Run("DirectBookingMissingAttendee", func(t *testing.T) {
+		response, calls, err := bot.ProcessMessage(context.Background(), []models.ChatMessage{{Role: "user", Booking: map[string]interface{}{
+			"eventTypeId": 3,
+			"startTime":   "2030-01-07T10:00:00Z",
+			"email":       "jane@example.com",
+		}}})
+		if err != nil {
+			t.Fatalf("Expected the missing name to be asked for, got %v", err)
+		}
+		if response != "Please provide their name." || len(calls) != 1 {
+			t.Errorf("Expected the name to be asked for, got %q", response)
+		}
+		if result, _ := calls[0].Result.(map[string]interface{}); result["status"] != "needs_input" {
+			t.Errorf("Expected the needs_input result to be recorded, got %+v", calls[0])
+		}
+	})
+
+	t.Run("AmbiguousEventType", func(t *testing.T) {
+		result := needsInput(t, callTool(t, bot, server, "bookMeeting", `{"eventType":"intro session","name":"Jane","email":"jane@example.com",`+times+`}`))
+		options, _ := result["eventTypeOptions"].([]models.EventType)
+		if len(options) != 2 || options[0].ID != 2 || options[1].ID != 3 {
+			t.Errorf("Expected both intro event types as options, got %+v", result)
+		}
+	})
+
+	t.Run("NoEventType", func(t *testing.T) {
+		result := needsInput(t, callTool(t, bot, server, "bookMeeting", `{"name":"Jane","email":"jane@example.com",`+times+`}`))
+		if options, _ := result["eventTypeOptions"].([]models.EventType); len(options) != len(testEventTypes) {
+			t.Errorf("Expected every event type as an option, got %+v", result)
+		}
+	})
+
+	t.Run("ResolvedByName", func(t *testing.T) {
+		call := callTool(t, bot, server, "bookMeeting", `{"eventType":"intro deep dive","name":"Jane","email":"jane@example.com",`+times+`}`)
+		if _, _, err := bot.ConfirmAction(context.Background(), "", pendingToken(t, call)); err != nil {
+			t.Fatalf("ConfirmAction failed: %v", err)
+		}
+		if calendar.BookingRequest == nil || calendar.BookingRequest.EventTypeID != 3 || calendar.BookingRequest.Email != "jane@example.com" {
+			t.Errorf("Expected the intro deep dive to be booked for Jane, got %+v", calendar.BookingRequest)
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/yourusername/cal-chatbot/internal/chatbot"
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/test/mocks"
+)
+
+// toolCall builds a single function tool call
+func toolCall(id, name, arguments string) goopenai.ToolCall {
+	return goopenai.ToolCall{
+		ID:       id,
+		Type:     goopenai.ToolTypeFunction,
+		Function: goopenai.FunctionCall{Name: name, Arguments: arguments},
+	}
+}
+
+// toolCallMessage builds an assistant message that makes the given tool calls
+func toolCallMessage(calls ...goopenai.ToolCall) goopenai.ChatCompletionMessage {
+	return goopenai.ChatCompletionMessage{
+		Role:      goopenai.ChatMessageRoleAssistant,
+		ToolCalls: calls,
+	}
+}
+
+// contentMessage builds a plain assistant reply
+func contentMessage(content string) goopenai.ChatCompletionMessage {
+	return goopenai.ChatCompletionMessage{
+		Role:    goopenai.ChatMessageRoleAssistant,
+		Content: content,
+	}
+}
+
+// newLoopTestBot creates a chatbot wired to a fake OpenAI server and the given calendar mock
+func newLoopTestBot(t *testing.T, server *mocks.MockOpenAIServer, calendar *mocks.MockCalcomClient) *chatbot.Chatbot {
+	t.Helper()
+	t.Setenv("OPENAI_BASE_URL", server.URL())
+	bot, err := chatbot.NewChatbot(calendar)
+	if err != nil {
+		t.Fatalf("Failed to create chatbot: %v", err)
+	}
+	return bot
+}
+
+// TestFunctionLoop tests chaining of function calls within a single message
+func TestFunctionLoop(t *testing.T) {
+	userMessage := []models.ChatMessage{{Role: "user", Content: "find a free slot Tuesday and book it"}}
+
+	t.Run("ChainsCallsUntilContent", func(t *testing.T) {
+		server := mocks.NewMockOpenAIServer(
+			toolCallMessage(toolCall("call_1", "listEventTypes", `{}`)),
+			toolCallMessage(toolCall("call_2", "checkAvailability", `{"eventTypeId":1,"startDate":"2030-01-01","endDate":"2030-01-01"}`)),
+			contentMessage("You're booked."),
+		)
+		defer server.Close()
+		bot := newLoopTestBot(t, server, mocks.NewMockCalcomClient())
+
+		response, calls, err := bot.ProcessMessage(context.Background(), userMessage)
+		if err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		if response != "You're booked." {
+			t.Errorf("Unexpected response: %q", response)
+		}
+		if len(calls) != 2 || calls[0].Name != "listEventTypes" || calls[1].Name != "checkAvailability" {
+			t.Fatalf("Unexpected executed calls: %+v", calls)
+		}
+		if server.RequestCount() != 3 {
+			t.Errorf("Expected 3 completion requests, got %d", server.RequestCount())
+		}
+		if len(server.LastRequest().Tools) == 0 {
+			t.Error("Expected tools to stay enabled after a tool result")
+		}
+	})
+
+	t.Run("StopsOnRepeatedCall", func(t *testing.T) {
+		server := mocks.NewMockOpenAIServer(
+			toolCallMessage(toolCall("call_3", "listEventTypes", `{}`)),
+			toolCallMessage(toolCall("call_4", "listEventTypes", `{}`)),
+			contentMessage("Here are your event types."),
+		)
+		defer server.Close()
+		bot := newLoopTestBot(t, server, mocks.NewMockCalcomClient())
+
+		response, calls, err := bot.ProcessMessage(context.Background(), userMessage)
+		if err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		if response != "Here are your event types." {
+			t.Errorf("Unexpected response: %q", response)
+		}
+		if len(calls) != 1 {
+			t.Errorf("Expected the repeated call to be skipped, got %d calls", len(calls))
+		}
+		if len(server.LastRequest().Tools) != 0 {
+			t.Error("Expected the final request to be sent without tools")
+		}
+	})
+
+	t.Run("StopsAtMaxIterations", func(t *testing.T) {
+		t.Setenv("OPENAI_MAX_ITERATIONS", "2")
+		server := mocks.NewMockOpenAIServer(
+			toolCallMessage(toolCall("call_5", "listEvents", `{"email":"a@example.com"}`)),
+			toolCallMessage(toolCall("call_6", "listEvents", `{"email":"b@example.com"}`)),
+			contentMessage("Done."),
+		)
+		defer server.Close()
+		bot := newLoopTestBot(t, server, mocks.NewMockCalcomClient())
+
+		_, calls, err := bot.ProcessMessage(context.Background(), userMessage)
+		if err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		if len(calls) != 2 {
+			t.Errorf("Expected 2 executed calls, got %d", len(calls))
+		}
+		if server.RequestCount() != 3 {
+			t.Errorf("Expected 3 completion requests, got %d", server.RequestCount())
+		}
+		if len(server.LastRequest().Tools) != 0 {
+			t.Error("Expected the final request to be sent without tools")
+		}
+	})
+
+	t.Run("FeedsErrorsBackToModel", func(t *testing.T) {
+		server := mocks.NewMockOpenAIServer(
+			toolCallMessage(toolCall("call_7", "listEventTypes", `{}`)),
+			contentMessage("Sorry, I couldn't load your event types."),
+		)
+		defer server.Close()
+		calendar := mocks.NewMockCalcomClient()
+		calendar.Err = errors.New("calendar unavailable")
+		bot := newLoopTestBot(t, server, calendar)
+
+		_, calls, err := bot.ProcessMessage(context.Background(), userMessage)
+		if err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		if len(calls) != 1 || calls[0].Error == "" {
+			t.Fatalf("Expected one failed call, got %+v", calls)
+		}
+		messages := server.LastRequest().Messages
+		last := messages[len(messages)-1]
+		if last.Role != goopenai.ChatMessageRoleTool || !strings.Contains(last.Content, "calendar unavailable") {
+			t.Errorf("Expected the error to be sent back as a tool message, got %+v", last)
+		}
+	})
+
+	t.Run("RunsParallelToolCalls", func(t *testing.T) {
+		server := mocks.NewMockOpenAIServer(
+			toolCallMessage(
+				toolCall("call_a", "listEvents", `{"email":"a@example.com"}`),
+				toolCall("call_b", "listEvents", `{"email":"b@example.com"}`),
+				toolCall("call_c", "listEventTypes", `{}`),
+			),
+			contentMessage("Both attendees are free on Tuesday."),
+		)
+		defer server.Close()
+		bot := newLoopTestBot(t, server, mocks.NewMockCalcomClient())
+
+		_, calls, err := bot.ProcessMessage(context.Background(), userMessage)
+		if err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		if len(calls) != 3 {
+			t.Fatalf("Expected 3 executed calls, got %d", len(calls))
+		}
+
+		// The assistant turn is followed by one tool message per call, in order
+		messages := server.LastRequest().Messages
+		toolMessages := messages[len(messages)-3:]
+		for i, id := range []string{"call_a", "call_b", "call_c"} {
+			if calls[i].ID != id {
+				t.Errorf("Expected executed call %d to be %s, got %s", i, id, calls[i].ID)
+			}
+			if toolMessages[i].Role != goopenai.ChatMessageRoleTool || toolMessages[i].ToolCallID != id {
+				t.Errorf("Expected tool message for %s, got %+v", id, toolMessages[i])
+			}
+		}
+		if assistant := messages[len(messages)-4]; len(assistant.ToolCalls) != 3 {
+			t.Errorf("Expected the assistant tool call turn before the results, got %+v", assistant)
+		}
+	})
+}
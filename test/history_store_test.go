@@ -0,0 +1,290 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/cal-chatbot/internal/api"
+	"github.com/yourusername/cal-chatbot/internal/history"
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// TestConversationStores runs the same checks against every conversation store
+func TestConversationStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) history.ConversationStore{
+		"JSONL": func(t *testing.T) history.ConversationStore {
+			return newTestHistoryStore(t)
+		},
+		"SQLite": func(t *testing.T) history.ConversationStore {
+			store, err := history.NewSQLiteStore(filepath.Join(t.TempDir(), "history.db"))
+			if err != nil {
+				t.Fatalf("Failed to create SQLite store: %v", err)
+			}
+			t.Cleanup(func() { store.Close() })
+			return store
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			testConversationStore(t, newStore(t))
+		})
+	}
+}
+
+// TestOpenCreatesDirectory tests that every store kind creates its directory
+func TestOpenCreatesDirectory(t *testing.T) {
+	for _, kind := range []string{"jsonl", "sqlite"} {
+		t.Run(kind, func(t *testing.T) {
+			store, err := history.Open(kind, filepath.Join(t.TempDir(), "new", "dir"))
+			if err != nil {
+				t.Fatalf("Failed to open the %s store in a new directory: %v", kind, err)
+			}
+			if closer, ok := store.(interface{ Close() error }); ok {
+				t.Cleanup(func() { closer.Close() })
+			}
+			if err := store.Append("conv-1", models.HistoryEntry{ChatMessage: models.ChatMessage{Role: "user", Content: "hi"}}); err != nil {
+				t.Errorf("Append failed: %v", err)
+			}
+		})
+	}
+}
+
+// TestSearchSnippetCaseFolding tests highlighting matches in text whose length changes when lowercased
+func TestSearchSnippetCaseFolding(t *testing.T) {
+	store := newTestHistoryStore(t)
+	err := store.Append("conv-1", models.HistoryEntry{
+		ChatMessage: models.ChatMessage{Role: "user", Content: "İİİ meet me in the GROẞE Straße tomorrow"},
+		Timestamp:   time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	for term, expected := range map[string]string{"tomorrow": "[tomorrow]", "große": "[GROẞE]"} {
+		results, err := store.Search(history.SearchQuery{Term: term})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 1 || !strings.Contains(results[0].Snippet, expected) {
+			t.Errorf("Expected %q in the snippet for %q, got %+v", expected, term, results)
+		}
+	}
+}
+
+// testConversationStore checks appending, loading and searching conversations
+func testConversationStore(t *testing.T, store history.ConversationStore) {
+	now := time.Now().UTC().Truncate(time.Second)
+	lastWeek := now.Add(-7 * 24 * time.Hour)
+
+	t.Run("AppendAndLoad", func(t *testing.T) {
+		err := store.Append("conv-1",
+			models.HistoryEntry{
+				ChatMessage: models.ChatMessage{Role: "user", Content: "Book a meeting\nfor tomorrow with alice@example.com"},
+				Timestamp:   now,
+				Metadata:    map[string]string{"userId": "u-1"},
+			},
+			models.HistoryEntry{
+				ChatMessage: models.ChatMessage{Role: "assistant", Content: "Done, your meeting is booked for tomorrow."},
+				ToolCalls: []models.ExecutedFunctionCall{{
+					ID:        "call_1",
+					Name:      "bookMeeting",
+					Arguments: `{"eventTypeId":1,"email":"alice@example.com"}`,
+					Result:    map[string]interface{}{"id": "event-1"},
+				}},
+				Timestamp: now,
+			},
+		)
+		if err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+		err = store.Append("conv-2", models.HistoryEntry{
+			ChatMessage: models.ChatMessage{Role: "user", Content: "Cancel tomorrow's meeting with bob@example.com"},
+			Timestamp:   lastWeek,
+		})
+		if err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+
+		entries, err := store.Load("conv-1")
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("Expected 2 entries, got %d", len(entries))
+		}
+		if entries[0].Content != "Book a meeting\nfor tomorrow with alice@example.com" || entries[0].Metadata["userId"] != "u-1" {
+			t.Errorf("Unexpected first entry: %+v", entries[0])
+		}
+		if !entries[0].Timestamp.Equal(now) {
+			t.Errorf("Expected timestamp %v, got %v", now, entries[0].Timestamp)
+		}
+		if len(entries[1].ToolCalls) != 1 || entries[1].ToolCalls[0].Name != "bookMeeting" {
+			t.Errorf("Expected the tool call to be stored, got %+v", entries[1].ToolCalls)
+		}
+	})
+
+	t.Run("LoadMissing", func(t *testing.T) {
+		if _, err := store.Load("missing"); err != history.ErrNotFound {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("RejectsInvalidID", func(t *testing.T) {
+		err := store.Append("../escape", models.HistoryEntry{ChatMessage: models.ChatMessage{Role: "user", Content: "hi"}})
+		if err == nil {
+			t.Error("Expected an error for a conversation ID with path separators")
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		results, err := store.Search(history.SearchQuery{Term: "TOMORROW"})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 3 {
+			t.Fatalf("Expected 3 matching messages, got %+v", results)
+		}
+		for _, result := range results {
+			if !strings.Contains(strings.ToLower(result.Snippet), "[tomorrow") {
+				t.Errorf("Expected the match to be highlighted in the snippet, got %q", result.Snippet)
+			}
+			if result.Timestamp.IsZero() || result.Role == "" {
+				t.Errorf("Expected the timestamp and role in the result, got %+v", result)
+			}
+		}
+	})
+
+	t.Run("SearchFilters", func(t *testing.T) {
+		for _, tc := range []struct {
+			name     string
+			query    history.SearchQuery
+			expected []string
+		}{
+			{name: "Role", query: history.SearchQuery{Term: "tomorrow", Role: "assistant"}, expected: []string{"conv-1"}},
+			{name: "From", query: history.SearchQuery{Term: "meeting", From: now.Add(-time.Hour)}, expected: []string{"conv-1", "conv-1"}},
+			{name: "To", query: history.SearchQuery{Term: "meeting", To: now.Add(-time.Hour)}, expected: []string{"conv-2"}},
+			{name: "Attendee", query: history.SearchQuery{Term: "meeting", AttendeeEmail: "BOB@example.com"}, expected: []string{"conv-2"}},
+			{name: "AttendeeInToolCall", query: history.SearchQuery{Term: "booked", AttendeeEmail: "alice@example.com"}, expected: []string{"conv-1"}},
+			{name: "Limit", query: history.SearchQuery{Term: "meeting", Limit: 1}, expected: nil},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				results, err := store.Search(tc.query)
+				if err != nil {
+					t.Fatalf("Search failed: %v", err)
+				}
+				if tc.query.Limit > 0 {
+					if len(results) != tc.query.Limit {
+						t.Errorf("Expected %d results, got %d", tc.query.Limit, len(results))
+					}
+					return
+				}
+				var ids []string
+				for _, result := range results {
+					ids = append(ids, result.ConversationID)
+				}
+				if strings.Join(ids, ",") != strings.Join(tc.expected, ",") {
+					t.Errorf("Expected %v, got %v", tc.expected, ids)
+				}
+			})
+		}
+	})
+}
+
+// TestMigrateTextHistory tests converting legacy .txt history files
+func TestMigrateTextHistory(t *testing.T) {
+	dir := t.TempDir()
+	legacy := "[2025-05-18T19:16:54-07:00] [user]: Create a booking\n" +
+		"[2025-05-18T19:16:58-07:00] [assistant]: Here are the details:\n" +
+		"\n" +
+		"- Title: General Discussion\n"
+	if err := os.WriteFile(filepath.Join(dir, "legacy-1.txt"), []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write legacy file: %v", err)
+	}
+
+	store := newTestHistoryStore(t)
+	migrated, err := history.MigrateTextHistory(dir, store)
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if migrated != 1 {
+		t.Fatalf("Expected 1 migrated conversation, got %d", migrated)
+	}
+
+	entries, err := store.Load("legacy-1")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Role != "user" || entries[0].Content != "Create a booking" {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Content != "Here are the details:\n\n- Title: General Discussion" {
+		t.Errorf("Expected multi-line content to be preserved, got %q", entries[1].Content)
+	}
+	if entries[1].Timestamp.Format(time.RFC3339) != "2025-05-18T19:16:58-07:00" {
+		t.Errorf("Unexpected timestamp: %v", entries[1].Timestamp)
+	}
+
+	// Running again must not duplicate the conversation
+	migrated, err = history.MigrateTextHistory(dir, store)
+	if err != nil || migrated != 0 {
+		t.Errorf("Expected a second run to migrate nothing, got %d (%v)", migrated, err)
+	}
+}
+
+// TestHistorySearchEndpoint tests query parameter handling of /api/history/search
+func TestHistorySearchEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := newTestHistoryStore(t)
+	store.Append("conv-1",
+		models.HistoryEntry{ChatMessage: models.ChatMessage{Role: "user", Content: "Book a demo on Friday"}, Timestamp: time.Date(2025, 5, 16, 9, 0, 0, 0, time.UTC)},
+		models.HistoryEntry{ChatMessage: models.ChatMessage{Role: "assistant", Content: "Your demo is booked."}, Timestamp: time.Date(2025, 5, 16, 9, 1, 0, 0, time.UTC)},
+	)
+	router := gin.New()
+	api.NewHandler(&RecordingChatbot{}, store).SetupRoutes(router)
+
+	for _, tc := range []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{name: "Term", query: "q=demo", expectedStatus: http.StatusOK, expectedCount: 2},
+		{name: "Role", query: "q=demo&role=user", expectedStatus: http.StatusOK, expectedCount: 1},
+		{name: "DateRange", query: "q=demo&from=2025-05-16&to=2025-05-16", expectedStatus: http.StatusOK, expectedCount: 2},
+		{name: "DateRangeExcludes", query: "q=demo&to=2025-05-15", expectedStatus: http.StatusOK, expectedCount: 0},
+		{name: "MissingTerm", query: "role=user", expectedStatus: http.StatusBadRequest},
+		{name: "InvalidDate", query: "q=demo&from=yesterday", expectedStatus: http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/api/history/search?"+tc.query, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			if resp.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tc.expectedStatus, resp.Code)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			var response struct {
+				Matches []models.HistorySearchResult `json:"matches"`
+			}
+			if err := json.Unmarshal(resp.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if len(response.Matches) != tc.expectedCount {
+				t.Errorf("Expected %d matches, got %+v", tc.expectedCount, response.Matches)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/chatbot"
+	"github.com/yourusername/cal-chatbot/internal/idempotency"
+	"github.com/yourusername/cal-chatbot/internal/models"
+)
+
+// fakeCalcomBookings is a fake Cal.com bookings API. When failAfterCreate is set, POST /bookings
+// creates the booking but answers with an error, like a request timing out after the fact.
+type fakeCalcomBookings struct {
+	mu              sync.Mutex
+	bookings        []models.Event
+	posts           int
+	failAfterCreate bool
+}
+
+// ServeHTTP handles GET and POST /bookings and POST /bookings/{id}/cancel
+func (f *fakeCalcomBookings) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/bookings/"), "/cancel"); ok && r.Method == http.MethodPost {
+		for i := range f.bookings {
+			if f.bookings[i].ID == id {
+				f.bookings[i].Status = "CANCELLED"
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
+		http.NotFound(w, r)
+		return
+	}
+	if r.URL.Path != "/bookings" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method == http.MethodGet {
+		json.NewEncoder(w).Encode(map[string]interface{}{"bookings": f.bookings})
+		return
+	}
+
+	var payload struct {
+		Start time.Time `json:"start"`
+		End   time.Time `json:"end"`
+	}
+	json.NewDecoder(r.Body).Decode(&payload)
+	f.posts++
+	event := models.Event{
+		ID:        fmt.Sprint(f.posts),
+		UID:       fmt.Sprintf("uid-%d", f.posts),
+		Title:     "Meeting",
+		StartTime: payload.Start,
+		EndTime:   payload.End,
+		Status:    "ACCEPTED",
+	}
+	f.bookings = append(f.bookings, event)
+	if f.failAfterCreate {
+		w.WriteHeader(http.StatusGatewayTimeout)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"booking": event})
+}
+
+// Posts returns the number of booking requests received
+func (f *fakeCalcomBookings) Posts() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.posts
+}
+
+// SetStatus changes the status of every booking, e.g. as if they were cancelled in the Cal.com app
+func (f *fakeCalcomBookings) SetStatus(status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.bookings {
+		f.bookings[i].Status = status
+	}
+}
+
+// TestBookingIdempotency tests that replayed bookings return the existing booking
+func TestBookingIdempotency(t *testing.T) {
+	start := time.Now().Add(200 * time.Hour).UTC().Truncate(time.Minute)
+	booking := models.BookingRequest{
+		EventTypeID: 1,
+		Start:       start,
+		End:         start.Add(30 * time.Minute),
+		Name:        "Jane",
+		Email:       "jane@example.com",
+	}
+
+	t.Run("Key", func(t *testing.T) {
+		key := idempotency.BookingKey(booking)
+		same := booking
+		same.Email = " Jane@Example.com"
+		same.Start = start.In(time.FixedZone("UTC+2", 2*60*60))
+		if idempotency.BookingKey(same) != key {
+			t.Error("Expected the key to ignore email case and the timezone of the times")
+		}
+		later := booking
+		later.Start = start.Add(time.Hour)
+		if idempotency.BookingKey(later) == key {
+			t.Error("Expected a different time to give a different key")
+		}
+	})
+
+	t.Run("FileStore", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "bookings.jsonl")
+		store, err := idempotency.NewFileStore(path, 0)
+		if err != nil {
+			t.Fatalf("Failed to open store: %v", err)
+		}
+		records := []idempotency.Record{
+			{Key: "k", BookingUID: "uid-1", Event: models.Event{ID: "1", UID: "uid-1"}},
+			{Key: "cancelled", BookingUID: "uid-2", Event: models.Event{ID: "2", UID: "uid-2"}},
+			{Key: "old", BookingUID: "uid-3", Event: models.Event{ID: "3", UID: "uid-3"}, CreatedAt: time.Now().Add(-25 * time.Hour)},
+		}
+		for _, record := range records {
+			if err := store.Put(record); err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+		}
+		if err := store.Forget("2"); err != nil {
+			t.Fatalf("Forget failed: %v", err)
+		}
+		if _, ok := store.Get("old"); ok {
+			t.Error("Expected a record older than the TTL to have expired")
+		}
+
+		reopened, err := idempotency.NewFileStore(path, 0)
+		if err != nil {
+			t.Fatalf("Failed to reopen store: %v", err)
+		}
+		if record, ok := reopened.Get("k"); !ok || record.BookingUID != "uid-1" {
+			t.Errorf("Expected the record to survive reopening, got %+v %v", record, ok)
+		}
+		for _, key := range []string{"cancelled", "old"} {
+			if record, ok := reopened.Get(key); ok {
+				t.Errorf("Expected no record for %q after reopening, got %+v", key, record)
+			}
+		}
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		fake := &fakeCalcomBookings{}
+		client := newTestCalcomClient(t, fake, calcomTestOptions{})
+
+		first, err := client.BookEvent(context.Background(), booking)
+		if err != nil {
+			t.Fatalf("BookEvent failed: %v", err)
+		}
+		second, err := client.BookEvent(context.Background(), booking)
+		if err != nil {
+			t.Fatalf("Replayed BookEvent failed: %v", err)
+		}
+		if fake.Posts() != 1 {
+			t.Errorf("Expected one booking request, got %d", fake.Posts())
+		}
+		if first.UID == "" || second.UID != first.UID {
+			t.Errorf("Expected the replay to return booking %q, got %q", first.UID, second.UID)
+		}
+
+		withKey := booking
+		withKey.IdempotencyKey = "form-2"
+		if _, err := client.BookEvent(context.Background(), withKey); err != nil {
+			t.Fatalf("BookEvent failed: %v", err)
+		}
+		if fake.Posts() != 2 {
+			t.Errorf("Expected a new key to book again, got %d booking requests", fake.Posts())
+		}
+	})
+
+	t.Run("CancelThenRebook", func(t *testing.T) {
+		fake := &fakeCalcomBookings{}
+		client := newTestCalcomClient(t, fake, calcomTestOptions{})
+
+		first, err := client.BookEvent(context.Background(), booking)
+		if err != nil {
+			t.Fatalf("BookEvent failed: %v", err)
+		}
+		if err := client.CancelEvent(context.Background(), first.ID); err != nil {
+			t.Fatalf("CancelEvent failed: %v", err)
+		}
+		if _, booked := client.BookedEvent(context.Background(), idempotency.BookingKey(booking)); booked {
+			t.Error("Expected the cancelled booking to be forgotten")
+		}
+		second, err := client.BookEvent(context.Background(), booking)
+		if err != nil {
+			t.Fatalf("BookEvent after cancelling failed: %v", err)
+		}
+		if fake.Posts() != 2 || second.UID == first.UID {
+			t.Errorf("Expected a new booking after cancelling %q, got %q after %d booking requests", first.UID, second.UID, fake.Posts())
+		}
+	})
+
+	t.Run("CancelledElsewhere", func(t *testing.T) {
+		fake := &fakeCalcomBookings{}
+		client := newTestCalcomClient(t, fake, calcomTestOptions{})
+
+		first, err := client.BookEvent(context.Background(), booking)
+		if err != nil {
+			t.Fatalf("BookEvent failed: %v", err)
+		}
+		fake.SetStatus("CANCELLED")
+		second, err := client.BookEvent(context.Background(), booking)
+		if err != nil {
+			t.Fatalf("BookEvent after cancelling failed: %v", err)
+		}
+		if fake.Posts() != 2 || second.UID == first.UID {
+			t.Errorf("Expected a new booking after %q was cancelled, got %q after %d booking requests", first.UID, second.UID, fake.Posts())
+		}
+	})
+
+	t.Run("ErrorAfterCreate", func(t *testing.T) {
+		fake := &fakeCalcomBookings{failAfterCreate: true}
+		client := newTestCalcomClient(t, fake, calcomTestOptions{})
+
+		event, err := client.BookEvent(context.Background(), booking)
+		if err != nil {
+			t.Fatalf("Expected the created booking to be found, got %v", err)
+		}
+		if event.UID != "uid-1" {
+			t.Errorf("Expected booking uid-1, got %q", event.UID)
+		}
+		if _, err := client.BookEvent(context.Background(), booking); err != nil {
+			t.Fatalf("Replayed BookEvent failed: %v", err)
+		}
+		if fake.Posts() != 1 {
+			t.Errorf("Expected one booking request, got %d", fake.Posts())
+		}
+	})
+
+	t.Run("DirectDoubleSubmit", func(t *testing.T) {
+		fake := &fakeCalcomBookings{}
+		bot, err := chatbot.NewChatbot(newTestCalcomClient(t, fake, calcomTestOptions{}))
+		if err != nil {
+			t.Fatalf("Failed to create chatbot: %v", err)
+		}
+		messages := []models.ChatMessage{{
+			Role: "user",
+			Booking: map[string]interface{}{
+				"eventTypeId": 1,
+				"startTime":   booking.Start.Format(time.RFC3339),
+				"endTime":     booking.End.Format(time.RFC3339),
+				"name":        booking.Name,
+				"email":       booking.Email,
+			},
+		}}
+
+		var responses []string
+		for i := 0; i < 2; i++ {
+			response, _, err := bot.ProcessMessage(context.Background(), messages)
+			if err != nil {
+				t.Fatalf("Direct booking %d failed: %v", i+1, err)
+			}
+			responses = append(responses, response)
+		}
+		if fake.Posts() != 1 {
+			t.Errorf("Expected one booking request, got %d", fake.Posts())
+		}
+		if responses[0] != responses[1] {
+			t.Errorf("Expected the same booking twice, got %s and %s", responses[0], responses[1])
+		}
+
+		// Once cancelled, the same booking is checked and made again
+		fake.SetStatus("CANCELLED")
+		if _, _, err := bot.ProcessMessage(context.Background(), messages); err != nil {
+			t.Fatalf("Direct booking after cancelling failed: %v", err)
+		}
+		if fake.Posts() != 2 {
+			t.Errorf("Expected the cancelled booking to be made again, got %d booking requests", fake.Posts())
+		}
+	})
+}
@@ -2,6 +2,7 @@ package test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -16,16 +17,20 @@ import (
 type MockChatbot struct{}
 
 // ProcessMessage mocks the chatbot's message processing
-func (m *MockChatbot) ProcessMessage(ctx interface{}, message string) (string, error) {
-	// Return different responses based on the message
+func (m *MockChatbot) ProcessMessage(ctx context.Context, messages []models.ChatMessage) (string, []models.ExecutedFunctionCall, error) {
+	// Return different responses based on the last message
+	message := ""
+	if len(messages) > 0 {
+		message = messages[len(messages)-1].Content
+	}
 	if message == "help me book a meeting" {
-		return "I'd be happy to help you book a meeting. What date and time works for you?", nil
+		return "I'd be happy to help you book a meeting. What date and time works for you?", nil, nil
 	} else if message == "show me my scheduled events" {
-		return "You have 2 scheduled events: 'Test Meeting 1' tomorrow and 'Test Meeting 2' in 2 days.", nil
+		return "You have 2 scheduled events: 'Test Meeting 1' tomorrow and 'Test Meeting 2' in 2 days.", nil, nil
 	} else if message == "cancel my event at 3pm today" {
-		return "I've canceled your event at 3pm today.", nil
+		return "I've canceled your event at 3pm today.", nil, nil
 	} else {
-		return "I'm here to help you manage your calendar. You can ask me to book a meeting, show your scheduled events, or cancel an event.", nil
+		return "I'm here to help you manage your calendar. You can ask me to book a meeting, show your scheduled events, or cancel an event.", nil, nil
 	}
 }
 
@@ -38,7 +43,7 @@ func TestIntegration(t *testing.T) {
 	mockChatbot := &MockChatbot{}
 
 	// Create API handlers with mock chatbot
-	handler := api.NewHandler(mockChatbot)
+	handler := api.NewHandler(mockChatbot, newTestHistoryStore(t))
 
 	// Create a test router
 	router := gin.New()
@@ -80,7 +85,7 @@ func TestIntegration(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			// Create request body
 			chatRequest := models.ChatRequest{
-				Message: tc.message,
+				Messages: []models.ChatMessage{{Role: "user", Content: tc.message}},
 			}
 			requestBody, _ := json.Marshal(chatRequest)
 
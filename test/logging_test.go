@@ -0,0 +1,173 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/cal-chatbot/internal/api"
+	"github.com/yourusername/cal-chatbot/internal/logging"
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/test/mocks"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write appends p to the buffer
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// records parses the JSON log records written so far
+func (b *syncBuffer) records(t *testing.T) []map[string]interface{} {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var records []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(b.buf.Bytes()))
+	for scanner.Scan() {
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Failed to parse log record %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// TestLogging tests the structured logger
+func TestLogging(t *testing.T) {
+	t.Run("ContextAndRedaction", func(t *testing.T) {
+		var buf syncBuffer
+		logger, err := logging.New(&buf, "info", "json")
+		if err != nil {
+			t.Fatalf("Failed to create logger: %v", err)
+		}
+		ctx := models.WithConversationID(models.WithRequestID(context.Background(), "req-1"), "conv-1")
+		logger.InfoContext(ctx, "listing events for jane@example.com", "email", "jane@example.com")
+		logger.DebugContext(ctx, "not logged at info level")
+
+		records := buf.records(t)
+		if len(records) != 1 {
+			t.Fatalf("Expected 1 record, got %d", len(records))
+		}
+		record := records[0]
+		if record["request_id"] != "req-1" || record["conversation_id"] != "conv-1" {
+			t.Errorf("Expected the IDs of the context, got %v", record)
+		}
+		if strings.Contains(record["msg"].(string), "jane@") || record["email"] != "j***@example.com" {
+			t.Errorf("Expected the email to be masked, got %v", record)
+		}
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		if _, err := logging.New(&bytes.Buffer{}, "loud", "text"); err == nil {
+			t.Error("Expected an invalid level to be rejected")
+		}
+		if _, err := logging.New(&bytes.Buffer{}, "info", "xml"); err == nil {
+			t.Error("Expected an invalid format to be rejected")
+		}
+	})
+}
+
+// TestRequestID tests assigning request IDs and carrying them into the chatbot's logs
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Middleware", func(t *testing.T) {
+		router := gin.New()
+		router.Use(api.RequestID())
+		var seen string
+		router.GET("/", func(c *gin.Context) { seen = models.RequestIDFromContext(c.Request.Context()) })
+
+		testCases := []struct {
+			name   string
+			header string
+			keep   bool
+		}{
+			{"FromClient", "abc-123", true},
+			{"Generated", "", false},
+			{"InvalidFromClient", "bad id\nINFO forged", false},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				if tc.header != "" {
+					req.Header.Set(api.RequestIDHeader, tc.header)
+				}
+				resp := httptest.NewRecorder()
+				router.ServeHTTP(resp, req)
+
+				if seen == "" || resp.Header().Get(api.RequestIDHeader) != seen {
+					t.Fatalf("Expected the request ID %q in the response header, got %q", seen, resp.Header().Get(api.RequestIDHeader))
+				}
+				if (seen == tc.header) != tc.keep {
+					t.Errorf("Expected keeping the client's ID to be %v, got %q", tc.keep, seen)
+				}
+			})
+		}
+	})
+
+	t.Run("CarriedIntoToolCalls", func(t *testing.T) {
+		var buf syncBuffer
+		logger, err := logging.New(&buf, "info", "json")
+		if err != nil {
+			t.Fatalf("Failed to create logger: %v", err)
+		}
+		previous := slog.Default()
+		slog.SetDefault(logger)
+		t.Cleanup(func() { slog.SetDefault(previous) })
+
+		server := mocks.NewMockOpenAIServer(
+			toolCallMessage(toolCall("call_1", "listEvents", `{"email":"jane@example.com"}`)),
+			contentMessage("You have two meetings."),
+		)
+		defer server.Close()
+		bot := newLoopTestBot(t, server, mocks.NewMockCalcomClient())
+		router := gin.New()
+		router.Use(api.RequestID(), api.RequestLogger())
+		api.NewHandler(bot, newTestHistoryStore(t)).SetupRoutes(router)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(`{"messages":[{"role":"user","content":"my meetings?"}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(api.RequestIDHeader, "req-42")
+		req.Header.Set("X-Conversation-Id", "conv-42")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", resp.Code, resp.Body.String())
+		}
+
+		found := map[string]bool{}
+		for _, record := range buf.records(t) {
+			msg, _ := record["msg"].(string)
+			if msg != "listEvents called" && msg != "Request served" {
+				continue
+			}
+			found[msg] = true
+			if record["request_id"] != "req-42" || record["conversation_id"] != "conv-42" {
+				t.Errorf("Expected %q to carry the request and conversation IDs, got %v", msg, record)
+			}
+			if args, _ := record["args"].(string); strings.Contains(args, "jane@") {
+				t.Errorf("Expected the email in the args to be masked, got %q", args)
+			}
+		}
+		if !found["listEvents called"] || !found["Request served"] {
+			t.Errorf("Expected the tool call and the request to be logged, found %v", found)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/yourusername/cal-chatbot/internal/api"
+	"github.com/yourusername/cal-chatbot/internal/metrics"
+	"github.com/yourusername/cal-chatbot/test/mocks"
+)
+
+// TestMetrics tests the metrics recorded for chat requests, tool calls, Cal.com requests and OpenAI completions
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("CalcomEndpoint", func(t *testing.T) {
+		testCases := map[string]string{
+			"/v2/bookings?attendeeEmail=x":       "/v2/bookings",
+			"/v2/bookings/abc-123/cancel":        "/v2/bookings/{id}/cancel",
+			"/v1/availability/jane/42":           "/v1/availability/{id}/{id}",
+			"/schedules/7":                       "/schedules/{id}",
+			"/v2/event-types":                    "/v2/event-types",
+			"/bookings/uid-1/reschedule?apiKey=": "/bookings/{id}/reschedule",
+		}
+		for path, expected := range testCases {
+			if got := metrics.CalcomEndpoint(path); got != expected {
+				t.Errorf("CalcomEndpoint(%q) = %q, expected %q", path, got, expected)
+			}
+		}
+	})
+
+	t.Run("Chat", func(t *testing.T) {
+		t.Setenv("OPENAI_MODEL", "metrics-test-model")
+		server := mocks.NewMockOpenAIServer(
+			toolCallMessage(toolCall("call_1", "listEventTypes", `{}`), toolCall("call_2", "noSuchTool", `{}`)),
+			contentMessage("Here are the event types."),
+		)
+		defer server.Close()
+		server.Usage = goopenai.Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120}
+		bot := newLoopTestBot(t, server, mocks.NewMockCalcomClient())
+		router := gin.New()
+		router.Use(api.Metrics())
+		api.NewHandler(bot, newTestHistoryStore(t)).SetupRoutes(router)
+
+		toolSuccesses := testutil.ToFloat64(metrics.ToolCalls.WithLabelValues("listEventTypes", "success"))
+		unknownTools := testutil.ToFloat64(metrics.ToolCalls.WithLabelValues("unknown", "error"))
+
+		req := httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(`{"messages":[{"role":"user","content":"what can I book?"}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", resp.Code, resp.Body.String())
+		}
+
+		if got := testutil.ToFloat64(metrics.ToolCalls.WithLabelValues("listEventTypes", "success")) - toolSuccesses; got != 1 {
+			t.Errorf("Expected 1 successful listEventTypes call, got %v", got)
+		}
+		if got := testutil.ToFloat64(metrics.ToolCalls.WithLabelValues("unknown", "error")) - unknownTools; got != 1 {
+			t.Errorf("Expected 1 unknown tool call, got %v", got)
+		}
+		// Two completions: the tool calls and the answer
+		if got := testutil.ToFloat64(metrics.OpenAITokens.WithLabelValues("metrics-test-model", "prompt")); got != 200 {
+			t.Errorf("Expected 200 prompt tokens, got %v", got)
+		}
+		if got := testutil.ToFloat64(metrics.OpenAITokens.WithLabelValues("metrics-test-model", "completion")); got != 40 {
+			t.Errorf("Expected 40 completion tokens, got %v", got)
+		}
+
+		body := scrapeMetrics(t)
+		for _, line := range []string{
+			`chatbot_http_requests_total{method="POST",route="/api/chat",status="200"}`,
+			`chatbot_http_request_duration_seconds_count{method="POST",route="/api/chat"}`,
+			`chatbot_openai_request_duration_seconds_count{model="metrics-test-model",outcome="success"} 2`,
+			`chatbot_tool_call_duration_seconds_count{tool="listEventTypes"}`,
+		} {
+			if !strings.Contains(body, line) {
+				t.Errorf("Expected /metrics to contain %s", line)
+			}
+		}
+	})
+
+	t.Run("CalcomRequests", func(t *testing.T) {
+		script := &scriptedCalcomServer{statuses: []int{http.StatusServiceUnavailable}}
+		client := newTestCalcomClient(t, script, fastRetries)
+		if _, err := client.FindAllSchedules(context.Background()); err != nil {
+			t.Fatalf("FindAllSchedules failed: %v", err)
+		}
+		body := scrapeMetrics(t)
+		for _, status := range []string{"503", "200"} {
+			line := `chatbot_calcom_request_duration_seconds_count{endpoint="/schedules",method="GET",status="` + status + `"}`
+			if !strings.Contains(body, line) {
+				t.Errorf("Expected /metrics to contain %s", line)
+			}
+		}
+	})
+}
+
+// scrapeMetrics returns the metrics served on /metrics
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+	router := gin.New()
+	api.NewHandler(nil, nil).SetupRoutes(router)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 from /metrics, got %d", resp.Code)
+	}
+	return resp.Body.String()
+}
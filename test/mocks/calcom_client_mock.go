@@ -1,18 +1,43 @@
 package mocks
 
 import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/yourusername/cal-chatbot/internal/calcom"
 	"github.com/yourusername/cal-chatbot/internal/models"
 )
 
+// Ensure MockCalcomClient satisfies calcom.CalendarBackend
+var _ calcom.CalendarBackend = (*MockCalcomClient)(nil)
+
 // MockCalcomClient is a mock implementation of the Cal.com client
 type MockCalcomClient struct {
 	// Mock return values
 	Events         []models.Event
 	AvailableSlots []time.Time
 	BookedEvent    *models.Event
+	EventTypes     []models.EventType
+	Schedules      []models.Schedule
+	Booking        *models.Booking
 	Err            error
+	// EventTypesDelay is how long GetEventTypes takes
+	EventTypesDelay time.Duration
+
+	// Recorded calls
+	mu               sync.Mutex
+	BookingRequest   *models.BookingRequest
+	SlotsStart       time.Time
+	SlotsEnd         time.Time
+	CanceledEventID  string
+	EditedBookingID  string
+	BookingUpdate    models.BookingUpdate
+	ScheduleUpdate   models.ScheduleUpdate
+	RemovedSchedule  string
+	EventTypeFetches int
 }
 
 // NewMockCalcomClient creates a new mock Cal.com client
@@ -46,12 +71,41 @@ func NewMockCalcomClient() *MockCalcomClient {
 			EndTime:   time.Now().Add(121 * time.Hour),
 			Status:    "confirmed",
 		},
+		EventTypes: []models.EventType{
+			{
+				ID:         1,
+				Title:      "30 Min Meeting",
+				Slug:       "30min",
+				Length:     30,
+				LengthUnit: "minutes",
+			},
+		},
+		Schedules: []models.Schedule{
+			{
+				ID:        1,
+				Name:      "Working Hours",
+				TimeZone:  "UTC",
+				IsDefault: true,
+				Availability: []models.AvailabilityRule{
+					{Days: []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}, StartTime: "09:00", EndTime: "17:00"},
+				},
+			},
+		},
+		Booking: &models.Booking{
+			ID:        1,
+			UID:       "event-1",
+			Title:     "Test Meeting 1",
+			StartTime: time.Now().Add(24 * time.Hour),
+			EndTime:   time.Now().Add(25 * time.Hour),
+			Status:    "ACCEPTED",
+			Attendees: []models.Attendee{{Name: "Jane Doe", Email: "jane@example.com", TimeZone: "UTC"}},
+		},
 		Err: nil,
 	}
 }
 
 // GetEvents mocks the GetEvents method
-func (m *MockCalcomClient) GetEvents(email string) ([]models.Event, error) {
+func (m *MockCalcomClient) GetEvents(ctx context.Context, email string) ([]models.Event, error) {
 	if m.Err != nil {
 		return nil, m.Err
 	}
@@ -59,7 +113,10 @@ func (m *MockCalcomClient) GetEvents(email string) ([]models.Event, error) {
 }
 
 // GetAvailableSlots mocks the GetAvailableSlots method
-func (m *MockCalcomClient) GetAvailableSlots(eventTypeID int, startDate, endDate time.Time) ([]time.Time, error) {
+func (m *MockCalcomClient) GetAvailableSlots(ctx context.Context, eventTypeID int, startDate, endDate time.Time) ([]time.Time, error) {
+	m.mu.Lock()
+	m.SlotsStart, m.SlotsEnd = startDate, endDate
+	m.mu.Unlock()
 	if m.Err != nil {
 		return nil, m.Err
 	}
@@ -67,7 +124,10 @@ func (m *MockCalcomClient) GetAvailableSlots(eventTypeID int, startDate, endDate
 }
 
 // BookEvent mocks the BookEvent method
-func (m *MockCalcomClient) BookEvent(booking models.BookingRequest) (*models.Event, error) {
+func (m *MockCalcomClient) BookEvent(ctx context.Context, booking models.BookingRequest) (*models.Event, error) {
+	m.mu.Lock()
+	m.BookingRequest = &booking
+	m.mu.Unlock()
 	if m.Err != nil {
 		return nil, m.Err
 	}
@@ -75,14 +135,146 @@ func (m *MockCalcomClient) BookEvent(booking models.BookingRequest) (*models.Eve
 }
 
 // CancelEvent mocks the CancelEvent method
-func (m *MockCalcomClient) CancelEvent(eventID string) error {
+func (m *MockCalcomClient) CancelEvent(ctx context.Context, eventID string) error {
+	m.mu.Lock()
+	m.CanceledEventID = eventID
+	m.mu.Unlock()
 	return m.Err
 }
 
 // RescheduleEvent mocks the RescheduleEvent method
-func (m *MockCalcomClient) RescheduleEvent(eventID string, newStartTime, newEndTime time.Time) (*models.Event, error) {
+func (m *MockCalcomClient) RescheduleEvent(ctx context.Context, eventID string, newStartTime, newEndTime time.Time) (*models.Event, error) {
 	if m.Err != nil {
 		return nil, m.Err
 	}
 	return m.BookedEvent, nil
 }
+
+// FindBooking mocks the FindBooking method
+func (m *MockCalcomClient) FindBooking(ctx context.Context, bookingID string) (*models.Booking, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	if m.Booking != nil && (m.Booking.UID == bookingID || strconv.Itoa(m.Booking.ID) == bookingID) {
+		return m.Booking, nil
+	}
+	for _, event := range m.Events {
+		if event.ID == bookingID {
+			return &models.Booking{UID: event.ID, Title: event.Title, StartTime: event.StartTime, EndTime: event.EndTime, Status: event.Status}, nil
+		}
+	}
+	return nil, &calcom.APIError{StatusCode: http.StatusNotFound, Message: "Booking not found"}
+}
+
+// EditBooking mocks the EditBooking method
+func (m *MockCalcomClient) EditBooking(ctx context.Context, bookingID string, update models.BookingUpdate) (*models.Booking, error) {
+	m.mu.Lock()
+	m.EditedBookingID, m.BookingUpdate = bookingID, update
+	m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	booking := models.Booking{UID: bookingID}
+	if m.Booking != nil {
+		booking = *m.Booking
+	}
+	if update.Title != "" {
+		booking.Title = update.Title
+	}
+	if update.Description != "" {
+		booking.Description = update.Description
+	}
+	if update.Location != "" {
+		booking.Location = update.Location
+	}
+	return &booking, nil
+}
+
+// GetBookableSlots mocks the GetBookableSlots method, offering the available slots for every event type
+func (m *MockCalcomClient) GetBookableSlots(ctx context.Context, startDate, endDate time.Time) ([]models.Slot, error) {
+	m.mu.Lock()
+	m.SlotsStart, m.SlotsEnd = startDate, endDate
+	m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var slots []models.Slot
+	for _, start := range m.AvailableSlots {
+		for _, eventType := range m.EventTypes {
+			length := time.Duration(eventType.Length) * time.Minute
+			slots = append(slots, models.Slot{EventTypeID: eventType.ID, Start: start, End: start.Add(length)})
+		}
+	}
+	return slots, nil
+}
+
+// GetEventTypes mocks the GetEventTypes method
+func (m *MockCalcomClient) GetEventTypes(ctx context.Context) ([]models.EventType, error) {
+	m.mu.Lock()
+	m.EventTypeFetches++
+	m.mu.Unlock()
+	time.Sleep(m.EventTypesDelay)
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.EventTypes, nil
+}
+
+// CreateEventType mocks the CreateEventType method
+func (m *MockCalcomClient) CreateEventType(ctx context.Context, req models.EventTypeCreateRequest) (*models.EventType, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return &models.EventType{
+		ID:          len(m.EventTypes) + 1,
+		Title:       req.Title,
+		Slug:        req.Slug,
+		Description: req.Description,
+		Length:      req.Length,
+		LengthUnit:  req.LengthUnit,
+	}, nil
+}
+
+// FindAllSchedules mocks the FindAllSchedules method
+func (m *MockCalcomClient) FindAllSchedules(ctx context.Context) ([]models.Schedule, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.Schedules, nil
+}
+
+// CreateSchedule mocks the CreateSchedule method
+func (m *MockCalcomClient) CreateSchedule(ctx context.Context, name, timeZone string) (*models.Schedule, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return &models.Schedule{
+		ID:       len(m.Schedules) + 1,
+		Name:     name,
+		TimeZone: timeZone,
+	}, nil
+}
+
+// EditSchedule mocks the EditSchedule method
+func (m *MockCalcomClient) EditSchedule(ctx context.Context, scheduleID string, update models.ScheduleUpdate) (*models.Schedule, error) {
+	m.mu.Lock()
+	m.ScheduleUpdate = update
+	m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	schedule := models.Schedule{Name: update.Name, TimeZone: update.TimeZone, Availability: update.Availability, Overrides: update.Overrides}
+	schedule.ID, _ = strconv.Atoi(scheduleID)
+	if update.IsDefault != nil {
+		schedule.IsDefault = *update.IsDefault
+	}
+	return &schedule, nil
+}
+
+// RemoveSchedule mocks the RemoveSchedule method
+func (m *MockCalcomClient) RemoveSchedule(ctx context.Context, scheduleID string) error {
+	m.mu.Lock()
+	m.RemovedSchedule = scheduleID
+	m.mu.Unlock()
+	return m.Err
+}
@@ -0,0 +1,160 @@
+package mocks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	goopenai "github.com/sashabaranov/go-openai"
+)
+
+// MockOpenAIServer is a fake OpenAI chat completions API that replays scripted assistant messages
+type MockOpenAIServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses []goopenai.ChatCompletionMessage
+	Requests  []goopenai.ChatCompletionRequest
+
+	// Respond, when set, answers requests instead of the script, e.g. to reply based on tool results
+	Respond func(req goopenai.ChatCompletionRequest) goopenai.ChatCompletionMessage
+
+	// Usage is the token usage reported with every completion that isn't streamed
+	Usage goopenai.Usage
+}
+
+// NewMockOpenAIServer starts a fake OpenAI server that answers with the given messages in order.
+// Once the script is exhausted the last message is repeated.
+func NewMockOpenAIServer(responses ...goopenai.ChatCompletionMessage) *MockOpenAIServer {
+	m := &MockOpenAIServer{responses: responses}
+	m.Server = httptest.NewServer(http.HandlerFunc(m.handleChatCompletion))
+	return m
+}
+
+// URL returns the base URL to use as the OpenAI API endpoint
+func (m *MockOpenAIServer) URL() string {
+	return m.Server.URL + "/v1"
+}
+
+// RequestCount returns the number of chat completion requests received so far
+func (m *MockOpenAIServer) RequestCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.Requests)
+}
+
+// Reset forgets the requests received so far and restarts the script
+func (m *MockOpenAIServer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Requests = nil
+}
+
+// SetResponses replaces the script and restarts it, e.g. to script the next turn of a conversation
+func (m *MockOpenAIServer) SetResponses(responses ...goopenai.ChatCompletionMessage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses = responses
+	m.Requests = nil
+}
+
+// LastRequest returns the most recent chat completion request
+func (m *MockOpenAIServer) LastRequest() goopenai.ChatCompletionRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.Requests) == 0 {
+		return goopenai.ChatCompletionRequest{}
+	}
+	return m.Requests[len(m.Requests)-1]
+}
+
+// handleChatCompletion records the request and replies with the next scripted message
+func (m *MockOpenAIServer) handleChatCompletion(w http.ResponseWriter, r *http.Request) {
+	var req goopenai.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	index := len(m.Requests)
+	m.Requests = append(m.Requests, req)
+	var message goopenai.ChatCompletionMessage
+	if m.Respond != nil {
+		message = m.Respond(req)
+	} else if len(m.responses) > 0 {
+		if index >= len(m.responses) {
+			index = len(m.responses) - 1
+		}
+		message = m.responses[index]
+	}
+	usage := m.Usage
+	m.mu.Unlock()
+
+	if message.Role == "" {
+		message.Role = goopenai.ChatMessageRoleAssistant
+	}
+	if req.Stream {
+		m.streamMessage(w, req.Model, message)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goopenai.ChatCompletionResponse{
+		ID:     "chatcmpl-mock",
+		Object: "chat.completion",
+		Model:  req.Model,
+		Choices: []goopenai.ChatCompletionChoice{{
+			Index:   0,
+			Message: message,
+		}},
+		Usage: usage,
+	})
+}
+
+// streamMessage replies with the message split into chat completion chunks, the way the
+// streaming API does: content word by word, and each tool call as a header and an arguments fragment
+func (m *MockOpenAIServer) streamMessage(w http.ResponseWriter, model string, message goopenai.ChatCompletionMessage) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	writeChunk := func(delta goopenai.ChatCompletionStreamChoiceDelta, finishReason goopenai.FinishReason) {
+		chunk, _ := json.Marshal(goopenai.ChatCompletionStreamResponse{
+			ID:     "chatcmpl-mock",
+			Object: "chat.completion.chunk",
+			Model:  model,
+			Choices: []goopenai.ChatCompletionStreamChoice{{
+				Delta:        delta,
+				FinishReason: finishReason,
+			}},
+		})
+		fmt.Fprintf(w, "data: %s\n\n", chunk)
+	}
+
+	writeChunk(goopenai.ChatCompletionStreamChoiceDelta{Role: message.Role}, "")
+	for _, word := range strings.SplitAfter(message.Content, " ") {
+		if word != "" {
+			writeChunk(goopenai.ChatCompletionStreamChoiceDelta{Content: word}, "")
+		}
+	}
+	for i, call := range message.ToolCalls {
+		index := i
+		writeChunk(goopenai.ChatCompletionStreamChoiceDelta{ToolCalls: []goopenai.ToolCall{{
+			Index:    &index,
+			ID:       call.ID,
+			Type:     call.Type,
+			Function: goopenai.FunctionCall{Name: call.Function.Name},
+		}}}, "")
+		writeChunk(goopenai.ChatCompletionStreamChoiceDelta{ToolCalls: []goopenai.ToolCall{{
+			Index:    &index,
+			Function: goopenai.FunctionCall{Arguments: call.Function.Arguments},
+		}}}, "")
+	}
+
+	finishReason := goopenai.FinishReasonStop
+	if len(message.ToolCalls) > 0 {
+		finishReason = goopenai.FinishReasonToolCalls
+	}
+	writeChunk(goopenai.ChatCompletionStreamChoiceDelta{}, finishReason)
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
@@ -2,26 +2,18 @@ package test
 
 import (
 	"context"
-	"os"
 	"testing"
 
 	"github.com/yourusername/cal-chatbot/internal/chatbot"
+	"github.com/yourusername/cal-chatbot/internal/models"
 )
 
 // TestOpenAIIntegration is a test suite for the OpenAI integration
 func TestOpenAIIntegration(t *testing.T) {
-	// Skip tests if OPENAI_API_KEY is not set
-	if os.Getenv("OPENAI_API_KEY") == "" {
-		t.Skip("OPENAI_API_KEY not set, skipping OpenAI tests")
-	}
-
-	// Skip tests if Cal.com environment variables are not set
-	if os.Getenv("CALCOM_API_KEY") == "" || os.Getenv("CALCOM_USERNAME") == "" {
-		t.Skip("CALCOM_API_KEY or CALCOM_USERNAME not set, skipping OpenAI tests that require Cal.com")
-	}
+	requireLiveEnv(t, "OPENAI_API_KEY", "CALCOM_API_KEY", "CALCOM_USERNAME")
 
 	t.Run("NewChatbot", func(t *testing.T) {
-		bot, err := chatbot.NewChatbot()
+		bot, err := chatbot.NewChatbot(nil)
 		if err != nil {
 			t.Fatalf("Failed to create chatbot: %v", err)
 		}
@@ -31,14 +23,14 @@ func TestOpenAIIntegration(t *testing.T) {
 	})
 
 	t.Run("ProcessMessage", func(t *testing.T) {
-		bot, err := chatbot.NewChatbot()
+		bot, err := chatbot.NewChatbot(nil)
 		if err != nil {
 			t.Fatalf("Failed to create chatbot: %v", err)
 		}
 
 		// Test a simple message that should not trigger a function call
 		ctx := context.Background()
-		response, err := bot.ProcessMessage(ctx, "Hello, how are you?")
+		response, _, err := bot.ProcessMessage(ctx, []models.ChatMessage{{Role: "user", Content: "Hello, how are you?"}})
 		if err != nil {
 			t.Fatalf("Failed to process message: %v", err)
 		}
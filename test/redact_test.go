@@ -0,0 +1,67 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/cal-chatbot/internal/calcom"
+	"github.com/yourusername/cal-chatbot/internal/redact"
+)
+
+// TestRedact tests masking secrets and email addresses
+func TestRedact(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Email", "events for jane.doe@example.com", "events for j***@example.com"},
+		{"QueryKey", "GET /bookings?apiKey=cal_live_abc123&email=x", "GET /bookings?apiKey=[REDACTED]&email=x"},
+		{"JSONKey", `{"apiKey":"secret-value","name":"Jane"}`, `{"apiKey":"[REDACTED]","name":"Jane"}`},
+		{"Bearer", "Authorization: Bearer abc.def", "Authorization: Bearer [REDACTED]"},
+		{"KeyPrefix", "key cal_live_0123456789 in text", "key [REDACTED] in text"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := redact.String(tc.input); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+
+	t.Run("URL", func(t *testing.T) {
+		got := redact.URL("https://api.cal.com/v1/bookings?apiKey=cal_live_abc&email=jane%40example.com")
+		if strings.Contains(got, "cal_live_abc") || strings.Contains(got, "jane") {
+			t.Errorf("Expected the key and email to be masked, got %q", got)
+		}
+		if !strings.HasPrefix(got, "https://api.cal.com/v1/bookings?") {
+			t.Errorf("Expected the URL to be kept, got %q", got)
+		}
+	})
+}
+
+// TestCalcomErrorsHideCredentials tests that Cal.com request errors don't contain the API key or email addresses
+func TestCalcomErrorsHideCredentials(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	t.Setenv("CALCOM_API_KEY", "cal_live_secret")
+	t.Setenv("CALCOM_API_URL", server.URL)
+	t.Setenv("CALCOM_MAX_RETRIES", "0")
+	client, err := calcom.NewClient()
+	if err != nil {
+		t.Fatalf("Failed to create Cal.com client: %v", err)
+	}
+
+	_, err = client.GetEvents(context.Background(), "jane@example.com")
+	if err == nil {
+		t.Fatal("Expected the request to the closed server to fail")
+	}
+	for _, secret := range []string{"cal_live_secret", "apiKey", "jane"} {
+		if strings.Contains(err.Error(), secret) {
+			t.Errorf("Expected the error not to contain %q, got %v", secret, err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedCalcomServer answers requests with the scripted status codes in order, then with 200.
+// A 429 carries the given Retry-After header.
+type scriptedCalcomServer struct {
+	mu         sync.Mutex
+	statuses   []int
+	retryAfter string
+	requests   int
+}
+
+// ServeHTTP answers with the next scripted status
+func (s *scriptedCalcomServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := http.StatusOK
+	if s.requests < len(s.statuses) {
+		status = s.statuses[s.requests]
+	}
+	s.requests++
+	if status == http.StatusTooManyRequests && s.retryAfter != "" {
+		w.Header().Set("Retry-After", s.retryAfter)
+	}
+	w.WriteHeader(status)
+	w.Write([]byte(`{"eventTypes":[],"schedules":[]}`))
+}
+
+// Requests returns the number of requests received
+func (s *scriptedCalcomServer) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// TestCalcomRetries tests retrying, backing off and rate limiting Cal.com requests
+func TestCalcomRetries(t *testing.T) {
+	t.Run("RetriesServerErrors", func(t *testing.T) {
+		script := &scriptedCalcomServer{statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable}}
+		client := newTestCalcomClient(t, script, fastRetries)
+		if _, err := client.GetEventTypes(context.Background()); err != nil {
+			t.Fatalf("Expected the request to succeed after retries, got %v", err)
+		}
+		if script.Requests() != 3 {
+			t.Errorf("Expected 3 requests, got %d", script.Requests())
+		}
+	})
+
+	t.Run("GivesUp", func(t *testing.T) {
+		script := &scriptedCalcomServer{statuses: []int{500, 500, 500, 500, 500}}
+		client := newTestCalcomClient(t, script, fastRetries)
+		if _, err := client.GetEventTypes(context.Background()); err == nil {
+			t.Fatal("Expected an error after the last retry")
+		}
+		if script.Requests() != 4 {
+			t.Errorf("Expected 1 attempt and 3 retries, got %d requests", script.Requests())
+		}
+	})
+
+	t.Run("NoRetryForNonIdempotentRequests", func(t *testing.T) {
+		script := &scriptedCalcomServer{statuses: []int{http.StatusServiceUnavailable}}
+		client := newTestCalcomClient(t, script, fastRetries)
+		if _, err := client.CreateSchedule(context.Background(), "Working Hours", "UTC"); err == nil {
+			t.Fatal("Expected the error to be returned")
+		}
+		if script.Requests() != 1 {
+			t.Errorf("Expected a POST not to be retried, got %d requests", script.Requests())
+		}
+	})
+
+	t.Run("NoRetryForClientErrors", func(t *testing.T) {
+		script := &scriptedCalcomServer{statuses: []int{http.StatusNotFound}}
+		client := newTestCalcomClient(t, script, fastRetries)
+		if _, err := client.GetEventTypes(context.Background()); err == nil {
+			t.Fatal("Expected the error to be returned")
+		}
+		if script.Requests() != 1 {
+			t.Errorf("Expected a 404 not to be retried, got %d requests", script.Requests())
+		}
+	})
+
+	t.Run("HonorsRetryAfter", func(t *testing.T) {
+		script := &scriptedCalcomServer{statuses: []int{http.StatusTooManyRequests}, retryAfter: "1"}
+		client := newTestCalcomClient(t, script, fastRetries)
+		start := time.Now()
+		if _, err := client.CreateSchedule(context.Background(), "Working Hours", "UTC"); err != nil {
+			t.Fatalf("Expected the rate-limited request to be retried, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("Expected to wait for Retry-After, retried after %v", elapsed)
+		}
+		if script.Requests() != 2 {
+			t.Errorf("Expected 2 requests, got %d", script.Requests())
+		}
+	})
+
+	t.Run("RetryAfterTooLong", func(t *testing.T) {
+		script := &scriptedCalcomServer{statuses: []int{http.StatusTooManyRequests}, retryAfter: "3600"}
+		client := newTestCalcomClient(t, script, fastRetries)
+		if _, err := client.GetEventTypes(context.Background()); err == nil {
+			t.Fatal("Expected the rate limit error to be returned")
+		}
+		if script.Requests() != 1 {
+			t.Errorf("Expected no retry, got %d requests", script.Requests())
+		}
+	})
+
+	t.Run("RateLimit", func(t *testing.T) {
+		script := &scriptedCalcomServer{}
+		client := newTestCalcomClient(t, script, fastRetries)
+		client.SetRateLimit(600, 1)
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			if _, err := client.GetEventTypes(context.Background()); err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+		}
+		// One request is allowed right away, then one every 100ms
+		if elapsed := time.Since(start); elapsed < 190*time.Millisecond {
+			t.Errorf("Expected requests to be spaced out by the rate limit, took %v", elapsed)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/cal-chatbot/internal/api"
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/test/mocks"
+)
+
+// parseSSE parses a Server-Sent Events body into stream events
+func parseSSE(t *testing.T, body string) []models.StreamEvent {
+	t.Helper()
+	var events []models.StreamEvent
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		var event models.StreamEvent
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &event); err != nil {
+			t.Fatalf("Failed to parse event %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// TestChatStream tests the Server-Sent Events chat endpoint
+func TestChatStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server := mocks.NewMockOpenAIServer(
+		toolCallMessage(toolCall("call_1", "listEventTypes", `{}`)),
+		contentMessage("You have one event type."),
+	)
+	defer server.Close()
+	bot := newLoopTestBot(t, server, mocks.NewMockCalcomClient())
+
+	router := gin.New()
+	api.NewHandler(bot, newTestHistoryStore(t)).SetupRoutes(router)
+
+	for _, tc := range []struct {
+		name   string
+		path   string
+		accept string
+	}{
+		{name: "StreamEndpoint", path: "/api/chat/stream"},
+		{name: "AcceptHeader", path: "/api/chat", accept: "text/event-stream"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server.Reset()
+			requestBody, _ := json.Marshal(models.ChatRequest{
+				Messages: []models.ChatMessage{{Role: "user", Content: "what event types do I have?"}},
+			})
+			req, _ := http.NewRequest("POST", tc.path, bytes.NewBuffer(requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Conversation-Id", "stream-test")
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			if resp.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, resp.Code)
+			}
+			if !strings.HasPrefix(resp.Header().Get("Content-Type"), "text/event-stream") {
+				t.Errorf("Expected an event stream, got %s", resp.Header().Get("Content-Type"))
+			}
+
+			events := parseSSE(t, resp.Body.String())
+			var types []string
+			var streamed string
+			for _, event := range events {
+				types = append(types, event.Type)
+				if event.Type == models.StreamEventDelta {
+					streamed += event.Content
+				}
+			}
+			if len(events) < 4 || types[0] != models.StreamEventToolCallStart || types[1] != models.StreamEventToolCallEnd {
+				t.Fatalf("Unexpected event sequence: %v", types)
+			}
+			if events[0].ToolCall == nil || events[0].ToolCall.Name != "listEventTypes" {
+				t.Errorf("Expected tool call start for listEventTypes, got %+v", events[0].ToolCall)
+			}
+			if streamed != "You have one event type." {
+				t.Errorf("Unexpected streamed content: %q", streamed)
+			}
+
+			last := events[len(events)-1]
+			if last.Type != models.StreamEventMessage {
+				t.Fatalf("Expected the stream to end with a message event, got %s", last.Type)
+			}
+			if last.Content != "You have one event type." || last.ConversationID != "stream-test" {
+				t.Errorf("Unexpected final event: %+v", last)
+			}
+			if len(last.FunctionCalls) != 1 {
+				t.Errorf("Expected 1 executed call in the final event, got %d", len(last.FunctionCalls))
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/yourusername/cal-chatbot/internal/api"
+	"github.com/yourusername/cal-chatbot/internal/chatbot"
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/test/mocks"
+)
+
+// TestSystemPrompt tests that a rendered system prompt is prepended to every conversation
+func TestSystemPrompt(t *testing.T) {
+	userMessage := []models.ChatMessage{{Role: "user", Content: "Book something next week"}}
+
+	t.Run("DefaultTemplate", func(t *testing.T) {
+		t.Setenv("CALCOM_USERNAME", "jane")
+		server := mocks.NewMockOpenAIServer(contentMessage("Sure."))
+		defer server.Close()
+		bot := newLoopTestBot(t, server, mocks.NewMockCalcomClient())
+
+		ctx := models.WithUserProfile(context.Background(), models.UserProfile{
+			Email:    "jane@example.com",
+			Timezone: "America/New_York",
+		})
+		if _, _, err := bot.ProcessMessage(ctx, userMessage); err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+
+		messages := server.LastRequest().Messages
+		if len(messages) != 2 || messages[0].Role != goopenai.ChatMessageRoleSystem {
+			t.Fatalf("Expected a system message before the user message, got %+v", messages)
+		}
+		location, _ := time.LoadLocation("America/New_York")
+		for _, expected := range []string{
+			time.Now().In(location).Format("2006-01-02"),
+			"America/New_York",
+			`"jane"`,
+			"jane@example.com",
+			"30 Min Meeting (id 1",
+		} {
+			if !strings.Contains(messages[0].Content, expected) {
+				t.Errorf("Expected the system prompt to contain %q, got:\n%s", expected, messages[0].Content)
+			}
+		}
+	})
+
+	t.Run("DefaultsToUTC", func(t *testing.T) {
+		server := mocks.NewMockOpenAIServer(contentMessage("Sure."))
+		defer server.Close()
+		bot := newLoopTestBot(t, server, mocks.NewMockCalcomClient())
+
+		ctx := models.WithUserProfile(context.Background(), models.UserProfile{Timezone: "Not/AZone"})
+		if _, _, err := bot.ProcessMessage(ctx, userMessage); err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		if prompt := server.LastRequest().Messages[0].Content; !strings.Contains(prompt, "timezone is UTC") {
+			t.Errorf("Expected an unknown timezone to fall back to UTC, got:\n%s", prompt)
+		}
+	})
+
+	t.Run("TemplateFromFile", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "prompt.tmpl")
+		if err := os.WriteFile(path, []byte(`Today is {{.Now.Format "2006-01-02"}} in {{.Timezone}} for {{.Email}}.`), 0644); err != nil {
+			t.Fatalf("Failed to write prompt file: %v", err)
+		}
+		t.Setenv("SYSTEM_PROMPT_FILE", path)
+		server := mocks.NewMockOpenAIServer(contentMessage("Sure."))
+		defer server.Close()
+		bot := newLoopTestBot(t, server, mocks.NewMockCalcomClient())
+
+		ctx := models.WithUserProfile(context.Background(), models.UserProfile{Email: "jane@example.com", Timezone: "Asia/Tokyo"})
+		if _, _, err := bot.ProcessMessage(ctx, userMessage); err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		location, _ := time.LoadLocation("Asia/Tokyo")
+		expected := "Today is " + time.Now().In(location).Format("2006-01-02") + " in Asia/Tokyo for jane@example.com."
+		if prompt := server.LastRequest().Messages[0].Content; prompt != expected {
+			t.Errorf("Expected %q, got %q", expected, prompt)
+		}
+	})
+
+	t.Run("InvalidTemplate", func(t *testing.T) {
+		t.Setenv("SYSTEM_PROMPT", "{{.Now")
+		if _, err := chatbot.NewChatbot(mocks.NewMockCalcomClient()); err == nil {
+			t.Error("Expected an invalid template to be rejected")
+		}
+	})
+}
+
+// TestSystemPromptEventTypesFetchedOnce tests that concurrent requests share one fetch of the event types
+func TestSystemPromptEventTypesFetchedOnce(t *testing.T) {
+	t.Setenv("SYSTEM_PROMPT", "{{range .EventTypes}}{{.Title}}{{end}}")
+	server := mocks.NewMockOpenAIServer()
+	defer server.Close()
+	server.Respond = func(goopenai.ChatCompletionRequest) goopenai.ChatCompletionMessage { return contentMessage("Hi.") }
+	calendar := mocks.NewMockCalcomClient()
+	calendar.EventTypesDelay = 50 * time.Millisecond
+	bot := newLoopTestBot(t, server, calendar)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := bot.ProcessMessage(context.Background(), []models.ChatMessage{{Role: "user", Content: "hi"}}); err != nil {
+				t.Errorf("ProcessMessage failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calendar.EventTypeFetches != 1 {
+		t.Errorf("Expected the event types to be fetched once, got %d fetches", calendar.EventTypeFetches)
+	}
+	if prompt := server.LastRequest().Messages[0].Content; prompt != "30 Min Meeting" {
+		t.Errorf("Expected the event types in the prompt, got %q", prompt)
+	}
+
+	// A request giving up doesn't wait for the fetch another request started
+	calendar = mocks.NewMockCalcomClient()
+	calendar.EventTypesDelay = time.Second
+	bot = newLoopTestBot(t, server, calendar)
+	fetched := make(chan struct{})
+	go func() {
+		defer close(fetched)
+		bot.ProcessMessage(context.Background(), []models.ChatMessage{{Role: "user", Content: "hi"}})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	bot.ProcessMessage(ctx, []models.ChatMessage{{Role: "user", Content: "hi"}})
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the cancelled request to return without waiting for the fetch, took %v", elapsed)
+	}
+	<-fetched
+}
+
+// TestChatVerifiedEmailProfile tests that the verified email cookie reaches the chatbot
+func TestChatVerifiedEmailProfile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bot := &RecordingChatbot{}
+	router := gin.New()
+	router.POST("/api/chat", api.NewHandler(bot, newTestHistoryStore(t)).HandleChat)
+
+	requestBody, _ := json.Marshal(models.ChatRequest{
+		Messages: []models.ChatMessage{{Role: "user", Content: "Show my bookings"}},
+		UserID:   "u-1",
+	})
+	req, _ := http.NewRequest("POST", "/api/chat", bytes.NewBuffer(requestBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "verified_email", Value: "jane@example.com"})
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, resp.Code)
+	}
+	if len(bot.Profiles) != 1 || bot.Profiles[0].Email != "jane@example.com" || bot.Profiles[0].UserID != "u-1" {
+		t.Errorf("Expected the verified email and user ID in the profile, got %+v", bot.Profiles)
+	}
+}
@@ -0,0 +1,183 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/internal/timeparse"
+	"github.com/yourusername/cal-chatbot/test/mocks"
+)
+
+// TestTimeParse tests resolving natural-language expressions against a fixed reference time
+func TestTimeParse(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+	// Wednesday, 11 June 2025, 10:30 in Berlin
+	ref := time.Date(2025, 6, 11, 10, 30, 0, 0, berlin)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2025, month, day, hour, minute, 0, 0, berlin)
+	}
+
+	for _, tc := range []struct {
+		text  string
+		start time.Time
+		end   time.Time
+	}{
+		{text: "3pm", start: at(6, 11, 15, 0), end: at(6, 11, 15, 0)},
+		{text: "14:00", start: at(6, 11, 14, 0), end: at(6, 11, 14, 0)},
+		{text: "cancel my meeting at 3", start: at(6, 11, 15, 0), end: at(6, 11, 15, 0)},
+		{text: "next Tuesday at 3", start: at(6, 17, 15, 0), end: at(6, 17, 15, 0)},
+		{text: "Tuesday at 9:30am", start: at(6, 17, 9, 30), end: at(6, 17, 9, 30)},
+		{text: "Wednesday", start: at(6, 11, 0, 0), end: at(6, 12, 0, 0)},
+		{text: "last Friday", start: at(6, 6, 0, 0), end: at(6, 7, 0, 0)},
+		{text: "tomorrow morning", start: at(6, 12, 9, 0), end: at(6, 12, 12, 0)},
+		{text: "tomorrow at noon", start: at(6, 12, 12, 0), end: at(6, 12, 12, 0)},
+		{text: "at 8 tonight", start: at(6, 11, 20, 0), end: at(6, 11, 20, 0)},
+		{text: "at 9 tonight", start: at(6, 11, 21, 0), end: at(6, 11, 21, 0)},
+		{text: "at 10 tonight", start: at(6, 11, 22, 0), end: at(6, 11, 22, 0)},
+		{text: "Friday at 3:30", start: at(6, 13, 15, 30), end: at(6, 13, 15, 30)},
+		{text: "Friday at 03:30", start: at(6, 13, 3, 30), end: at(6, 13, 3, 30)},
+		{text: "I sat down, book 3pm", start: at(6, 11, 15, 0), end: at(6, 11, 15, 0)},
+		{text: "I sat down, book Friday 3pm", start: at(6, 13, 15, 0), end: at(6, 13, 15, 0)},
+		{text: "on sat at 3pm", start: at(6, 14, 15, 0), end: at(6, 14, 15, 0)},
+		{text: "next mon", start: at(6, 16, 0, 0), end: at(6, 17, 0, 0)},
+		{text: "at 7:30 in the morning", start: at(6, 11, 7, 30), end: at(6, 11, 7, 30)},
+		{text: "tomorrow at 7 in the morning", start: at(6, 12, 7, 0), end: at(6, 12, 7, 0)},
+		{text: "between 6 and 7 in the morning", start: at(6, 11, 6, 0), end: at(6, 11, 7, 0)},
+		{text: "tomorrow at 10.30", start: at(6, 12, 10, 30), end: at(6, 12, 10, 30)},
+		{text: "in two hours", start: at(6, 11, 12, 30), end: at(6, 11, 12, 30)},
+		{text: "in 45 minutes", start: at(6, 11, 11, 15), end: at(6, 11, 11, 15)},
+		{text: "in half an hour", start: at(6, 11, 11, 0), end: at(6, 11, 11, 0)},
+		{text: "in 3 days at 4pm", start: at(6, 14, 16, 0), end: at(6, 14, 16, 0)},
+		{text: "the 14th", start: at(6, 14, 0, 0), end: at(6, 15, 0, 0)},
+		{text: "on the 2nd", start: at(7, 2, 0, 0), end: at(7, 3, 0, 0)},
+		{text: "June 20th at 11am", start: at(6, 20, 11, 0), end: at(6, 20, 11, 0)},
+		{text: "3 July", start: at(7, 3, 0, 0), end: at(7, 4, 0, 0)},
+		{text: "2025-06-30 16:15", start: at(6, 30, 16, 15), end: at(6, 30, 16, 15)},
+		{text: "end of next week", start: at(6, 20, 0, 0), end: at(6, 21, 0, 0)},
+		{text: "next week", start: at(6, 16, 0, 0), end: at(6, 23, 0, 0)},
+		{text: "end of the month", start: at(6, 30, 0, 0), end: at(7, 1, 0, 0)},
+		{text: "between 2 and 4pm Friday", start: at(6, 13, 14, 0), end: at(6, 13, 16, 0)},
+		{text: "from 9 to 5 tomorrow", start: at(6, 12, 9, 0), end: at(6, 12, 17, 0)},
+		{text: "11-1pm on Monday", start: at(6, 16, 11, 0), end: at(6, 16, 13, 0)},
+	} {
+		t.Run(tc.text, func(t *testing.T) {
+			result, err := timeparse.Parse(tc.text, ref)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if !result.Start.Equal(tc.start) || !result.End.Equal(tc.end) {
+				t.Errorf("Expected %v - %v, got %v - %v", tc.start, tc.end, result.Start, result.End)
+			}
+			if result.Start.Location() != berlin {
+				t.Errorf("Expected the result in the reference location, got %v", result.Start.Location())
+			}
+		})
+	}
+
+	for _, text := range []string{"", "sometime soon", "between 4pm and 2pm", "the 45th", "at 3pm in two hours", "in 2 hours tomorrow", "in 30 minutes tonight", "10/20 at 3pm", "tomorrow at 3pm room 4b"} {
+		t.Run("Invalid/"+text, func(t *testing.T) {
+			if result, err := timeparse.Parse(text, ref); err == nil {
+				t.Errorf("Expected an error, got %v - %v", result.Start, result.End)
+			}
+		})
+	}
+}
+
+// TestTimeParseDST tests times on the days daylight saving time ends, when midnight is 25 hours
+// before the end of the day
+func TestTimeParseDST(t *testing.T) {
+	for _, tc := range []struct {
+		zone       string
+		text       string
+		month      time.Month
+		day        int
+		start, end int // wall clock hours of the result in the zone
+	}{
+		{zone: "Europe/Berlin", text: "next sunday at 3pm", month: time.October, day: 25, start: 15, end: 15},
+		{zone: "Europe/Berlin", text: "2026-10-25 at 10am", month: time.October, day: 25, start: 10, end: 10},
+		{zone: "Europe/Berlin", text: "next sunday evening", month: time.October, day: 25, start: 17, end: 21},
+		{zone: "America/New_York", text: "2026-11-01 at 3pm", month: time.November, day: 1, start: 15, end: 15},
+	} {
+		t.Run(tc.zone+"/"+tc.text, func(t *testing.T) {
+			location, err := time.LoadLocation(tc.zone)
+			if err != nil {
+				t.Skipf("timezone database not available: %v", err)
+			}
+			// Wednesday, 14 October 2026
+			ref := time.Date(2026, 10, 14, 12, 0, 0, 0, location)
+			start := time.Date(2026, tc.month, tc.day, tc.start, 0, 0, 0, location)
+			end := time.Date(2026, tc.month, tc.day, tc.end, 0, 0, 0, location)
+			result, err := timeparse.Parse(tc.text, ref)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if !result.Start.Equal(start) || !result.End.Equal(end) {
+				t.Errorf("Expected %v - %v, got %v - %v", start, end, result.Start, result.End)
+			}
+		})
+	}
+}
+
+// TestCancelEventByTime tests cancelling an event described by when it takes place
+func TestCancelEventByTime(t *testing.T) {
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1)
+	at := func(hour, minute int) time.Time {
+		return time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), hour, minute, 0, 0, time.UTC)
+	}
+	events := []models.Event{
+		{ID: "event-1", Title: "Standup", StartTime: at(9, 0), EndTime: at(9, 15)},
+		{ID: "event-2", Title: "Design review", StartTime: at(15, 0), EndTime: at(16, 0)},
+		{ID: "event-3", Title: "1:1", StartTime: at(16, 30), EndTime: at(17, 0)},
+		{ID: "event-4", Title: "Lunch", StartTime: at(12, 0), EndTime: at(13, 0), Status: "cancelled"},
+	}
+
+	for _, tc := range []struct {
+		name        string
+		timeText    string
+		expectedID  string
+		expectedErr string
+	}{
+		{name: "ExactTime", timeText: "tomorrow at 3pm", expectedID: "event-2"},
+		{name: "SinglePartOfDay", timeText: "tomorrow morning", expectedID: "event-1"},
+		{name: "Ambiguous", timeText: "tomorrow afternoon", expectedErr: "There are 2 events"},
+		{name: "NoEvent", timeText: "tomorrow at 11am", expectedErr: "couldn't find an event"},
+		{name: "CancelledEvent", timeText: "tomorrow at noon", expectedErr: "couldn't find an event"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := mocks.NewMockOpenAIServer(
+				toolCallMessage(toolCall("call_1", "cancelEvent", `{"timeText":"`+tc.timeText+`"}`)),
+				contentMessage("Done."),
+			)
+			defer server.Close()
+			calendar := mocks.NewMockCalcomClient()
+			calendar.Events = events
+			bot := newLoopTestBot(t, server, calendar)
+
+			_, calls, err := bot.ProcessMessage(context.Background(), []models.ChatMessage{{Role: "user", Content: "cancel my meeting " + tc.timeText}})
+			if err != nil {
+				t.Fatalf("ProcessMessage failed: %v", err)
+			}
+			if len(calls) != 1 {
+				t.Fatalf("Expected one cancelEvent call, got %+v", calls)
+			}
+			if !strings.Contains(calls[0].Error, tc.expectedErr) || (tc.expectedErr == "") != (calls[0].Error == "") {
+				t.Fatalf("Expected error containing %q, got %q", tc.expectedErr, calls[0].Error)
+			}
+			if tc.expectedID == "" {
+				return
+			}
+			if _, _, err := bot.ConfirmAction(context.Background(), "", pendingToken(t, calls[0])); err != nil {
+				t.Fatalf("ConfirmAction failed: %v", err)
+			}
+			if calendar.CanceledEventID != tc.expectedID {
+				t.Errorf("Expected %q to be cancelled, got %q", tc.expectedID, calendar.CanceledEventID)
+			}
+		})
+	}
+}
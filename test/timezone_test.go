@@ -0,0 +1,152 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/cal-chatbot/internal/api"
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/test/mocks"
+)
+
+// TestUserTimezone tests that tools read and present times in the user's timezone
+func TestUserTimezone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone database not available: %v", err)
+	}
+	ctx := models.WithUserProfile(context.Background(), models.UserProfile{Timezone: "Europe/Berlin"})
+	userMessage := []models.ChatMessage{{Role: "user", Content: "book 3pm on June 3rd"}}
+
+	t.Run("BookMeeting", func(t *testing.T) {
+		server := mocks.NewMockOpenAIServer(
+			toolCallMessage(toolCall("call_1", "bookMeeting", `{"eventTypeId":1,"startTime":"2030-06-03T15:00:00","endTime":"2030-06-03T15:30:00","name":"Jane","email":"jane@example.com"}`)),
+			contentMessage("Booked."),
+		)
+		defer server.Close()
+		calendar := mocks.NewMockCalcomClient()
+		calendar.AvailableSlots = []time.Time{time.Date(2030, 6, 3, 15, 0, 0, 0, berlin)}
+		bot := newLoopTestBot(t, server, calendar)
+
+		_, calls, err := bot.ProcessMessage(ctx, userMessage)
+		if err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		if _, _, err := bot.ConfirmAction(ctx, "", pendingToken(t, calls[0])); err != nil {
+			t.Fatalf("ConfirmAction failed: %v", err)
+		}
+		booking := calendar.BookingRequest
+		if booking == nil {
+			t.Fatal("Expected the event to be booked")
+		}
+		if booking.TimeZone != "Europe/Berlin" {
+			t.Errorf("Expected the booking timezone to be Europe/Berlin, got %q", booking.TimeZone)
+		}
+		if expected := time.Date(2030, 6, 3, 13, 0, 0, 0, time.UTC); !booking.Start.Equal(expected) {
+			t.Errorf("Expected a time without offset to be read in Berlin time (%v), got %v", expected, booking.Start)
+		}
+	})
+
+	t.Run("CheckAvailability", func(t *testing.T) {
+		server := mocks.NewMockOpenAIServer(
+			toolCallMessage(toolCall("call_1", "checkAvailability", `{"eventTypeId":1,"startDate":"2030-06-03","endDate":"2030-06-03"}`)),
+			contentMessage("Here are the slots."),
+		)
+		defer server.Close()
+		calendar := mocks.NewMockCalcomClient()
+		bot := newLoopTestBot(t, server, calendar)
+
+		_, calls, err := bot.ProcessMessage(ctx, userMessage)
+		if err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		if expected := time.Date(2030, 6, 3, 0, 0, 0, 0, berlin); !calendar.SlotsStart.Equal(expected) || calendar.SlotsStart.Location().String() != "Europe/Berlin" {
+			t.Errorf("Expected availability from Berlin midnight (%v), got %v", expected, calendar.SlotsStart)
+		}
+		if expected := time.Date(2030, 6, 4, 0, 0, 0, 0, berlin); !calendar.SlotsEnd.Equal(expected) {
+			t.Errorf("Expected availability until the end of the Berlin day (%v), got %v", expected, calendar.SlotsEnd)
+		}
+		result, _ := calls[0].Result.(map[string]interface{})
+		if result["timeZone"] != "Europe/Berlin" {
+			t.Errorf("Expected the slots to be labelled with the user's timezone, got %+v", result)
+		}
+	})
+
+	t.Run("ListEvents", func(t *testing.T) {
+		server := mocks.NewMockOpenAIServer(
+			toolCallMessage(toolCall("call_1", "listEvents", `{"email":"jane@example.com"}`)),
+			contentMessage("Here are your events."),
+		)
+		defer server.Close()
+		calendar := mocks.NewMockCalcomClient()
+		calendar.Events = []models.Event{{
+			ID:        "event-1",
+			Title:     "Planning",
+			StartTime: time.Date(2030, 6, 3, 13, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2030, 6, 3, 13, 30, 0, 0, time.UTC),
+		}}
+		bot := newLoopTestBot(t, server, calendar)
+
+		_, calls, err := bot.ProcessMessage(ctx, userMessage)
+		if err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		result, _ := calls[0].Result.(map[string]interface{})
+		message, _ := result["message"].(string)
+		if !strings.Contains(message, "2030-06-03 15:00 to 15:30 CEST") {
+			t.Errorf("Expected the event in Berlin time, got %q", message)
+		}
+	})
+}
+
+// TestChatTimezone tests how the chat endpoint resolves the user's timezone
+func TestChatTimezone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, tc := range []struct {
+		name           string
+		timezone       string
+		cookie         string
+		expectedStatus int
+		expected       string
+	}{
+		{name: "FromRequest", timezone: "Europe/Berlin", cookie: "America/New_York", expectedStatus: http.StatusOK, expected: "Europe/Berlin"},
+		{name: "FromVerifiedProfile", cookie: "America/New_York", expectedStatus: http.StatusOK, expected: "America/New_York"},
+		{name: "Invalid", timezone: "Mars/Olympus_Mons", expectedStatus: http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bot := &RecordingChatbot{}
+			router := gin.New()
+			router.POST("/api/chat", api.NewHandler(bot, newTestHistoryStore(t)).HandleChat)
+
+			requestBody, _ := json.Marshal(models.ChatRequest{
+				Messages: []models.ChatMessage{{Role: "user", Content: "What's on today?"}},
+				Timezone: tc.timezone,
+			})
+			req, _ := http.NewRequest("POST", "/api/chat", bytes.NewBuffer(requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "timezone", Value: tc.cookie})
+			}
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			if resp.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tc.expectedStatus, resp.Code)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			if len(bot.Profiles) != 1 || bot.Profiles[0].Timezone != tc.expected {
+				t.Errorf("Expected timezone %q, got %+v", tc.expected, bot.Profiles)
+			}
+		})
+	}
+}
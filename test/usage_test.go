@@ -0,0 +1,135 @@
+package test
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/yourusername/cal-chatbot/internal/api"
+	"github.com/yourusername/cal-chatbot/internal/models"
+	"github.com/yourusername/cal-chatbot/test/mocks"
+)
+
+// newUsageTestRouter creates a router for a chatbot using model whose completions each use 100 prompt
+// and 20 completion tokens
+func newUsageTestRouter(t *testing.T, model string) (*gin.Engine, *mocks.MockOpenAIServer) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("OPENAI_MODEL", model)
+	server := mocks.NewMockOpenAIServer(contentMessage("Hello!"))
+	t.Cleanup(server.Close)
+	server.Usage = goopenai.Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120}
+	bot := newLoopTestBot(t, server, mocks.NewMockCalcomClient())
+	router := gin.New()
+	api.NewHandler(bot, newTestHistoryStore(t)).SetupRoutes(router)
+	return router, server
+}
+
+// chatTurn sends a message in a conversation
+func chatTurn(t *testing.T, router *gin.Engine, conversationID, content string) {
+	t.Helper()
+	body, _ := json.Marshal(models.ChatRequest{Messages: []models.ChatMessage{{Role: "user", Content: content}}})
+	req := httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Conversation-Id", conversationID)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+}
+
+// conversationUsage gets the usage of a conversation
+func conversationUsage(t *testing.T, router *gin.Engine, conversationID string) models.ConversationUsage {
+	t.Helper()
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/api/conversations/"+conversationID+"/usage", nil))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var usage models.ConversationUsage
+	if err := json.Unmarshal(resp.Body.Bytes(), &usage); err != nil {
+		t.Fatalf("Failed to parse usage: %v", err)
+	}
+	return usage
+}
+
+// TestConversationUsage tests recording token usage and cost per turn and per conversation
+func TestConversationUsage(t *testing.T) {
+	t.Run("PerTurnAndTotal", func(t *testing.T) {
+		// A dated snapshot is priced like gpt-4o: $2.50 and $10 per million prompt and completion tokens
+		router, server := newUsageTestRouter(t, "gpt-4o-2024-08-06")
+		server.SetResponses(
+			toolCallMessage(toolCall("call_1", "listEventTypes", `{}`)),
+			contentMessage("You can book an intro call."),
+		)
+		chatTurn(t, router, "usage-1", "what can I book?")
+		server.SetResponses(contentMessage("You're welcome!"))
+		chatTurn(t, router, "usage-1", "thanks")
+
+		usage := conversationUsage(t, router, "usage-1")
+		if len(usage.Turns) != 2 {
+			t.Fatalf("Expected 2 turns, got %+v", usage.Turns)
+		}
+		first := usage.Turns[0].Usage
+		if first.Completions != 2 || first.PromptTokens != 200 || first.CompletionTokens != 40 || first.TotalTokens != 240 {
+			t.Errorf("Expected two completions in the first turn, got %+v", first)
+		}
+		if math.Abs(first.EstimatedCost-0.0009) > 1e-12 {
+			t.Errorf("Expected a first turn cost of $0.0009, got %v", first.EstimatedCost)
+		}
+		if usage.Total.Completions != 3 || usage.Total.TotalTokens != 360 || math.Abs(usage.Total.EstimatedCost-0.00135) > 1e-12 {
+			t.Errorf("Unexpected total: %+v", usage.Total)
+		}
+		if usage.Total.Unpriced {
+			t.Error("Expected gpt-4o to be priced")
+		}
+	})
+
+	t.Run("ConfiguredPrices", func(t *testing.T) {
+		t.Setenv("OPENAI_PRICES", `{"in-house":{"prompt":1,"completion":5}}`)
+		router, _ := newUsageTestRouter(t, "in-house")
+		chatTurn(t, router, "usage-2", "hi")
+		total := conversationUsage(t, router, "usage-2").Total
+		if math.Abs(total.EstimatedCost-0.0002) > 1e-12 || total.Unpriced {
+			t.Errorf("Expected a cost of $0.0002 from the configured price, got %+v", total)
+		}
+	})
+
+	t.Run("Unpriced", func(t *testing.T) {
+		router, _ := newUsageTestRouter(t, "mystery-model")
+		chatTurn(t, router, "usage-3", "hi")
+		total := conversationUsage(t, router, "usage-3").Total
+		if !total.Unpriced || total.TotalTokens != 120 {
+			t.Errorf("Expected the tokens of an unpriced model to be counted and flagged, got %+v", total)
+		}
+	})
+
+	t.Run("Streamed", func(t *testing.T) {
+		router, _ := newUsageTestRouter(t, "gpt-4o")
+		body, _ := json.Marshal(models.ChatRequest{Messages: []models.ChatMessage{{Role: "user", Content: "hi"}}})
+		req := httptest.NewRequest(http.MethodPost, "/api/chat/stream", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Conversation-Id", "usage-4")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		total := conversationUsage(t, router, "usage-4").Total
+		if !total.Estimated || total.PromptTokens == 0 || total.CompletionTokens == 0 || total.EstimatedCost == 0 {
+			t.Errorf("Expected the tokens of a streamed turn to be estimated and priced, got %+v", total)
+		}
+	})
+
+	t.Run("UnknownConversation", func(t *testing.T) {
+		router, _ := newUsageTestRouter(t, "gpt-4o")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/api/conversations/nope/usage", nil))
+		if resp.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", resp.Code)
+		}
+	})
+}
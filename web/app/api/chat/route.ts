@@ -2,7 +2,8 @@ import { NextResponse } from "next/server"
 
 export async function POST(request: Request) {
   try {
-    const { messages } = await request.json()
+    const { messages, timezone } = await request.json()
+    const conversationId = request.headers.get("X-Conversation-Id")
     // Remove frontend-only fields (like 'read') from each message
     const sanitizedMessages = Array.isArray(messages)
       ? messages.map(({ read, ...rest }) => rest)
@@ -13,8 +14,9 @@ export async function POST(request: Request) {
       method: "POST",
       headers: {
         "Content-Type": "application/json",
+        ...(conversationId ? { "X-Conversation-Id": conversationId } : {}),
       },
-      body: JSON.stringify({ messages: sanitizedMessages }),
+      body: JSON.stringify({ messages: sanitizedMessages, timezone }),
     })
 
     if (!response.ok) {
@@ -82,7 +82,11 @@ export function EmailVerification() {
         headers: {
           "Content-Type": "application/json",
         },
-        body: JSON.stringify({ email, code: verificationCode }),
+        body: JSON.stringify({
+          email,
+          code: verificationCode,
+          timezone: Intl.DateTimeFormat().resolvedOptions().timeZone,
+        }),
       })
 
       if (!response.ok) {
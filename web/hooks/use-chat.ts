@@ -22,8 +22,9 @@ function extractEmail(content: string): string | null {
   return match ? match[0] : null
 }
 
-// Helper: Try to extract booking details from user message
-function extractBookingDetails(content: string): any | null {
+// Helper: Try to extract booking details from user message. The attendee is never made up:
+// the email is the one in the message or the verified one, and the backend asks for anything missing.
+function extractBookingDetails(content: string, verifiedEmail: string | null): any | null {
   // Very basic extraction for demo; in production, use NLP or a form
   if (/random details/i.test(content)) {
     const now = new Date()
@@ -31,12 +32,11 @@ function extractBookingDetails(content: string): any | null {
     const startTime = new Date(tomorrow.setHours(10, 0, 0, 0))
     const endTime = new Date(tomorrow.setHours(11, 0, 0, 0))
     return {
-      eventTypeId: 0,
       startTime: startTime.toISOString(),
       endTime: endTime.toISOString(),
-      name: "Random User",
-      email: `randomuser+${Date.now()}@example.com`,
-      notes: "Random details for the meeting",
+      email: extractEmail(content) || verifiedEmail || "",
+      // No idempotencyKey: the backend derives one from the event type, time and email, so a
+      // resubmission of the same booking returns the first one
     }
   }
   // Add more extraction logic as needed
@@ -156,7 +156,7 @@ export function useChat() {
 
     // Attach only one of booking or listEvents
     if (isBookingIntent(content)) {
-      userMessage.booking = extractBookingDetails(content)
+      userMessage.booking = extractBookingDetails(content, userEmail)
     } else if (isListEventsIntent(content)) {
       // Use explicit email if present, else fallback to authenticated email
       const explicitEmail = extractEmail(content)
@@ -203,8 +203,12 @@ export function useChat() {
     try {
       const response = await fetch("/api/chat", {
         method: "POST",
-        headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({ messages: sanitizedMessages }),
+        // The conversation ID lets the backend keep the conversation's history and booking draft
+        headers: { "Content-Type": "application/json", "X-Conversation-Id": convId },
+        body: JSON.stringify({
+          messages: sanitizedMessages,
+          timezone: Intl.DateTimeFormat().resolvedOptions().timeZone,
+        }),
       })
       const data = await response.json()
       const botMessage: Message = {